@@ -49,6 +49,14 @@ func DBinstance() *mongo.Client {
 
 var Client *mongo.Client = DBinstance()
 
+// DatabaseName is the database every collection lives in, from DB_NAME (default "Tomato")
+func DatabaseName() string {
+	if name := os.Getenv("DB_NAME"); name != "" {
+		return name
+	}
+	return "Tomato"
+}
+
 func OpenCollection(client *mongo.Client, collectionName string) *mongo.Collection {
-	return client.Database("Tomato").Collection(collectionName)
+	return client.Database(DatabaseName()).Collection(collectionName)
 }
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Fields whose values must not repeat within a collection; handlers check them too, the
+// index stops two requests racing past the check. A partial index only covers the documents
+// matching its filter.
+var uniqueIndexes = []struct {
+	collection string
+	keys       bson.D
+	partial    bson.M
+}{
+	{"gift_card", bson.D{{Key: "code", Value: 1}}, nil},
+	{"z_report", bson.D{{Key: "outlet_id", Value: 1}, {Key: "business_date", Value: 1}}, nil},
+	{"staff", bson.D{{Key: "employee_code", Value: 1}}, nil},
+	{"customer_profile", bson.D{{Key: "user_id", Value: 1}}, nil},
+	{"outlet", bson.D{{Key: "code", Value: 1}}, nil},
+	{"outlet", bson.D{{Key: "invoice_prefix", Value: 1}}, bson.M{"invoice_prefix": bson.M{"$type": "string"}}},
+	{"cash_drawer_session", bson.D{{Key: "outlet_id", Value: 1}}, bson.M{"status": "OPEN"}},
+	{"time_entry", bson.D{{Key: "staff_id", Value: 1}}, bson.M{"status": "OPEN"}},
+}
+
+// EnsureIndexes creates the unique indexes, leaving existing ones alone. A collection that
+// already holds duplicates is logged and skipped so the server still starts.
+func EnsureIndexes(client *mongo.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, index := range uniqueIndexes {
+		opts := options.Index().SetUnique(true)
+		if index.partial != nil {
+			opts.SetPartialFilterExpression(index.partial)
+		}
+		model := mongo.IndexModel{Keys: index.keys, Options: opts}
+		if _, err := OpenCollection(client, index.collection).Indexes().CreateOne(ctx, model); err != nil {
+			log.Printf("index on %s %v: %v", index.collection, index.keys, err)
+		}
+	}
+}
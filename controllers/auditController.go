@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var auditCollection *mongo.Collection = database.OpenCollection(database.Client, "audit_log")
+
+// Get audit log entries, newest first, filtered by entity, actor, action, outcome and time range
+func GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	recordPerPage, err := strconv.Atoi(r.URL.Query().Get("recordPerPage"))
+	if err != nil || recordPerPage < 1 {
+		recordPerPage = 10
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	startIndex := (page - 1) * recordPerPage
+
+	filter := bson.M{}
+	for _, field := range []string{"entity", "entity_id", "actor_id", "action"} {
+		if value := r.URL.Query().Get(field); value != "" {
+			filter[field] = value
+		}
+	}
+
+	switch r.URL.Query().Get("failed") {
+	case "true":
+		filter["status_code"] = bson.M{"$gte": http.StatusBadRequest}
+	case "false":
+		filter["status_code"] = bson.M{"$lt": http.StatusBadRequest}
+	}
+
+	createdAt := bson.M{}
+	if from := r.URL.Query().Get("from"); from != "" {
+		fromTime, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid from time, expected RFC3339"}`, http.StatusBadRequest)
+			return
+		}
+		createdAt["$gte"] = fromTime
+	}
+	if to := r.URL.Query().Get("to"); to != "" {
+		toTime, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid to time, expected RFC3339"}`, http.StatusBadRequest)
+			return
+		}
+		createdAt["$lte"] = toTime
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64(startIndex)).
+		SetLimit(int64(recordPerPage)).
+		SetProjection(bson.M{"_id": 0})
+
+	cursor, err := auditCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving audit logs"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var auditLogs []bson.M
+	if err = cursor.All(ctx, &auditLogs); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding audit log data"}`, http.StatusInternalServerError)
+		return
+	}
+
+	totalLogs, err := auditCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving total audit log count"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Audit logs retrieved successfully",
+		"data":    auditLogs,
+		"pagination": map[string]interface{}{
+			"current_page":     page,
+			"records_per_page": recordPerPage,
+			"total_logs":       totalLogs,
+			"total_pages":      (totalLogs + int64(recordPerPage) - 1) / int64(recordPerPage),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,418 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var cashDrawerCollection *mongo.Collection = database.OpenCollection(database.Client, "cash_drawer_session")
+
+// Cash drawer session statuses and entry types
+const (
+	drawerOpen   = "OPEN"
+	drawerClosed = "CLOSED"
+	drawerIn     = "CASH_IN"
+	drawerOut    = "CASH_OUT"
+)
+
+// businessDate is the local calendar date a moment falls on, as YYYY-MM-DD
+func businessDate(t time.Time) string {
+	return t.In(time.Local).Format("2006-01-02")
+}
+
+// cashTakings sums the cash an outlet took in (CASH invoices paid, tips included) and paid back out
+// (credit notes on CASH invoices) between from and to
+func cashTakings(ctx context.Context, outletId string, from, to time.Time) (float64, float64, error) {
+	salesPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: activeFilter(sameOutlet(bson.M{
+			"payment_method": "CASH",
+			"payment_status": bson.M{"$in": salesInvoiceStatuses},
+			"payment_date":   bson.M{"$gte": from, "$lt": to},
+		}, outletId))}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$add", Value: bson.A{
+				"$total_price", bson.D{{Key: "$ifNull", Value: bson.A{"$tip_amount", 0}}},
+			}}}}}},
+		}}},
+	}
+	refundPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: sameOutlet(bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}, outletId)}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "invoice"},
+			{Key: "localField", Value: "invoice_id"},
+			{Key: "foreignField", Value: "invoice_id"},
+			{Key: "as", Value: "invoice"},
+		}}},
+		{{Key: "$match", Value: bson.M{"invoice.payment_method": "CASH"}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+		}}},
+	}
+
+	var totals [2]float64
+	for i, aggregate := range []struct {
+		collection *mongo.Collection
+		pipeline   mongo.Pipeline
+	}{{invoiceCollection, salesPipeline}, {creditNoteCollection, refundPipeline}} {
+		cursor, err := aggregate.collection.Aggregate(ctx, aggregate.pipeline)
+		if err != nil {
+			return 0, 0, err
+		}
+		var results []struct {
+			Total float64 `bson:"total"`
+		}
+		if err := cursor.All(ctx, &results); err != nil {
+			return 0, 0, err
+		}
+		if len(results) > 0 {
+			totals[i] = helper.RoundMoney(results[0].Total)
+		}
+	}
+	return totals[0], totals[1], nil
+}
+
+// reconcileDrawer works out the cash a session's drawer should hold as of until
+func reconcileDrawer(ctx context.Context, session *models.CashDrawerSession, until time.Time) (float64, error) {
+	sales, refunds, err := cashTakings(ctx, session.Outlet_id, session.Opened_at, until)
+	if err != nil {
+		return 0, err
+	}
+	session.Cash_sales, session.Cash_refunds = sales, refunds
+
+	expected := session.Opening_float + sales - refunds
+	for _, entry := range session.Entries {
+		if entry.Type == drawerIn {
+			expected += entry.Amount
+		} else {
+			expected -= entry.Amount
+		}
+	}
+	return helper.RoundMoney(expected), nil
+}
+
+// openDrawerFilter matches the open drawer of the caller's outlet
+func openDrawerFilter(r *http.Request) bson.M {
+	return sameOutlet(bson.M{"status": drawerOpen}, requestOutletId(r))
+}
+
+// OpenCashDrawer opens the outlet's drawer with an opening float. Each outlet has one drawer open at a time.
+func OpenCashDrawer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var requestBody struct {
+		Opening_float float64 `json:"opening_float"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if requestBody.Opening_float < 0 {
+		http.Error(w, `{"success": false, "message": "Opening float cannot be negative"}`, http.StatusBadRequest)
+		return
+	}
+
+	outletId, outletExists, err := checkRequestOutlet(ctx, r)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking outlet"}`, http.StatusInternalServerError)
+		return
+	}
+	if !outletExists {
+		http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	closed, err := businessDateClosed(ctx, outletId, now)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking business date"}`, http.StatusInternalServerError)
+		return
+	}
+	if closed {
+		http.Error(w, `{"success": false, "message": "Today's business date is already closed"}`, http.StatusConflict)
+		return
+	}
+
+	count, err := cashDrawerCollection.CountDocuments(ctx, openDrawerFilter(r))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking cash drawer"}`, http.StatusInternalServerError)
+		return
+	}
+	if count > 0 {
+		http.Error(w, `{"success": false, "message": "A cash drawer session is already open"}`, http.StatusConflict)
+		return
+	}
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	session := models.CashDrawerSession{
+		ID:            primitive.NewObjectID(),
+		Outlet_id:     outletId,
+		Status:        drawerOpen,
+		Business_date: businessDate(now),
+		Opening_float: helper.RoundMoney(requestBody.Opening_float),
+		Entries:       []models.CashDrawerEntry{},
+		Opened_by:     uid,
+		Opened_at:     now,
+	}
+	session.Session_id = session.ID.Hex()
+
+	// The index on open drawers turns away a second open racing past the check above
+	if _, err := cashDrawerCollection.InsertOne(ctx, session); mongo.IsDuplicateKeyError(err) {
+		http.Error(w, `{"success": false, "message": "A cash drawer session is already open"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to open cash drawer"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Cash drawer opened successfully",
+		"data":    session,
+	})
+}
+
+// GetCurrentCashDrawer returns the open session with the cash it should hold right now
+func GetCurrentCashDrawer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var session models.CashDrawerSession
+	err := cashDrawerCollection.FindOne(ctx, openDrawerFilter(r)).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "No cash drawer session is open"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving cash drawer"}`, http.StatusInternalServerError)
+		return
+	}
+
+	expected, err := reconcileDrawer(ctx, &session, time.Now())
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error totalling cash sales"}`, http.StatusInternalServerError)
+		return
+	}
+	session.Expected_cash = &expected
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Cash drawer retrieved successfully",
+		"data":    session,
+	})
+}
+
+// AddCashDrawerEntry records cash put into or taken out of the open drawer, e.g. a change top-up or a petty cash payout
+func AddCashDrawerEntry(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var requestBody struct {
+		Type   string  `json:"type"`
+		Amount float64 `json:"amount"`
+		Reason string  `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	entryType := strings.ToUpper(requestBody.Type)
+	if entryType != drawerIn && entryType != drawerOut {
+		http.Error(w, `{"success": false, "message": "Type must be CASH_IN or CASH_OUT"}`, http.StatusBadRequest)
+		return
+	}
+	amount := helper.RoundMoney(requestBody.Amount)
+	if amount <= 0 {
+		http.Error(w, `{"success": false, "message": "Amount must be greater than 0"}`, http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(requestBody.Reason)
+	if reason == "" {
+		http.Error(w, `{"success": false, "message": "A reason is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	entry := models.CashDrawerEntry{
+		Entry_id:   primitive.NewObjectID().Hex(),
+		Type:       entryType,
+		Amount:     amount,
+		Reason:     reason,
+		Created_by: uid,
+		Created_at: time.Now(),
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var session models.CashDrawerSession
+	err := cashDrawerCollection.FindOneAndUpdate(ctx, openDrawerFilter(r), bson.M{"$push": bson.M{"entries": entry}}, opts).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "No cash drawer session is open"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to record cash drawer entry"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Cash drawer entry recorded successfully",
+		"data":    session,
+	})
+}
+
+// CloseCashDrawer closes the open drawer with the cash counted in it and reports the variance
+// against what CASH invoices, refunds and entries say it should hold
+func CloseCashDrawer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var requestBody struct {
+		Counted_cash *float64 `json:"counted_cash"`
+		Notes        string   `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if requestBody.Counted_cash == nil || *requestBody.Counted_cash < 0 {
+		http.Error(w, `{"success": false, "message": "counted_cash is required and cannot be negative"}`, http.StatusBadRequest)
+		return
+	}
+
+	var session models.CashDrawerSession
+	err := cashDrawerCollection.FindOne(ctx, openDrawerFilter(r)).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "No cash drawer session is open"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving cash drawer"}`, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	expected, err := reconcileDrawer(ctx, &session, now)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error totalling cash sales"}`, http.StatusInternalServerError)
+		return
+	}
+	counted := helper.RoundMoney(*requestBody.Counted_cash)
+	variance := helper.RoundMoney(counted - expected)
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	session.Status = drawerClosed
+	session.Closed_by = uid
+	session.Closed_at = &now
+	session.Expected_cash = &expected
+	session.Counted_cash = &counted
+	session.Variance = &variance
+	session.Close_notes = strings.TrimSpace(requestBody.Notes)
+
+	// Entries added while we were counting would be lost by replacing the document, so only
+	// the closing fields are set, and only if the session is still open
+	update := bson.M{"$set": bson.M{
+		"status":        session.Status,
+		"closed_by":     session.Closed_by,
+		"closed_at":     session.Closed_at,
+		"cash_sales":    session.Cash_sales,
+		"cash_refunds":  session.Cash_refunds,
+		"expected_cash": session.Expected_cash,
+		"counted_cash":  session.Counted_cash,
+		"variance":      session.Variance,
+		"close_notes":   session.Close_notes,
+	}}
+	result, err := cashDrawerCollection.UpdateOne(ctx, bson.M{"session_id": session.Session_id, "status": drawerOpen, "entries": bson.M{"$size": len(session.Entries)}}, update)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to close cash drawer"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"success": false, "message": "The cash drawer changed while closing; count again and retry"}`, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Cash drawer closed successfully",
+		"data":    session,
+	})
+}
+
+// GetCashDrawerSessions lists drawer sessions, newest first, optionally for one ?date=YYYY-MM-DD
+func GetCashDrawerSessions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := outletScope(r, bson.M{})
+	if date := r.URL.Query().Get("date"); date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid date, expected YYYY-MM-DD"}`, http.StatusBadRequest)
+			return
+		}
+		filter["business_date"] = date
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "opened_at", Value: -1}}).SetLimit(100)
+	cursor, err := cashDrawerCollection.Find(ctx, filter, opts)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving cash drawer sessions"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []models.CashDrawerSession{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding cash drawer sessions"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Cash drawer sessions retrieved successfully",
+		"data":    sessions,
+	})
+}
+
+// GetCashDrawerSession returns one drawer session
+func GetCashDrawerSession(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var session models.CashDrawerSession
+	err := cashDrawerCollection.FindOne(ctx, outletScope(r, bson.M{"session_id": mux.Vars(r)["session_id"]})).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Cash drawer session not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving cash drawer session"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Cash drawer session retrieved successfully",
+		"data":    session,
+	})
+}
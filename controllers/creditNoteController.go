@@ -0,0 +1,499 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/02priyeshraj/Hotel_Management_Backend/payments"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var creditNoteCollection *mongo.Collection = database.OpenCollection(database.Client, "credit_note")
+
+// Invoice payment statuses beyond PENDING and PAID
+const (
+	invoiceVoid              = "VOID"
+	invoiceRefunded          = "REFUNDED"
+	invoicePartiallyRefunded = "PARTIALLY_REFUNDED"
+)
+
+var errInvoiceChanged = errors.New("invoice changed while refunding")
+
+// Invoices in these statuses are settled records and can no longer be edited or deleted
+var lockedInvoiceStatuses = []string{invoiceVoid, invoiceRefunded, invoicePartiallyRefunded}
+
+// VoidInvoice cancels an unpaid invoice. Paid invoices have to be refunded instead.
+func VoidInvoice(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	invoiceId := mux.Vars(r)["invoice_id"]
+
+	var requestBody struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || strings.TrimSpace(requestBody.Reason) == "" {
+		http.Error(w, `{"success": false, "message": "A reason is required to void an invoice"}`, http.StatusBadRequest)
+		return
+	}
+
+	var invoice models.Invoice
+	err := invoiceCollection.FindOne(ctx, activeFilter(bson.M{"invoice_id": invoiceId})).Decode(&invoice)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Invoice not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving invoice"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if invoice.Payment_status == nil || *invoice.Payment_status != "PENDING" {
+		http.Error(w, `{"success": false, "message": "Only pending invoices can be voided; refund a paid invoice instead"}`, http.StatusConflict)
+		return
+	}
+
+	// The void would change the counts of the invoice's business date
+	closed, err := businessDateClosed(ctx, invoice.Outlet_id, invoice.Created_at)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking business date"}`, http.StatusInternalServerError)
+		return
+	}
+	if closed {
+		http.Error(w, `{"success": false, "message": "Business date `+businessDate(invoice.Created_at)+` is closed; invoices from it can no longer be changed"}`, http.StatusConflict)
+		return
+	}
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"payment_status": invoiceVoid,
+		"void_reason":    requestBody.Reason,
+		"voided_by":      uid,
+		"voided_at":      now,
+		"updated_at":     now,
+	}}
+
+	// Guard against the invoice being paid in the meantime
+	result, err := invoiceCollection.UpdateOne(ctx, activeFilter(bson.M{"invoice_id": invoiceId, "payment_status": "PENDING"}), update)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to void invoice"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"success": false, "message": "Invoice is no longer pending"}`, http.StatusConflict)
+		return
+	}
+
+	// Points redeemed on the bill go back to the customer
+	if err := refundLoyaltyPoints(ctx, invoice, invoice.TotalPrice, true, uid); err != nil {
+		log.Printf("invoice %s: failed to give loyalty points back after void: %v", invoiceId, err)
+	}
+
+	voided := invoiceVoid
+	invoice.Payment_status = &voided
+	invoice.Void_reason = &requestBody.Reason
+	invoice.Voided_by = &uid
+	invoice.Voided_at = &now
+	invoice.Updated_at = now
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Invoice voided successfully",
+		"data":    invoice,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// refundPayment returns money to where an invoice was paid from: the provider account it was
+// captured through or the gift card it was redeemed from. Cash and manually recorded payments
+// are handed back at the counter, so nothing happens for them. It returns the credit note
+// field that records where the money went and the reference to store in it.
+func refundPayment(ctx context.Context, invoice models.Invoice, amount float64, uid string) (string, string, error) {
+	if invoice.Payment_reference != "" {
+		provider, err := payments.Get(invoice.Payment_provider)
+		if err != nil {
+			return "", "", err
+		}
+		refund, err := provider.Refund(ctx, invoice.Payment_reference, amount)
+		if err != nil {
+			log.Printf("payment provider %s: refund of %.2f on %s failed: %v", provider.Name(), amount, invoice.Payment_reference, err)
+			return "", "", err
+		}
+		return "provider_refund_id", refund.Refund_id, nil
+	}
+
+	if invoice.Payment_method != nil && *invoice.Payment_method == "GIFT_CARD" && invoice.Payment_details != nil {
+		entry, err := creditGiftCard(ctx, invoice.Payment_details.Gift_card_code, amount, invoice.Invoice_id, uid)
+		if err != nil {
+			log.Printf("invoice %s: refund of %.2f to gift card failed: %v", invoice.Invoice_id, amount, err)
+			return "", "", err
+		}
+		return "gift_card_entry_id", entry.Entry_id, nil
+	}
+	return "", "", nil
+}
+
+// RefundInvoice returns all or part of a paid invoice and records it as a credit note.
+// Leaving out the amount refunds whatever has not been refunded yet.
+func RefundInvoice(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	invoiceId := mux.Vars(r)["invoice_id"]
+
+	var requestBody struct {
+		Amount *float64 `json:"amount"`
+		Reason string   `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(requestBody.Reason) == "" {
+		http.Error(w, `{"success": false, "message": "A reason is required for a refund"}`, http.StatusBadRequest)
+		return
+	}
+
+	var invoice models.Invoice
+	err := invoiceCollection.FindOne(ctx, activeFilter(bson.M{"invoice_id": invoiceId})).Decode(&invoice)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Invoice not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving invoice"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if invoice.Payment_status == nil || (*invoice.Payment_status != "PAID" && *invoice.Payment_status != invoicePartiallyRefunded) {
+		http.Error(w, `{"success": false, "message": "Only paid invoices can be refunded"}`, http.StatusConflict)
+		return
+	}
+
+	// Refunds count on the day the credit note is issued, so that day must still be open
+	now := time.Now()
+	closed, err := businessDateClosed(ctx, invoice.Outlet_id, now)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking business date"}`, http.StatusInternalServerError)
+		return
+	}
+	if closed {
+		http.Error(w, `{"success": false, "message": "Today's business date is already closed"}`, http.StatusConflict)
+		return
+	}
+
+	remaining := helper.RoundMoney(invoice.TotalPrice - invoice.Refunded_total)
+	amount := remaining
+	if requestBody.Amount != nil {
+		amount = helper.RoundMoney(*requestBody.Amount)
+	}
+	if amount <= 0 || amount > remaining {
+		http.Error(w, `{"success": false, "message": "Refund amount must be greater than 0 and at most `+strconv.FormatFloat(remaining, 'f', 2, 64)+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	refundedTotal := helper.RoundMoney(invoice.Refunded_total + amount)
+	fullRefund := refundedTotal >= helper.RoundMoney(invoice.TotalPrice)
+	newStatus := invoicePartiallyRefunded
+	if fullRefund {
+		newStatus = invoiceRefunded
+	}
+
+	email, _, _, uid := middleware.GetUserFromContext(r)
+	creditNote := models.CreditNote{
+		ID:                primitive.NewObjectID(),
+		Invoice_id:        invoice.Invoice_id,
+		Order_id:          invoice.Order_id,
+		Outlet_id:         invoice.Outlet_id,
+		Amount:            amount,
+		Reason:            requestBody.Reason,
+		Full_refund:       fullRefund,
+		Approved_by:       uid,
+		Approved_by_email: email,
+		Created_at:        now,
+	}
+	creditNote.Credit_note_id = creditNote.ID.Hex()
+
+	// The order follows the invoice: partly refunded until the whole amount has gone back
+	orderStatus := "Order Partially Refunded"
+	if fullRefund {
+		orderStatus = "Order Refunded"
+	}
+
+	session, err := database.Client.StartSession()
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to refund invoice"}`, http.StatusInternalServerError)
+		return
+	}
+	defer session.EndSession(ctx)
+
+	// Record the refund before sending the money back, so a refund that went out always has a
+	// credit note; both are undone together if the money cannot be returned
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		// Only apply the refund if no other refund landed since the invoice was read
+		filter := activeFilter(bson.M{
+			"invoice_id":     invoiceId,
+			"payment_status": *invoice.Payment_status,
+			"refunded_total": invoice.Refunded_total,
+		})
+		if invoice.Refunded_total == 0 {
+			// Invoices created before refunds existed have no refunded_total yet
+			filter["refunded_total"] = bson.M{"$in": bson.A{0, nil}}
+		}
+		update := bson.M{"$set": bson.M{
+			"payment_status": newStatus,
+			"refunded_total": refundedTotal,
+			"updated_at":     now,
+		}}
+		result, err := invoiceCollection.UpdateOne(sessCtx, filter, update)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			return nil, errInvoiceChanged
+		}
+
+		if _, err := creditNoteCollection.InsertOne(sessCtx, creditNote); err != nil {
+			return nil, err
+		}
+
+		if invoice.Order_id != nil {
+			_, err := orderCollection.UpdateOne(sessCtx,
+				bson.M{"order_id": *invoice.Order_id, "status": bson.M{"$in": bson.A{"Order Paid", "Order Partially Refunded"}}},
+				bson.M{"$set": bson.M{"status": orderStatus, "updated_at": now}},
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if errors.Is(err, errInvoiceChanged) {
+		http.Error(w, `{"success": false, "message": "Invoice changed while refunding, please retry"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to refund invoice"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Send the money back the way it came; undo the refund here if that fails
+	referenceField, refundReference, err := refundPayment(ctx, invoice, amount, uid)
+	if err != nil {
+		_, revertErr := session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+			revert := bson.M{"$set": bson.M{
+				"payment_status": *invoice.Payment_status,
+				"refunded_total": invoice.Refunded_total,
+				"updated_at":     time.Now(),
+			}}
+			if _, err := invoiceCollection.UpdateOne(sessCtx, bson.M{"invoice_id": invoiceId, "refunded_total": refundedTotal}, revert); err != nil {
+				return nil, err
+			}
+			if _, err := creditNoteCollection.DeleteOne(sessCtx, bson.M{"credit_note_id": creditNote.Credit_note_id}); err != nil {
+				return nil, err
+			}
+			if invoice.Order_id != nil {
+				previous := "Order Paid"
+				if *invoice.Payment_status == invoicePartiallyRefunded {
+					previous = "Order Partially Refunded"
+				}
+				_, err := orderCollection.UpdateOne(sessCtx,
+					bson.M{"order_id": *invoice.Order_id, "status": orderStatus},
+					bson.M{"$set": bson.M{"status": previous, "updated_at": time.Now()}},
+				)
+				return nil, err
+			}
+			return nil, nil
+		})
+		if revertErr != nil {
+			log.Printf("invoice %s: failed to undo refund of %.2f after payment refund error: %v", invoiceId, amount, revertErr)
+		}
+		http.Error(w, `{"success": false, "message": "Could not return the money to the original payment"}`, http.StatusBadGateway)
+		return
+	}
+
+	// The reference is stored once; the credit note already stands for the refund either way
+	if refundReference != "" {
+		_, err := creditNoteCollection.UpdateOne(ctx,
+			bson.M{"credit_note_id": creditNote.Credit_note_id, referenceField: bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{referenceField: refundReference}},
+		)
+		if err != nil {
+			log.Printf("invoice %s: refund %s went through but its reference was not saved on credit note %s: %v", invoiceId, refundReference, creditNote.Credit_note_id, err)
+		}
+		if referenceField == "provider_refund_id" {
+			creditNote.Provider_refund_id = refundReference
+		} else {
+			creditNote.Gift_card_entry_id = refundReference
+		}
+	}
+
+	// Points earned on the refunded share are taken back, and points redeemed on it given back
+	if err := refundLoyaltyPoints(ctx, invoice, amount, fullRefund, uid); err != nil {
+		log.Printf("invoice %s: failed to adjust loyalty points after refund: %v", invoiceId, err)
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Invoice refunded successfully",
+		"data": map[string]interface{}{
+			"invoice_id":     invoice.Invoice_id,
+			"payment_status": newStatus,
+			"total_price":    invoice.TotalPrice,
+			"refunded_total": refundedTotal,
+			"credit_note":    creditNote,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetInvoiceCreditNotes lists the credit notes issued against an invoice.
+func GetInvoiceCreditNotes(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	invoiceId := mux.Vars(r)["invoice_id"]
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := creditNoteCollection.Find(ctx, bson.M{"invoice_id": invoiceId}, opts)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving credit notes"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	creditNotes := []models.CreditNote{}
+	if err := cursor.All(ctx, &creditNotes); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding credit notes"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Credit notes retrieved successfully",
+		"data":    creditNotes,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetCreditNotes lists credit notes in a date range (RFC3339 from/to) with the refunded total.
+func GetCreditNotes(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	recordPerPage, err := strconv.Atoi(r.URL.Query().Get("recordPerPage"))
+	if err != nil || recordPerPage < 1 {
+		recordPerPage = 10
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	skip := (page - 1) * recordPerPage
+
+	filter := outletScope(r, bson.M{})
+	createdAt := bson.M{}
+	if from := r.URL.Query().Get("from"); from != "" {
+		fromTime, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid from time, expected RFC3339"}`, http.StatusBadRequest)
+			return
+		}
+		createdAt["$gte"] = fromTime
+	}
+	if to := r.URL.Query().Get("to"); to != "" {
+		toTime, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid to time, expected RFC3339"}`, http.StatusBadRequest)
+			return
+		}
+		createdAt["$lte"] = toTime
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64(skip)).
+		SetLimit(int64(recordPerPage))
+
+	cursor, err := creditNoteCollection.Find(ctx, filter, opts)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving credit notes"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	creditNotes := []models.CreditNote{}
+	if err := cursor.All(ctx, &creditNotes); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding credit notes"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Totals cover the whole range, not just the current page
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "refunded_total", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+		}}},
+	}
+	totalsCursor, err := creditNoteCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error totalling credit notes"}`, http.StatusInternalServerError)
+		return
+	}
+	defer totalsCursor.Close(ctx)
+
+	var totals []struct {
+		Count          int64   `bson:"count"`
+		Refunded_total float64 `bson:"refunded_total"`
+	}
+	if err := totalsCursor.All(ctx, &totals); err != nil {
+		http.Error(w, `{"success": false, "message": "Error totalling credit notes"}`, http.StatusInternalServerError)
+		return
+	}
+	var totalCount int64
+	var refundedTotal float64
+	if len(totals) > 0 {
+		totalCount = totals[0].Count
+		refundedTotal = helper.RoundMoney(totals[0].Refunded_total)
+	}
+
+	response := map[string]interface{}{
+		"success":        true,
+		"message":        "Credit notes retrieved successfully",
+		"data":           creditNotes,
+		"refunded_total": refundedTotal,
+		"pagination": map[string]interface{}{
+			"current_page":       page,
+			"records_per_page":   recordPerPage,
+			"total_credit_notes": totalCount,
+			"total_pages":        (totalCount + int64(recordPerPage) - 1) / int64(recordPerPage),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
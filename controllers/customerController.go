@@ -0,0 +1,466 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var customerProfileCollection *mongo.Collection = database.OpenCollection(database.Client, "customer_profile")
+
+// customerUserFilter narrows a user filter to customers: users with the USER role, or none. They
+// belong to no outlet, so their profile and history are shared by every outlet.
+func customerUserFilter(filter bson.M) bson.M {
+	filter["role"] = bson.M{"$in": bson.A{"USER", nil}}
+	return filter
+}
+
+// Orders that never reached the table are not visits
+var missedOrderStatuses = []string{"Order Cancelled", "Order Rejected"}
+
+// How many favourite dishes and recent visits a profile shows
+const (
+	favouriteDishCount = 5
+	recentVisitCount   = 10
+)
+
+var nonDigits = regexp.MustCompile(`\D`)
+
+// phonePattern matches stored phone numbers ending in the digits of phone, whatever the spacing
+// or punctuation, so a number looks up with or without its country code. It needs at least 6
+// digits.
+func phonePattern(phone string) (string, bool) {
+	digits := nonDigits.ReplaceAllString(phone, "")
+	if len(digits) < 6 {
+		return "", false
+	}
+	return strings.Join(strings.Split(digits, ""), `\D*`) + `\D*$`, true
+}
+
+// checkCustomer makes sure a user is a customer. It returns why not, or "".
+func checkCustomer(ctx context.Context, userId string) (string, error) {
+	count, err := userCollection.CountDocuments(ctx, bson.M{"user_id": userId})
+	if err != nil {
+		return "", err
+	}
+	if count == 0 {
+		return "Customer not found", nil
+	}
+	count, err = userCollection.CountDocuments(ctx, customerUserFilter(bson.M{"user_id": userId}))
+	if err != nil {
+		return "", err
+	}
+	if count == 0 {
+		return "User is not a customer", nil
+	}
+	return "", nil
+}
+
+// canActForCustomer reports whether the caller may put an order or points on a customer's
+// account: staff for any customer, everyone else only for themselves
+func canActForCustomer(r *http.Request, customerId string) bool {
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	return staffRoles[middleware.GetRoleFromContext(r)] || customerId == uid
+}
+
+// customerOrderFilter matches the customers' visits. Orders from before customer_id was recorded
+// were placed under the customer's own user_id.
+func customerOrderFilter(userIds []string) bson.M {
+	return activeFilter(bson.M{
+		"status": bson.M{"$nin": missedOrderStatuses},
+		"$or": bson.A{
+			bson.M{"customer_id": bson.M{"$in": userIds}},
+			bson.M{"customer_id": nil, "user_id": bson.M{"$in": userIds}},
+		},
+	})
+}
+
+type favouriteDish struct {
+	Food_id  string `json:"food_id" bson:"food_id"`
+	Name     string `json:"name" bson:"name"`
+	Quantity int64  `json:"quantity" bson:"quantity"`
+}
+
+type customerVisit struct {
+	Order_id  string    `json:"order_id"`
+	Outlet_id string    `json:"outlet_id,omitempty"`
+	Date      time.Time `json:"date"`
+	Covers    *int      `json:"covers,omitempty"`
+	Spend     float64   `json:"spend"`
+}
+
+type customerHistory struct {
+	Visits           int64           `json:"visits"`
+	Lifetime_spend   float64         `json:"lifetime_spend"` // paid invoices less refunds
+	Average_spend    float64         `json:"average_spend"`
+	First_visit      *time.Time      `json:"first_visit"`
+	Last_visit       *time.Time      `json:"last_visit"`
+	Favourite_dishes []favouriteDish `json:"favourite_dishes,omitempty"`
+	Recent_visits    []customerVisit `json:"recent_visits,omitempty"`
+}
+
+// customerOrders loads the customers' visits, newest first, and what each order was paid less
+// refunds, with one query for all the orders and one for their invoices
+func customerOrders(ctx context.Context, userIds []string) (map[string][]models.Order, map[string]float64, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := orderCollection.Find(ctx, customerOrderFilter(userIds), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	var orders []models.Order
+	if err := cursor.All(ctx, &orders); err != nil {
+		return nil, nil, err
+	}
+
+	ordersByCustomer := make(map[string][]models.Order)
+	orderIds := make([]string, 0, len(orders))
+	for _, order := range orders {
+		customerId := ""
+		if order.Customer_id != nil {
+			customerId = *order.Customer_id
+		} else if order.User_id != nil {
+			customerId = *order.User_id
+		}
+		ordersByCustomer[customerId] = append(ordersByCustomer[customerId], order)
+		orderIds = append(orderIds, order.Order_id)
+	}
+	if len(orderIds) == 0 {
+		return ordersByCustomer, map[string]float64{}, nil
+	}
+
+	spendPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: activeFilter(bson.M{
+			"order_id":       bson.M{"$in": orderIds},
+			"payment_status": bson.M{"$in": salesInvoiceStatuses},
+		})}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$order_id"},
+			{Key: "spend", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$subtract", Value: bson.A{
+				"$total_price", bson.D{{Key: "$ifNull", Value: bson.A{"$refunded_total", 0}}},
+			}}}}}},
+		}}},
+	}
+	cursor, err = invoiceCollection.Aggregate(ctx, spendPipeline)
+	if err != nil {
+		return nil, nil, err
+	}
+	var spends []struct {
+		Order_id string  `bson:"_id"`
+		Spend    float64 `bson:"spend"`
+	}
+	if err := cursor.All(ctx, &spends); err != nil {
+		return nil, nil, err
+	}
+	spendByOrder := make(map[string]float64, len(spends))
+	for _, spend := range spends {
+		spendByOrder[spend.Order_id] = spend.Spend
+	}
+	return ordersByCustomer, spendByOrder, nil
+}
+
+// summarizeVisits counts a customer's visits and spend from their orders, newest first
+func summarizeVisits(orders []models.Order, spendByOrder map[string]float64) customerHistory {
+	var history customerHistory
+	history.Visits = int64(len(orders))
+	if len(orders) == 0 {
+		return history
+	}
+	history.Last_visit = &orders[0].Created_at
+	history.First_visit = &orders[len(orders)-1].Created_at
+	for _, order := range orders {
+		history.Lifetime_spend += spendByOrder[order.Order_id]
+	}
+	history.Lifetime_spend = helper.RoundMoney(history.Lifetime_spend)
+	history.Average_spend = helper.RoundMoney(history.Lifetime_spend / float64(history.Visits))
+	return history
+}
+
+// customerHistories works out the visits and spend of several customers at once, for lookups
+func customerHistories(ctx context.Context, userIds []string) (map[string]customerHistory, error) {
+	ordersByCustomer, spendByOrder, err := customerOrders(ctx, userIds)
+	if err != nil {
+		return nil, err
+	}
+	histories := make(map[string]customerHistory, len(userIds))
+	for _, userId := range userIds {
+		histories[userId] = summarizeVisits(ordersByCustomer[userId], spendByOrder)
+	}
+	return histories, nil
+}
+
+// customerHistoryOf works out a customer's visits and spend, favourite dishes and recent visits
+func customerHistoryOf(ctx context.Context, userId string) (customerHistory, error) {
+	ordersByCustomer, spendByOrder, err := customerOrders(ctx, []string{userId})
+	if err != nil {
+		return customerHistory{}, err
+	}
+	orders := ordersByCustomer[userId]
+	history := summarizeVisits(orders, spendByOrder)
+	if len(orders) == 0 {
+		return history, nil
+	}
+
+	orderIds := make([]string, 0, len(orders))
+	for _, order := range orders {
+		orderIds = append(orderIds, order.Order_id)
+	}
+
+	for _, order := range orders[:min(len(orders), recentVisitCount)] {
+		history.Recent_visits = append(history.Recent_visits, customerVisit{
+			Order_id:  order.Order_id,
+			Outlet_id: order.Outlet_id,
+			Date:      order.Created_at,
+			Covers:    order.Covers,
+			Spend:     helper.RoundMoney(spendByOrder[order.Order_id]),
+		})
+	}
+
+	// Dishes are counted by name, as order items from before priced lines only have the food
+	// name to quantity map; those get their food_id from the food of that name
+	dishPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"order_id": bson.M{"$in": orderIds}}}},
+		{{Key: "$project", Value: bson.D{{Key: "lines", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$gt", Value: bson.A{bson.D{{Key: "$size", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$lines", bson.A{}}}}}}, 0}}},
+			"$lines",
+			bson.D{{Key: "$map", Value: bson.D{
+				{Key: "input", Value: bson.D{{Key: "$objectToArray", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$items", bson.D{}}}}}}},
+				{Key: "as", Value: "item"},
+				{Key: "in", Value: bson.D{{Key: "name", Value: "$$item.k"}, {Key: "quantity", Value: "$$item.v"}}},
+			}}},
+		}}}}}}},
+		{{Key: "$unwind", Value: "$lines"}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$lines.name"},
+			{Key: "food_id", Value: bson.D{{Key: "$max", Value: "$lines.food_id"}}},
+			{Key: "quantity", Value: bson.D{{Key: "$sum", Value: "$lines.quantity"}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "quantity", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: favouriteDishCount}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "food"},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "name"},
+			{Key: "as", Value: "food"},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "quantity", Value: 1},
+			{Key: "name", Value: "$_id"},
+			{Key: "food_id", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$food_id", bson.D{{Key: "$arrayElemAt", Value: bson.A{"$food.food_id", 0}}}, ""}}}},
+		}}},
+	}
+	cursor, err := orderItemCollection.Aggregate(ctx, dishPipeline)
+	if err != nil {
+		return history, err
+	}
+	if err := cursor.All(ctx, &history.Favourite_dishes); err != nil {
+		return history, err
+	}
+	return history, nil
+}
+
+// customerProfileOf returns what staff recorded about a customer, empty when nothing yet
+func customerProfileOf(ctx context.Context, userId string) (models.CustomerProfile, error) {
+	profile := models.CustomerProfile{User_id: userId, Allergies: []string{}}
+	err := customerProfileCollection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&profile)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return profile, nil
+	}
+	return profile, err
+}
+
+// customerData is a customer as hosts see them
+func customerData(user models.User, profile models.CustomerProfile, history customerHistory) map[string]interface{} {
+	return map[string]interface{}{
+		"user_id":    user.User_id,
+		"first_name": user.First_name,
+		"last_name":  user.Last_name,
+		"email":      user.Email,
+		"phone":      user.Phone,
+		"allergies":  profile.Allergies,
+		"notes":      profile.Notes,
+		"history":    history,
+	}
+}
+
+// FindCustomers looks customers up by ?phone=, for hosts seating a party or taking a booking
+func FindCustomers(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	pattern, ok := phonePattern(r.URL.Query().Get("phone"))
+	if !ok {
+		http.Error(w, `{"success": false, "message": "phone must have at least 6 digits"}`, http.StatusBadRequest)
+		return
+	}
+
+	filter := customerUserFilter(bson.M{"phone": bson.M{"$regex": pattern}})
+	cursor, err := userCollection.Find(ctx, filter, options.Find().SetLimit(10))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving customers"}`, http.StatusInternalServerError)
+		return
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding customers"}`, http.StatusInternalServerError)
+		return
+	}
+
+	userIds := make([]string, 0, len(users))
+	for _, user := range users {
+		userIds = append(userIds, user.User_id)
+	}
+
+	// One query each for the profiles and the histories of every match
+	cursor, err = customerProfileCollection.Find(ctx, bson.M{"user_id": bson.M{"$in": userIds}})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving customer profile"}`, http.StatusInternalServerError)
+		return
+	}
+	var profiles []models.CustomerProfile
+	if err := cursor.All(ctx, &profiles); err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving customer profile"}`, http.StatusInternalServerError)
+		return
+	}
+	profileByUser := make(map[string]models.CustomerProfile, len(profiles))
+	for _, profile := range profiles {
+		profileByUser[profile.User_id] = profile
+	}
+	histories, err := customerHistories(ctx, userIds)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving customer history"}`, http.StatusInternalServerError)
+		return
+	}
+
+	customers := make([]map[string]interface{}, 0, len(users))
+	for _, user := range users {
+		profile, ok := profileByUser[user.User_id]
+		if !ok {
+			profile = models.CustomerProfile{User_id: user.User_id, Allergies: []string{}}
+		}
+		customers = append(customers, customerData(user, profile, histories[user.User_id]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Customers retrieved successfully",
+		"data":    customers,
+	})
+}
+
+// GetCustomer returns a customer's profile with their visits, spend, favourite dishes and recent visits
+func GetCustomer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	userId := mux.Vars(r)["user_id"]
+	var user models.User
+	err := userCollection.FindOne(ctx, customerUserFilter(bson.M{"user_id": userId})).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Customer not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving customer"}`, http.StatusInternalServerError)
+		return
+	}
+
+	profile, err := customerProfileOf(ctx, userId)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving customer profile"}`, http.StatusInternalServerError)
+		return
+	}
+	history, err := customerHistoryOf(ctx, userId)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving customer history"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Customer retrieved successfully",
+		"data":    customerData(user, profile, history),
+	})
+}
+
+// UpdateCustomerProfile records a customer's allergies and notes. Allergies replace the list.
+func UpdateCustomerProfile(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	userId := mux.Vars(r)["user_id"]
+	var requestBody struct {
+		Allergies *[]string `json:"allergies"`
+		Notes     *string   `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if requestBody.Allergies == nil && requestBody.Notes == nil {
+		http.Error(w, `{"success": false, "message": "No fields to update"}`, http.StatusBadRequest)
+		return
+	}
+
+	msg, err := checkCustomer(ctx, userId)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking customer"}`, http.StatusInternalServerError)
+		return
+	}
+	if msg != "" {
+		http.Error(w, `{"success": false, "message": "`+msg+`"}`, http.StatusNotFound)
+		return
+	}
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	set := bson.M{"updated_by": uid, "updated_at": time.Now()}
+	if requestBody.Allergies != nil {
+		allergies := []string{}
+		for _, allergy := range *requestBody.Allergies {
+			if allergy = strings.TrimSpace(allergy); allergy != "" {
+				allergies = append(allergies, allergy)
+			}
+		}
+		set["allergies"] = allergies
+	}
+	if requestBody.Notes != nil {
+		notes := strings.TrimSpace(*requestBody.Notes)
+		if validationErr := validate.Var(notes, "max=1000"); validationErr != nil {
+			http.Error(w, `{"success": false, "message": "Notes can be at most 1000 characters"}`, http.StatusBadRequest)
+			return
+		}
+		set["notes"] = notes
+	}
+
+	var profile models.CustomerProfile
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	update := bson.M{"$set": set, "$setOnInsert": bson.M{"user_id": userId}}
+	if requestBody.Allergies == nil {
+		update["$setOnInsert"] = bson.M{"user_id": userId, "allergies": []string{}}
+	}
+	err = customerProfileCollection.FindOneAndUpdate(ctx, bson.M{"user_id": userId}, update, opts).Decode(&profile)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to update customer profile"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Customer profile updated successfully",
+		"data":    profile,
+	})
+}
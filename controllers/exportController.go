@@ -0,0 +1,218 @@
+package controller
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Streamed exports can run far longer than a normal request
+const exportTimeout = 15 * time.Minute
+
+// exportFormat reads ?format=csv|xlsx. It returns "" when a JSON response is wanted,
+// and false once it has answered 400 for any other format.
+func exportFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	value := r.URL.Query().Get("format")
+	if value == "" || strings.EqualFold(value, "json") {
+		return "", true
+	}
+	format, ok := helper.ExportFormat(value)
+	if !ok {
+		http.Error(w, `{"success": false, "message": "format must be csv or xlsx"}`, http.StatusBadRequest)
+		return "", false
+	}
+	return format, true
+}
+
+// writeExport writes a table built in memory, such as a report, as a spreadsheet download
+func writeExport(w http.ResponseWriter, format, name string, header []interface{}, rows [][]interface{}) {
+	table, err := helper.NewTableWriter(w, format, name)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error starting export"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := table.WriteRow(header...); err != nil {
+		log.Printf("export %s: %v", name, err)
+		return
+	}
+	for _, row := range rows {
+		if err := table.WriteRow(row...); err != nil {
+			log.Printf("export %s: %v", name, err)
+			return
+		}
+	}
+	if err := table.Close(); err != nil {
+		log.Printf("export %s: %v", name, err)
+	}
+}
+
+// streamExport writes the header, then one or more rows per document as the cursor yields them.
+// Once the first byte is out the status is sent, so a later failure is logged and ends the file
+// with an error row rather than leaving it to look complete.
+func streamExport(ctx context.Context, w http.ResponseWriter, format, name string, header []interface{}, cursor *mongo.Cursor, rows func(*mongo.Cursor) ([][]interface{}, error)) {
+	defer cursor.Close(ctx)
+
+	table, err := helper.NewTableWriter(w, format, name)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error starting export"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := table.WriteRow(header...); err != nil {
+		log.Printf("export %s: %v", name, err)
+		return
+	}
+	for cursor.Next(ctx) {
+		docRows, err := rows(cursor)
+		if err != nil {
+			log.Printf("export %s: decoding: %v", name, err)
+			endIncompleteExport(table, name)
+			return
+		}
+		for _, row := range docRows {
+			if err := table.WriteRow(row...); err != nil {
+				log.Printf("export %s: %v", name, err)
+				return
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		log.Printf("export %s: cursor: %v", name, err)
+		endIncompleteExport(table, name)
+		return
+	}
+	if err := table.Close(); err != nil {
+		log.Printf("export %s: %v", name, err)
+	}
+}
+
+// endIncompleteExport marks a stream that stopped early, so the download cannot pass for the full export
+func endIncompleteExport(table helper.TableWriter, name string) {
+	if err := table.WriteRow("ERROR: export incomplete, rows are missing; download it again or narrow the filters"); err != nil {
+		return
+	}
+	if err := table.Close(); err != nil {
+		log.Printf("export %s: %v", name, err)
+	}
+}
+
+// requiredExportFormat is exportFormat for endpoints that only export, defaulting to CSV
+func requiredExportFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	format, ok := exportFormat(w, r)
+	if ok && format == "" {
+		format = helper.ExportCSV
+	}
+	return format, ok
+}
+
+// ExportInvoices streams the invoices matching the GET /invoices filters as CSV or XLSX (?format=)
+func ExportInvoices(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	format, ok := requiredExportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := invoiceCollection.Find(ctx, invoiceListFilter(r), opts)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving invoices"}`, http.StatusInternalServerError)
+		return
+	}
+
+	header := []interface{}{
+		"invoice_number", "invoice_id", "order_id", "user_id", "payment_status", "payment_method",
+		"subtotal", "discount_amount", "tax_amount", "total_price", "tip_amount", "refunded_total",
+		"payment_reference", "payment_date", "created_at", "deleted_at",
+	}
+	streamExport(ctx, w, format, "invoices", header, cursor, func(cursor *mongo.Cursor) ([][]interface{}, error) {
+		var invoice models.Invoice
+		if err := cursor.Decode(&invoice); err != nil {
+			return nil, err
+		}
+		return [][]interface{}{{
+			invoice.Invoice_number, invoice.Invoice_id, invoice.Order_id, invoice.User_id, invoice.Payment_status, invoice.Payment_method,
+			invoice.Subtotal, invoice.Discount_amount, invoice.Tax_amount, invoice.TotalPrice, invoice.Tip_amount, invoice.Refunded_total,
+			invoice.Payment_reference, invoice.Payment_date, invoice.Created_at, invoice.Deleted_at,
+		}}, nil
+	})
+}
+
+// ExportOrders streams the orders matching the GET /orders filters as CSV or XLSX (?format=),
+// one row per food ordered. Orders with nothing on them get a single row.
+func ExportOrders(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	format, ok := requiredExportFormat(w, r)
+	if !ok {
+		return
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: listFilter(r, bson.M{})}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "orderitems"},
+			{Key: "localField", Value: "order_id"},
+			{Key: "foreignField", Value: "order_id"},
+			{Key: "as", Value: "items"},
+		}}},
+	}
+	cursor, err := orderCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving orders"}`, http.StatusInternalServerError)
+		return
+	}
+
+	header := []interface{}{
+		"order_id", "order_date", "table_id", "user_id", "waiter_id", "covers", "status", "created_at",
+		"order_item_id", "food_id", "food_name", "quantity", "unit_price", "amount",
+	}
+	streamExport(ctx, w, format, "orders", header, cursor, func(cursor *mongo.Cursor) ([][]interface{}, error) {
+		var order struct {
+			models.Order `bson:",inline"`
+			Items        []models.OrderItem `bson:"items"`
+		}
+		if err := cursor.Decode(&order); err != nil {
+			return nil, err
+		}
+		orderCells := []interface{}{
+			order.Order_id, order.Order_Date, order.Table_id, order.User_id, order.Waiter_id, order.Covers, order.Status, order.Created_at,
+		}
+
+		var rows [][]interface{}
+		for _, item := range order.Items {
+			for _, line := range item.Lines {
+				row := append(append([]interface{}{}, orderCells...), item.Order_item_id, line.Food_id, line.Name, line.Quantity, line.Unit_price, line.Amount)
+				rows = append(rows, row)
+			}
+			// Order items from before lines were priced only have quantities by food name
+			if len(item.Lines) == 0 {
+				names := make([]string, 0, len(item.Items))
+				for name := range item.Items {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					row := append(append([]interface{}{}, orderCells...), item.Order_item_id, nil, name, item.Items[name], nil, nil)
+					rows = append(rows, row)
+				}
+			}
+		}
+		if len(rows) == 0 {
+			rows = append(rows, append(orderCells, nil, nil, nil, nil, nil, nil))
+		}
+		return rows, nil
+	})
+}
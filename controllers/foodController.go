@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -36,39 +37,59 @@ func GetFoods(w http.ResponseWriter, r *http.Request) {
 
 	startIndex := (page - 1) * recordPerPage
 
-	totalFoods, err := foodCollection.CountDocuments(ctx, bson.M{})
-	if err != nil {
-		http.Error(w, `{"success": false, "message": "Error retrieving total food count"}`, http.StatusInternalServerError)
-		return
-	}
-
-	matchStage := bson.D{{Key: "$match", Value: bson.D{}}}
-	skipStage := bson.D{{Key: "$skip", Value: startIndex}}
-	limitStage := bson.D{{Key: "$limit", Value: int64(recordPerPage)}}
-	projectStage := bson.D{
-		{Key: "$project", Value: bson.D{
-			{Key: "_id", Value: 0},
-			{Key: "food_id", Value: 1},
-			{Key: "name", Value: 1},
-			{Key: "price", Value: 1},
-			{Key: "food_image", Value: 1},
-			{Key: "menu_id", Value: 1},
-			{Key: "created_at", Value: 1},
-			{Key: "updated_at", Value: 1},
+	// Foods as the caller's outlet sells them, counted and paged in one pass
+	pipeline := append(mongo.Pipeline{
+		{{Key: "$match", Value: menuListFilter(r, bson.M{})}},
+	}, effectiveFoodStages(requestOutletId(r))...)
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "foods", Value: bson.A{
+			bson.D{{Key: "$skip", Value: startIndex}},
+			bson.D{{Key: "$limit", Value: int64(recordPerPage)}},
+			bson.D{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "food_id", Value: 1},
+				{Key: "name", Value: 1},
+				{Key: "price", Value: 1},
+				{Key: "cost", Value: 1},
+				{Key: "food_image", Value: 1},
+				{Key: "available", Value: 1},
+				{Key: "overridden", Value: 1},
+				{Key: "menu_id", Value: 1},
+				{Key: "outlet_id", Value: 1},
+				{Key: "created_at", Value: 1},
+				{Key: "updated_at", Value: 1},
+				{Key: "deleted_at", Value: 1},
+				{Key: "deleted_by", Value: 1},
+			}}},
 		}},
-	}
+		{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "count"}}}},
+	}}})
 
-	result, err := foodCollection.Aggregate(ctx, mongo.Pipeline{matchStage, skipStage, limitStage, projectStage})
+	cursor, err := foodCollection.Aggregate(ctx, pipeline)
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error retrieving food items"}`, http.StatusInternalServerError)
 		return
 	}
+	defer cursor.Close(ctx)
 
-	var allFoods []bson.M
-	if err = result.All(ctx, &allFoods); err != nil {
+	var facets []struct {
+		Foods []bson.M `bson:"foods"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
 		http.Error(w, `{"success": false, "message": "Error decoding food data"}`, http.StatusInternalServerError)
 		return
 	}
+	allFoods := []bson.M{}
+	var totalFoods int64
+	if len(facets) > 0 {
+		allFoods = facets[0].Foods
+		if len(facets[0].Total) > 0 {
+			totalFoods = facets[0].Total[0].Count
+		}
+	}
 
 	response := map[string]interface{}{
 		"success": true,
@@ -94,8 +115,15 @@ func GetFood(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	foodId := params["food_id"]
 
+	// An outlet sees the food with its own overrides; head office without an outlet sees it as stored
 	var food models.Food
-	if err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&food); err != nil {
+	var err error
+	if outletId := requestOutletId(r); outletId != "" {
+		food, err = effectiveFood(ctx, outletId, foodId)
+	} else {
+		err = foodCollection.FindOne(ctx, activeFilter(bson.M{"food_id": foodId})).Decode(&food)
+	}
+	if err != nil {
 		http.Error(w, `{"success": false, "message": "Food item not found"}`, http.StatusNotFound)
 		return
 	}
@@ -108,6 +136,7 @@ func GetFood(w http.ResponseWriter, r *http.Request) {
 			"food_id":    food.Food_id,
 			"name":       food.Name,
 			"price":      food.Price,
+			"cost":       food.Cost,
 			"food_image": food.Food_image,
 			"menu_id":    food.Menu_id,
 			"created_at": food.Created_at,
@@ -138,10 +167,31 @@ func CreateFood(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The food belongs to the menu's outlet. Added to a master menu by an outlet, it is that
+	// outlet's own item.
+	var menu models.Menu
+	err = menuCollection.FindOne(ctx, activeFilter(menuScope(r, bson.M{"menu_id": *food.Menu_id}))).Decode(&menu)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Menu not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking menu existence"}`, http.StatusInternalServerError)
+		return
+	}
+
+	food.Outlet_id = menu.Outlet_id
+	if food.Outlet_id == "" {
+		food.Outlet_id = requestOutletId(r)
+	}
+	if food.Outlet_id == "" && !canEditMasterMenu(r) {
+		http.Error(w, `{"success": false, "message": "The master menu is managed by head office"}`, http.StatusForbidden)
+		return
+	}
+
 	uniqueFoodID := *food.Menu_id + "-" + *food.Name
 	food.UniqueFoodID = uniqueFoodID
 
-	existingCount, err := foodCollection.CountDocuments(ctx, bson.M{"unique_food_id": uniqueFoodID})
+	existingCount, err := foodCollection.CountDocuments(ctx, activeFilter(masterOrOutlet(bson.M{"unique_food_id": uniqueFoodID}, food.Outlet_id)))
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error checking existing food items"}`, http.StatusInternalServerError)
 		return
@@ -172,6 +222,7 @@ func CreateFood(w http.ResponseWriter, r *http.Request) {
 			"food_id":    food.Food_id,
 			"name":       food.Name,
 			"price":      food.Price,
+			"cost":       food.Cost,
 			"food_image": food.Food_image,
 			"menu_id":    food.Menu_id,
 			"created_at": food.Created_at,
@@ -188,13 +239,13 @@ func DeleteFood(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	foodId := params["food_id"]
 
-	result, err := foodCollection.DeleteOne(ctx, bson.M{"food_id": foodId})
+	result, err := softDelete(ctx, r, foodCollection, bson.M{"food_id": foodId}, time.Now())
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error deleting food item"}`, http.StatusInternalServerError)
 		return
 	}
 
-	if result.DeletedCount == 0 {
+	if result.MatchedCount == 0 {
 		http.Error(w, `{"success": false, "message": "No food item found"}`, http.StatusNotFound)
 		return
 	}
@@ -206,7 +257,8 @@ func DeleteFood(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Get all foods for a specific menu
+// GetFoodsByMenu lists a menu's foods as the caller's outlet sells them: the master's foods with
+// the outlet's overrides applied and hidden ones left out, plus the outlet's own additions
 func GetFoodsByMenu(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 	defer cancel()
@@ -222,7 +274,7 @@ func GetFoodsByMenu(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if menu exists
-	menuCount, err := menuCollection.CountDocuments(ctx, bson.M{"_id": menuObjID})
+	menuCount, err := menuCollection.CountDocuments(ctx, menuListFilter(r, bson.M{"_id": menuObjID}))
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error checking menu existence"}`, http.StatusInternalServerError)
 		return
@@ -245,42 +297,59 @@ func GetFoodsByMenu(w http.ResponseWriter, r *http.Request) {
 
 	startIndex := (page - 1) * recordPerPage
 
-	// Get total food count for the menu
-	totalFoods, err := foodCollection.CountDocuments(ctx, bson.M{"menu_id": menuId})
-	if err != nil {
-		http.Error(w, `{"success": false, "message": "Error retrieving total food count"}`, http.StatusInternalServerError)
-		return
-	}
-
-	// Fetch paginated food items linked to this menu
-	matchStage := bson.D{{Key: "$match", Value: bson.D{{Key: "menu_id", Value: menuId}}}}
-	skipStage := bson.D{{Key: "$skip", Value: startIndex}}
-	limitStage := bson.D{{Key: "$limit", Value: int64(recordPerPage)}}
-	projectStage := bson.D{
-		{Key: "$project", Value: bson.D{
-			{Key: "_id", Value: 0},
-			{Key: "food_id", Value: 1},
-			{Key: "name", Value: 1},
-			{Key: "price", Value: 1},
-			{Key: "food_image", Value: 1},
-			{Key: "menu_id", Value: 1},
-			{Key: "created_at", Value: 1},
-			{Key: "updated_at", Value: 1},
+	// Resolve the effective menu, then count and page through it in one pass
+	pipeline := append(mongo.Pipeline{
+		{{Key: "$match", Value: menuListFilter(r, bson.M{"menu_id": menuId})}},
+	}, effectiveFoodStages(requestOutletId(r))...)
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "foods", Value: bson.A{
+			bson.D{{Key: "$skip", Value: startIndex}},
+			bson.D{{Key: "$limit", Value: int64(recordPerPage)}},
+			bson.D{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "food_id", Value: 1},
+				{Key: "name", Value: 1},
+				{Key: "price", Value: 1},
+				{Key: "cost", Value: 1},
+				{Key: "food_image", Value: 1},
+				{Key: "available", Value: 1},
+				{Key: "overridden", Value: 1},
+				{Key: "menu_id", Value: 1},
+				{Key: "outlet_id", Value: 1},
+				{Key: "created_at", Value: 1},
+				{Key: "updated_at", Value: 1},
+				{Key: "deleted_at", Value: 1},
+				{Key: "deleted_by", Value: 1},
+			}}},
 		}},
-	}
+		{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "count"}}}},
+	}}})
 
-	cursor, err := foodCollection.Aggregate(ctx, mongo.Pipeline{matchStage, skipStage, limitStage, projectStage})
+	cursor, err := foodCollection.Aggregate(ctx, pipeline)
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error retrieving food items"}`, http.StatusInternalServerError)
 		return
 	}
 	defer cursor.Close(ctx)
 
-	var foodItems []bson.M
-	if err := cursor.All(ctx, &foodItems); err != nil {
+	var facets []struct {
+		Foods []bson.M `bson:"foods"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
 		http.Error(w, `{"success": false, "message": "Error decoding food items"}`, http.StatusInternalServerError)
 		return
 	}
+	foodItems := []bson.M{}
+	var totalFoods int64
+	if len(facets) > 0 {
+		foodItems = facets[0].Foods
+		if len(facets[0].Total) > 0 {
+			totalFoods = facets[0].Total[0].Count
+		}
+	}
 
 	response := map[string]interface{}{
 		"success": true,
@@ -314,7 +383,7 @@ func UpdateFood(w http.ResponseWriter, r *http.Request) {
 
 	// Fetch existing food details
 	var existingFood models.Food
-	if err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&existingFood); err != nil {
+	if err := foodCollection.FindOne(ctx, activeFilter(bson.M{"food_id": foodId})).Decode(&existingFood); err != nil {
 		http.Error(w, `{"success": false, "message": "Food item not found"}`, http.StatusNotFound)
 		return
 	}
@@ -325,7 +394,7 @@ func UpdateFood(w http.ResponseWriter, r *http.Request) {
 	if food.Name != nil && *food.Name != *existingFood.Name {
 		newUniqueFoodID := *existingFood.Menu_id + "-" + *food.Name
 
-		duplicateCount, err := foodCollection.CountDocuments(ctx, bson.M{"unique_food_id": newUniqueFoodID})
+		duplicateCount, err := foodCollection.CountDocuments(ctx, activeFilter(masterOrOutlet(bson.M{"unique_food_id": newUniqueFoodID}, existingFood.Outlet_id)))
 		if err != nil {
 			http.Error(w, `{"success": false, "message": "Error checking duplicate food items"}`, http.StatusInternalServerError)
 			return
@@ -342,11 +411,35 @@ func UpdateFood(w http.ResponseWriter, r *http.Request) {
 	if food.Price != nil {
 		updateObj["price"] = food.Price
 	}
+	if food.Cost != nil {
+		if *food.Cost < 0 {
+			http.Error(w, `{"success": false, "message": "Cost cannot be negative"}`, http.StatusBadRequest)
+			return
+		}
+		updateObj["cost"] = food.Cost
+	}
 	if food.Food_image != nil {
 		updateObj["food_image"] = food.Food_image
 	}
+	// On a master food this is the default; outlets override it for themselves
+	if food.Available != nil {
+		updateObj["available"] = food.Available
+	}
 	if food.Menu_id != nil {
+		// A food moved to an outlet's menu becomes that outlet's; on a master menu it keeps its outlet
+		var menu models.Menu
+		err := menuCollection.FindOne(ctx, activeFilter(menuScope(r, bson.M{"menu_id": *food.Menu_id}))).Decode(&menu)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, `{"success": false, "message": "Menu not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, `{"success": false, "message": "Error checking menu existence"}`, http.StatusInternalServerError)
+			return
+		}
 		updateObj["menu_id"] = food.Menu_id
+		if menu.Outlet_id != "" {
+			updateObj["outlet_id"] = menu.Outlet_id
+		}
 	}
 
 	filter := bson.M{"food_id": foodId}
@@ -372,3 +465,54 @@ func UpdateFood(w http.ResponseWriter, r *http.Request) {
 		"data":    updatedFood,
 	})
 }
+
+// Restore a deleted food item; its menu must not be deleted
+func RestoreFood(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	foodId := mux.Vars(r)["food_id"]
+
+	var food models.Food
+	if err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId, "deleted_at": bson.M{"$ne": nil}}).Decode(&food); err != nil {
+		http.Error(w, `{"success": false, "message": "Deleted food item not found"}`, http.StatusNotFound)
+		return
+	}
+
+	menuCount, err := menuCollection.CountDocuments(ctx, activeFilter(bson.M{"menu_id": *food.Menu_id}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking menu existence"}`, http.StatusInternalServerError)
+		return
+	}
+	if menuCount == 0 {
+		http.Error(w, `{"success": false, "message": "The food item's menu is deleted; restore the menu first"}`, http.StatusConflict)
+		return
+	}
+
+	duplicateCount, err := foodCollection.CountDocuments(ctx, activeFilter(bson.M{"unique_food_id": food.UniqueFoodID}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking duplicate food items"}`, http.StatusInternalServerError)
+		return
+	}
+	if duplicateCount > 0 {
+		http.Error(w, `{"success": false, "message": "Another food item with the same name exists in this menu"}`, http.StatusConflict)
+		return
+	}
+
+	if _, err := restoreDeleted(ctx, foodCollection, bson.M{"food_id": foodId}); err != nil {
+		http.Error(w, `{"success": false, "message": "Error restoring food item"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Food item restored successfully",
+		"data": map[string]interface{}{
+			"food_id": food.Food_id,
+			"name":    food.Name,
+			"price":   food.Price,
+			"menu_id": food.Menu_id,
+		},
+	})
+}
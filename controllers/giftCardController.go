@@ -0,0 +1,365 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var giftCardCollection *mongo.Collection = database.OpenCollection(database.Client, "gift_card")
+var giftCardLedgerCollection *mongo.Collection = database.OpenCollection(database.Client, "gift_card_ledger")
+
+// Gift card ledger entry types
+const (
+	giftCardIssue  = "ISSUE"
+	giftCardRedeem = "REDEEM"
+	giftCardRefund = "REFUND"
+)
+
+var (
+	errGiftCardNotFound     = errors.New("gift card not found")
+	errGiftCardExpired      = errors.New("gift card has expired")
+	errGiftCardInsufficient = errors.New("gift card balance is too low")
+)
+
+// Gift card codes avoid characters that are easy to misread (0/O, 1/I)
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func generateGiftCardCode() (string, error) {
+	code := make([]byte, 12)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(giftCardAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = giftCardAlphabet[n.Int64()]
+	}
+	return "GC" + string(code), nil
+}
+
+func normalizeGiftCardCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// unexpiredGiftCardFilter matches a card by code if it has not expired
+func unexpiredGiftCardFilter(code string) bson.M {
+	return bson.M{
+		"code": normalizeGiftCardCode(code),
+		"$or": bson.A{
+			bson.M{"expires_at": nil},
+			bson.M{"expires_at": bson.M{"$gt": time.Now()}},
+		},
+	}
+}
+
+func recordGiftCardEntry(ctx context.Context, card models.GiftCard, entryType string, amount float64, invoiceId, uid string) (models.GiftCardEntry, error) {
+	entry := models.GiftCardEntry{
+		ID:            primitive.NewObjectID(),
+		Gift_card_id:  card.Gift_card_id,
+		Code:          card.Code,
+		Type:          entryType,
+		Amount:        amount,
+		Balance_after: card.Balance,
+		Invoice_id:    invoiceId,
+		Created_by:    uid,
+		Created_at:    time.Now(),
+	}
+	entry.Entry_id = entry.ID.Hex()
+	_, err := giftCardLedgerCollection.InsertOne(ctx, entry)
+	return entry, err
+}
+
+// redeemGiftCard takes amount off a card's balance, failing without a change if the card cannot cover it
+func redeemGiftCard(ctx context.Context, code string, amount float64, invoiceId, uid string) (models.GiftCardEntry, error) {
+	filter := unexpiredGiftCardFilter(code)
+	filter["balance"] = bson.M{"$gte": amount}
+	update := bson.M{
+		"$inc": bson.M{"balance": -amount},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var card models.GiftCard
+	err := giftCardCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&card)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Work out why the card could not be charged
+		if findErr := giftCardCollection.FindOne(ctx, bson.M{"code": normalizeGiftCardCode(code)}).Decode(&card); findErr != nil {
+			return models.GiftCardEntry{}, errGiftCardNotFound
+		}
+		if card.Expires_at != nil && !card.Expires_at.After(time.Now()) {
+			return models.GiftCardEntry{}, errGiftCardExpired
+		}
+		return models.GiftCardEntry{}, errGiftCardInsufficient
+	} else if err != nil {
+		return models.GiftCardEntry{}, err
+	}
+
+	card.Balance = helper.RoundMoney(card.Balance)
+	return recordGiftCardEntry(ctx, card, giftCardRedeem, amount, invoiceId, uid)
+}
+
+// creditGiftCard puts amount back on a card, for refunds and for payments that could not be completed
+func creditGiftCard(ctx context.Context, code string, amount float64, invoiceId, uid string) (models.GiftCardEntry, error) {
+	update := bson.M{
+		"$inc": bson.M{"balance": amount},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var card models.GiftCard
+	err := giftCardCollection.FindOneAndUpdate(ctx, bson.M{"code": normalizeGiftCardCode(code)}, update, opts).Decode(&card)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.GiftCardEntry{}, errGiftCardNotFound
+	} else if err != nil {
+		return models.GiftCardEntry{}, err
+	}
+
+	card.Balance = helper.RoundMoney(card.Balance)
+	return recordGiftCardEntry(ctx, card, giftCardRefund, amount, invoiceId, uid)
+}
+
+// giftCardErrorResponse writes the response for an error from redeemGiftCard or creditGiftCard
+func giftCardErrorResponse(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errGiftCardNotFound):
+		http.Error(w, `{"success": false, "message": "Gift card not found"}`, http.StatusNotFound)
+	case errors.Is(err, errGiftCardExpired):
+		http.Error(w, `{"success": false, "message": "Gift card has expired"}`, http.StatusConflict)
+	case errors.Is(err, errGiftCardInsufficient):
+		http.Error(w, `{"success": false, "message": "Gift card balance is too low"}`, http.StatusConflict)
+	default:
+		http.Error(w, `{"success": false, "message": "Gift card update failed"}`, http.StatusInternalServerError)
+	}
+}
+
+// IssueGiftCard creates a gift card loaded with an amount. A code is generated unless one is given.
+func IssueGiftCard(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var requestBody struct {
+		Code       string     `json:"code"`
+		Amount     float64    `json:"amount"`
+		Expires_at *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	amount := helper.RoundMoney(requestBody.Amount)
+	if amount <= 0 {
+		http.Error(w, `{"success": false, "message": "Amount must be greater than 0"}`, http.StatusBadRequest)
+		return
+	}
+	if requestBody.Expires_at != nil && !requestBody.Expires_at.After(time.Now()) {
+		http.Error(w, `{"success": false, "message": "expires_at must be in the future"}`, http.StatusBadRequest)
+		return
+	}
+
+	code := normalizeGiftCardCode(requestBody.Code)
+	if code == "" {
+		generated, err := generateGiftCardCode()
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Failed to generate gift card code"}`, http.StatusInternalServerError)
+			return
+		}
+		code = generated
+	} else if len(code) < 6 || len(code) > 32 || validate.Var(code, "alphanum") != nil {
+		http.Error(w, `{"success": false, "message": "Code must be 6 to 32 letters or digits"}`, http.StatusBadRequest)
+		return
+	}
+
+	count, err := giftCardCollection.CountDocuments(ctx, bson.M{"code": code})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking gift card code"}`, http.StatusInternalServerError)
+		return
+	}
+	if count > 0 {
+		http.Error(w, `{"success": false, "message": "Gift card code already exists"}`, http.StatusConflict)
+		return
+	}
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	card := models.GiftCard{
+		ID:             primitive.NewObjectID(),
+		Code:           code,
+		Initial_amount: amount,
+		Balance:        amount,
+		Expires_at:     requestBody.Expires_at,
+		Issued_by:      uid,
+		Created_at:     time.Now(),
+		Updated_at:     time.Now(),
+	}
+	card.Gift_card_id = card.ID.Hex()
+
+	if _, err := giftCardCollection.InsertOne(ctx, card); mongo.IsDuplicateKeyError(err) {
+		http.Error(w, `{"success": false, "message": "Gift card code already exists"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Gift card creation failed"}`, http.StatusInternalServerError)
+		return
+	}
+	if _, err := recordGiftCardEntry(ctx, card, giftCardIssue, amount, "", uid); err != nil {
+		http.Error(w, `{"success": false, "message": "Gift card created but its ledger entry failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Gift card issued successfully",
+		"data":    card,
+	})
+}
+
+// GetGiftCardBalance returns a card's balance and whether it can still be used
+func GetGiftCardBalance(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	code := normalizeGiftCardCode(mux.Vars(r)["code"])
+
+	var card models.GiftCard
+	err := giftCardCollection.FindOne(ctx, bson.M{"code": code}).Decode(&card)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Gift card not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving gift card"}`, http.StatusInternalServerError)
+		return
+	}
+
+	expired := card.Expires_at != nil && !card.Expires_at.After(time.Now())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Gift card balance retrieved successfully",
+		"data": map[string]interface{}{
+			"code":       card.Code,
+			"balance":    helper.RoundMoney(card.Balance),
+			"expires_at": card.Expires_at,
+			"expired":    expired,
+		},
+	})
+}
+
+// RedeemGiftCard takes an amount off a card outside of invoice payment, e.g. a counter sale
+func RedeemGiftCard(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	code := mux.Vars(r)["code"]
+
+	var requestBody struct {
+		Amount     float64 `json:"amount"`
+		Invoice_id string  `json:"invoice_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	amount := helper.RoundMoney(requestBody.Amount)
+	if amount <= 0 {
+		http.Error(w, `{"success": false, "message": "Amount must be greater than 0"}`, http.StatusBadRequest)
+		return
+	}
+
+	// A redemption against an invoice must name an unpaid invoice the caller can see
+	if requestBody.Invoice_id != "" {
+		var invoice models.Invoice
+		err := invoiceCollection.FindOne(ctx, activeFilter(outletScope(r, bson.M{"invoice_id": requestBody.Invoice_id}))).Decode(&invoice)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, `{"success": false, "message": "Invoice not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, `{"success": false, "message": "Error retrieving invoice"}`, http.StatusInternalServerError)
+			return
+		}
+		if invoice.Payment_status == nil || *invoice.Payment_status != "PENDING" {
+			http.Error(w, `{"success": false, "message": "Invoice is not pending payment"}`, http.StatusConflict)
+			return
+		}
+	}
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	entry, err := redeemGiftCard(ctx, code, amount, requestBody.Invoice_id, uid)
+	if err != nil {
+		giftCardErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Gift card redeemed successfully",
+		"data":    entry,
+	})
+}
+
+// GetGiftCardLedger lists every issue, redemption and refund on a card, oldest first
+func GetGiftCardLedger(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	code := normalizeGiftCardCode(mux.Vars(r)["code"])
+
+	recordPerPage, err := strconv.Atoi(r.URL.Query().Get("recordPerPage"))
+	if err != nil || recordPerPage < 1 {
+		recordPerPage = 50
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	filter := bson.M{"code": code}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetSkip(int64((page - 1) * recordPerPage)).
+		SetLimit(int64(recordPerPage))
+	cursor, err := giftCardLedgerCollection.Find(ctx, filter, opts)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving gift card ledger"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.GiftCardEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding gift card ledger"}`, http.StatusInternalServerError)
+		return
+	}
+
+	totalCount, _ := giftCardLedgerCollection.CountDocuments(ctx, filter)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Gift card ledger retrieved successfully",
+		"data":    entries,
+		"pagination": map[string]interface{}{
+			"current_page":     page,
+			"records_per_page": recordPerPage,
+			"total_entries":    totalCount,
+			"total_pages":      (totalCount + int64(recordPerPage) - 1) / int64(recordPerPage),
+		},
+	})
+}
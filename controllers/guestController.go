@@ -0,0 +1,396 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StartGuestSession exchanges a scanned table QR token for a guest session token
+func StartGuestSession(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var requestBody struct {
+		Qr_token string `json:"qr_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Qr_token == "" {
+		http.Error(w, `{"success": false, "message": "QR token is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims, errMsg := helper.ValidateTableQRToken(requestBody.Qr_token)
+	if errMsg != "" {
+		// The message can carry the parser's error text, so it is encoded rather than pasted in
+		body, _ := json.Marshal(map[string]interface{}{"success": false, "message": errMsg})
+		http.Error(w, string(body), http.StatusUnauthorized)
+		return
+	}
+
+	var table models.Table
+	if err := tableCollection.FindOne(ctx, bson.M{"table_id": claims.Table_id}).Decode(&table); err != nil {
+		http.Error(w, `{"success": false, "message": "Table not found"}`, http.StatusNotFound)
+		return
+	}
+
+	token, sessionId, err := helper.GenerateGuestToken(table.Table_id, table.Qr_version)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Could not start guest session"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Guest session started successfully",
+		"data": map[string]interface{}{
+			"session_id":   sessionId,
+			"token":        token,
+			"table_id":     table.Table_id,
+			"table_number": table.Table_number,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// guestOutletId returns the outlet of the guest's table, whose menus the guest browses
+func guestOutletId(ctx context.Context, r *http.Request) (string, error) {
+	tableId, _ := middleware.GetGuestFromContext(r)
+	var table models.Table
+	err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table)
+	return table.Outlet_id, err
+}
+
+// guestOpenOrder returns the open order of a guest's table. Secondary tables of a merge share
+// the primary table's order.
+func guestOpenOrder(ctx context.Context, tableId string) (models.Order, error) {
+	var table models.Table
+	if err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table); err != nil {
+		return models.Order{}, err
+	}
+	if table.Merged_into != "" {
+		tableId = table.Merged_into
+	}
+	return findOpenOrder(ctx, tableId)
+}
+
+// GetGuestMenus lists the menus a guest can order from: the master menus and the table's outlet's
+// own, as long as the outlet sells something on them
+func GetGuestMenus(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	outletId, err := guestOutletId(ctx, r)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Table not found"}`, http.StatusNotFound)
+		return
+	}
+
+	// Menus whose foods are all hidden or unavailable at the outlet are not active there
+	pipeline := append(mongo.Pipeline{
+		{{Key: "$match", Value: activeFilter(masterOrOutlet(bson.M{}, outletId))}},
+	}, effectiveFoodStages(outletId)...)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$match", Value: bson.M{"available": true}}},
+		bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$menu_id"}}}},
+	)
+	foodCursor, err := foodCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving menus"}`, http.StatusInternalServerError)
+		return
+	}
+	var activeMenus []struct {
+		Menu_id string `bson:"_id"`
+	}
+	if err = foodCursor.All(ctx, &activeMenus); err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving menus"}`, http.StatusInternalServerError)
+		return
+	}
+	menuIds := make([]string, 0, len(activeMenus))
+	for _, menu := range activeMenus {
+		menuIds = append(menuIds, menu.Menu_id)
+	}
+
+	projection := bson.M{"_id": 0, "menu_id": 1, "name": 1, "category": 1}
+	filter := activeFilter(masterOrOutlet(bson.M{"menu_id": bson.M{"$in": menuIds}}, outletId))
+	cursor, err := menuCollection.Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving menus"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var menus []bson.M
+	if err = cursor.All(ctx, &menus); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding menu data"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Menus retrieved successfully",
+		"data":    menus,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetGuestMenuFoods lists the foods of a menu for a guest, as the table's outlet sells them
+func GetGuestMenuFoods(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	menuId := mux.Vars(r)["menu_id"]
+
+	outletId, err := guestOutletId(ctx, r)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Table not found"}`, http.StatusNotFound)
+		return
+	}
+
+	menuCount, err := menuCollection.CountDocuments(ctx, activeFilter(masterOrOutlet(bson.M{"menu_id": menuId}, outletId)))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking menu existence"}`, http.StatusInternalServerError)
+		return
+	}
+	if menuCount == 0 {
+		http.Error(w, `{"success": false, "message": "Menu not found"}`, http.StatusNotFound)
+		return
+	}
+
+	pipeline := append(mongo.Pipeline{
+		{{Key: "$match", Value: activeFilter(masterOrOutlet(bson.M{"menu_id": menuId}, outletId))}},
+	}, effectiveFoodStages(outletId)...)
+	pipeline = append(pipeline, bson.D{{Key: "$project", Value: bson.M{"_id": 0, "food_id": 1, "name": 1, "price": 1, "food_image": 1, "menu_id": 1, "available": 1}}})
+	cursor, err := foodCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving food items"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var foods []bson.M
+	if err = cursor.All(ctx, &foods); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding food items"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Food items retrieved successfully",
+		"data":    foods,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetGuestOrder returns the table's open order with the items placed so far
+func GetGuestOrder(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	tableId, _ := middleware.GetGuestFromContext(r)
+
+	order, err := guestOpenOrder(ctx, tableId)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "No open order for this table"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving order"}`, http.StatusInternalServerError)
+		return
+	}
+
+	cursor, err := orderItemCollection.Find(ctx, bson.M{"order_id": order.Order_id})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving order items"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var orderItems []models.OrderItem
+	if err = cursor.All(ctx, &orderItems); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding order items"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var total float64
+	for _, item := range orderItems {
+		total += item.TotalPrice
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Order retrieved successfully",
+		"data": map[string]interface{}{
+			"order_id":          order.Order_id,
+			"table_id":          order.Table_id,
+			"status":            order.Status,
+			"bill_requested_at": order.Bill_requested_at,
+			"order_items":       orderItems,
+			"total_price":       total,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateGuestOrderItem places items against the table's open order
+func CreateGuestOrderItem(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	tableId, _ := middleware.GetGuestFromContext(r)
+
+	var requestBody struct {
+		Items map[string]int `json:"items"`
+		Notes string         `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || len(requestBody.Items) == 0 {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	for _, quantity := range requestBody.Items {
+		if quantity < 1 {
+			http.Error(w, `{"success": false, "message": "Quantities must be at least 1"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	order, err := guestOpenOrder(ctx, tableId)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "No open order for this table, please ask a staff member"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving order"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if order.Bill_requested_at != nil {
+		http.Error(w, `{"success": false, "message": "The bill has already been requested for this order"}`, http.StatusConflict)
+		return
+	}
+
+	transformedItems, lines, totalPrice, missingFoodIDs := priceOrderItems(ctx, order.Outlet_id, requestBody.Items)
+	if len(missingFoodIDs) > 0 {
+		http.Error(w, `{"success": false, "message": "Food items not found or unavailable: `+strings.Join(missingFoodIDs, ", ")+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	// If order status is "Order Pending", update it to "Order Placed"
+	if order.Status == "Order Pending" {
+		_, err := orderCollection.UpdateOne(ctx,
+			bson.M{"order_id": order.Order_id},
+			bson.M{"$set": bson.M{"status": "Order Placed", "updated_at": time.Now()}},
+		)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Failed to update order status"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	orderItem := models.OrderItem{
+		Items:      transformedItems,
+		Lines:      lines,
+		TotalPrice: totalPrice,
+		Notes:      requestBody.Notes,
+		Order_id:   order.Order_id,
+		Table_id:   *order.Table_id,
+		Created_at: time.Now(),
+		Updated_at: time.Now(),
+		ID:         primitive.NewObjectID(),
+	}
+	orderItem.Order_item_id = orderItem.ID.Hex()
+
+	if _, err = orderItemCollection.InsertOne(ctx, orderItem); err != nil {
+		http.Error(w, `{"success": false, "message": "Order item creation failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err = advanceTableStatus(ctx, *order.Table_id, "order", order.Order_id); ignoreNoDocuments(err) != nil {
+		http.Error(w, `{"success": false, "message": "Failed to update table status"}`, http.StatusInternalServerError)
+		return
+	}
+
+	kots, err := generateKOTs(ctx, r, orderItem)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Order item created but kitchen tickets could not be generated"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Order item created successfully",
+		"data":    orderItem,
+		"kots":    kots,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RequestGuestBill flags the table's open order so staff bring the bill
+func RequestGuestBill(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	tableId, _ := middleware.GetGuestFromContext(r)
+
+	order, err := guestOpenOrder(ctx, tableId)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "No open order for this table"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving order"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if order.Bill_requested_at == nil {
+		now := time.Now()
+		_, err = orderCollection.UpdateOne(ctx,
+			bson.M{"order_id": order.Order_id},
+			bson.M{"$set": bson.M{"bill_requested_at": now, "updated_at": now}},
+		)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Failed to request the bill"}`, http.StatusInternalServerError)
+			return
+		}
+		order.Bill_requested_at = &now
+
+		if _, err = advanceTableStatus(ctx, *order.Table_id, "request_bill", order.Order_id); ignoreNoDocuments(err) != nil {
+			http.Error(w, `{"success": false, "message": "Failed to update table status"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Bill requested successfully",
+		"data": map[string]interface{}{
+			"order_id":          order.Order_id,
+			"table_id":          order.Table_id,
+			"bill_requested_at": order.Bill_requested_at,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
 	"github.com/02priyeshraj/Hotel_Management_Backend/models"
 
 	"github.com/gorilla/mux"
@@ -22,6 +26,18 @@ import (
 // Open the Invoice collection
 var invoiceCollection *mongo.Collection = database.OpenCollection(database.Client, "invoice")
 
+// invoiceListFilter reads the invoice list filters: ?invoice_number=, ?payment_method= and ?include_deleted=
+func invoiceListFilter(r *http.Request) bson.M {
+	filter := listFilter(r, bson.M{})
+	if invoiceNumber := r.URL.Query().Get("invoice_number"); invoiceNumber != "" {
+		filter["invoice_number"] = invoiceNumber
+	}
+	if paymentMethod := r.URL.Query().Get("payment_method"); paymentMethod != "" {
+		filter["payment_method"] = strings.ToUpper(paymentMethod)
+	}
+	return filter
+}
+
 // GetInvoices retrieves all invoices with pagination.
 func GetInvoices(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
@@ -39,20 +55,25 @@ func GetInvoices(w http.ResponseWriter, r *http.Request) {
 	skip := (page - 1) * recordPerPage
 
 	// Build aggregation pipeline
-	matchStage := bson.D{{Key: "$match", Value: bson.D{}}}
+	filter := invoiceListFilter(r)
+	matchStage := bson.D{{Key: "$match", Value: filter}}
 	skipStage := bson.D{{Key: "$skip", Value: int64(skip)}}
 	limitStage := bson.D{{Key: "$limit", Value: int64(recordPerPage)}}
 	projectStage := bson.D{{Key: "$project", Value: bson.D{
 		{Key: "_id", Value: 0},
 		{Key: "invoice_id", Value: 1},
+		{Key: "invoice_number", Value: 1},
 		{Key: "order_id", Value: 1},
 		{Key: "user_id", Value: 1},
 		{Key: "payment_method", Value: 1},
+		{Key: "payment_details", Value: 1},
 		{Key: "payment_status", Value: 1},
 		{Key: "total_price", Value: 1},
 		{Key: "payment_date", Value: 1},
 		{Key: "created_at", Value: 1},
 		{Key: "updated_at", Value: 1},
+		{Key: "deleted_at", Value: 1},
+		{Key: "deleted_by", Value: 1},
 	}}}
 
 	cursor, err := invoiceCollection.Aggregate(ctx, mongo.Pipeline{matchStage, skipStage, limitStage, projectStage})
@@ -68,7 +89,7 @@ func GetInvoices(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	totalCount, err := invoiceCollection.CountDocuments(ctx, bson.M{})
+	totalCount, err := invoiceCollection.CountDocuments(ctx, filter)
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error retrieving total invoice count"}`, http.StatusInternalServerError)
 		return
@@ -102,7 +123,7 @@ func GetInvoiceById(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var invoice models.Invoice
-	err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+	err := invoiceCollection.FindOne(ctx, activeFilter(bson.M{"invoice_id": invoiceId})).Decode(&invoice)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		http.Error(w, `{"success": false, "message": "Invoice not found"}`, http.StatusNotFound)
 		return
@@ -115,15 +136,17 @@ func GetInvoiceById(w http.ResponseWriter, r *http.Request) {
 		"success": true,
 		"message": "Invoice retrieved successfully",
 		"data": map[string]interface{}{
-			"invoice_id":     invoice.Invoice_id,
-			"order_id":       invoice.Order_id,
-			"user_id":        invoice.User_id,
-			"payment_method": invoice.Payment_method,
-			"payment_status": invoice.Payment_status,
-			"total_price":    invoice.TotalPrice,
-			"payment_date":   invoice.Payment_date,
-			"created_at":     invoice.Created_at,
-			"updated_at":     invoice.Updated_at,
+			"invoice_id":      invoice.Invoice_id,
+			"invoice_number":  invoice.Invoice_number,
+			"order_id":        invoice.Order_id,
+			"user_id":         invoice.User_id,
+			"payment_method":  invoice.Payment_method,
+			"payment_details": invoice.Payment_details,
+			"payment_status":  invoice.Payment_status,
+			"total_price":     invoice.TotalPrice,
+			"payment_date":    invoice.Payment_date,
+			"created_at":      invoice.Created_at,
+			"updated_at":      invoice.Updated_at,
 		},
 	}
 
@@ -148,12 +171,51 @@ func CreateInvoice(w http.ResponseWriter, r *http.Request) {
 		invoice.Payment_status = &defaultStatus
 	}
 
+	paymentMethod := ""
+	if invoice.Payment_method != nil {
+		paymentMethod = *invoice.Payment_method
+	}
+	if message := validatePaymentDetails(paymentMethod, invoice.Payment_details, strings.EqualFold(*invoice.Payment_status, "PAID")); message != "" {
+		http.Error(w, `{"success": false, "message": "`+message+`"}`, http.StatusBadRequest)
+		return
+	}
+
 	// Calculate total price from all order items for the given order_id
 	if invoice.Order_id == nil || *invoice.Order_id == "" {
 		http.Error(w, `{"success": false, "message": "Order ID is required in invoice"}`, http.StatusBadRequest)
 		return
 	}
 
+	// The order must exist in the caller's outlet and not be deleted; the invoice belongs to its outlet
+	var order models.Order
+	err := orderCollection.FindOne(ctx, activeFilter(outletScope(r, bson.M{"order_id": *invoice.Order_id}))).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Order not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking order"}`, http.StatusInternalServerError)
+		return
+	}
+	invoice.Outlet_id = order.Outlet_id
+
+	// Nothing more can be billed on a day that has had its Z-report
+	closed, err := businessDateClosed(ctx, invoice.Outlet_id, time.Now())
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking business date"}`, http.StatusInternalServerError)
+		return
+	}
+	if closed {
+		http.Error(w, `{"success": false, "message": "Today's business date is already closed"}`, http.StatusConflict)
+		return
+	}
+
+	// Each outlet numbers its invoices in its own series
+	_, invoicePrefix, err := outletCodes(ctx, invoice.Outlet_id)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving outlet"}`, http.StatusInternalServerError)
+		return
+	}
+
 	// Query all order items with the given order_id
 	cursor, err := orderItemCollection.Find(ctx, bson.M{"order_id": *invoice.Order_id})
 	if err != nil {
@@ -173,8 +235,68 @@ func CreateInvoice(w http.ResponseWriter, r *http.Request) {
 	for _, item := range orderItems {
 		calculatedTotal += item.TotalPrice
 	}
-	// Assign calculated total price to invoice.TotalPrice field
-	invoice.TotalPrice = calculatedTotal
+	// Charge the configured taxes on top of the order total
+	invoice.Subtotal = helper.RoundMoney(calculatedTotal)
+
+	// Discounts need a manager and come off before taxes
+	invoice.Discount_amount = helper.RoundMoney(invoice.Discount_amount)
+	if invoice.Discount_amount != 0 {
+		role := middleware.GetRoleFromContext(r)
+		if role != "MANAGER" && role != "ADMIN" {
+			http.Error(w, `{"success": false, "message": "Only a manager can give a discount"}`, http.StatusForbidden)
+			return
+		}
+		if invoice.Discount_amount < 0 || invoice.Discount_amount > invoice.Subtotal {
+			http.Error(w, `{"success": false, "message": "Discount must be between 0 and the subtotal"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Loyalty points come off like a discount, after the manager's and before taxes
+	invoice.Customer_id = orderCustomerId(ctx, invoice.Order_id)
+	invoice.Loyalty_discount, invoice.Loyalty_points_earned = 0, 0
+	if invoice.Loyalty_points_redeemed < 0 {
+		http.Error(w, `{"success": false, "message": "loyalty_points_redeemed cannot be negative"}`, http.StatusBadRequest)
+		return
+	}
+	if invoice.Loyalty_points_redeemed > 0 {
+		if invoice.Customer_id == "" {
+			http.Error(w, `{"success": false, "message": "Points can only be redeemed on a customer's order"}`, http.StatusBadRequest)
+			return
+		}
+		if !canActForCustomer(r, invoice.Customer_id) {
+			http.Error(w, `{"success": false, "message": "Only staff or the customer can redeem their points"}`, http.StatusForbidden)
+			return
+		}
+		invoice.Loyalty_discount = helper.RoundMoney(float64(invoice.Loyalty_points_redeemed) * helper.LoyaltyPointValue())
+		if invoice.Loyalty_discount > helper.RoundMoney(invoice.Subtotal-invoice.Discount_amount) {
+			maxPoints := int64((invoice.Subtotal - invoice.Discount_amount) / helper.LoyaltyPointValue())
+			http.Error(w, `{"success": false, "message": "At most `+strconv.FormatInt(maxPoints, 10)+` points can be redeemed on this invoice"}`, http.StatusBadRequest)
+			return
+		}
+		invoice.Discount_amount = helper.RoundMoney(invoice.Discount_amount + invoice.Loyalty_discount)
+	}
+	discountedSubtotal := helper.RoundMoney(invoice.Subtotal - invoice.Discount_amount)
+	invoice.Taxes, invoice.Tax_amount = helper.ApplyTaxes(discountedSubtotal)
+	invoice.TotalPrice = helper.RoundMoney(discountedSubtotal + invoice.Tax_amount)
+
+	// Tips are only taken with the payment and go to the order's server
+	tipAmount, tipPercent, message := resolveTip(discountedSubtotal, invoice.Tip_amount, invoice.Tip_percent)
+	if message != "" {
+		http.Error(w, `{"success": false, "message": "`+message+`"}`, http.StatusBadRequest)
+		return
+	}
+	if tipAmount > 0 && !strings.EqualFold(*invoice.Payment_status, "PAID") {
+		http.Error(w, `{"success": false, "message": "A tip can only be added when the invoice is paid"}`, http.StatusBadRequest)
+		return
+	}
+	invoice.Tip_amount, invoice.Tip_percent, invoice.Tip_server_id = tipAmount, tipPercent, ""
+	if strings.EqualFold(*invoice.Payment_status, "PAID") && invoice.Payment_date.IsZero() {
+		invoice.Payment_date = time.Now()
+	}
+	if tipAmount > 0 {
+		invoice.Tip_server_id = orderServerId(ctx, invoice.Order_id)
+	}
 
 	// Set timestamps and unique Invoice ID
 	invoice.Created_at = time.Now()
@@ -182,12 +304,65 @@ func CreateInvoice(w http.ResponseWriter, r *http.Request) {
 	invoice.ID = primitive.NewObjectID()
 	invoice.Invoice_id = invoice.ID.Hex()
 
-	// Insert the invoice into the database
-	_, err = invoiceCollection.InsertOne(ctx, invoice)
+	// The session is started before anything is charged, so a failure here costs the customer nothing
+	session, err := database.Client.StartSession()
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Invoice creation failed"}`, http.StatusInternalServerError)
 		return
 	}
+	defer session.EndSession(ctx)
+
+	// Take the redeemed points up front; they are given back if the invoice cannot be saved
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	returnRedeemedPoints := func() {
+		if invoice.Loyalty_points_redeemed == 0 {
+			return
+		}
+		if _, err := creditLoyaltyPoints(ctx, invoice.Customer_id, loyaltyReturn, invoice.Loyalty_points_redeemed, invoice.Invoice_id, "", uid); err != nil {
+			log.Printf("invoice %s: failed to give loyalty points back after error: %v", invoice.Invoice_id, err)
+		}
+	}
+	if invoice.Loyalty_points_redeemed > 0 {
+		if _, err := takeLoyaltyPoints(ctx, invoice.Customer_id, loyaltyRedeem, invoice.Loyalty_points_redeemed, invoice.Invoice_id, uid); errors.Is(err, errLoyaltyInsufficient) {
+			http.Error(w, `{"success": false, "message": "The customer does not have enough loyalty points"}`, http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, `{"success": false, "message": "Failed to redeem loyalty points"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Charge the gift card up front; it is credited back if the invoice cannot be saved
+	giftCardCharged := false
+	if strings.EqualFold(*invoice.Payment_status, "PAID") && paymentMethod == "GIFT_CARD" {
+		if _, err := redeemGiftCard(ctx, invoice.Payment_details.Gift_card_code, helper.RoundMoney(invoice.TotalPrice+invoice.Tip_amount), invoice.Invoice_id, uid); err != nil {
+			returnRedeemedPoints()
+			giftCardErrorResponse(w, err)
+			return
+		}
+		giftCardCharged = true
+	}
+
+	// Allocate the invoice number and insert the invoice in one transaction,
+	// so a failed insert never burns a number and leaves a gap
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		number, err := helper.NextInvoiceNumber(sessCtx, invoicePrefix, invoice.Created_at)
+		if err != nil {
+			return nil, err
+		}
+		invoice.Invoice_number = number
+		return invoiceCollection.InsertOne(sessCtx, invoice)
+	})
+	if err != nil {
+		if giftCardCharged {
+			if _, creditErr := creditGiftCard(ctx, invoice.Payment_details.Gift_card_code, helper.RoundMoney(invoice.TotalPrice+invoice.Tip_amount), invoice.Invoice_id, uid); creditErr != nil {
+				log.Printf("invoice %s: failed to credit gift card back after insert error: %v", invoice.Invoice_id, creditErr)
+			}
+		}
+		returnRedeemedPoints()
+		http.Error(w, `{"success": false, "message": "Invoice creation failed"}`, http.StatusInternalServerError)
+		return
+	}
 
 	// If the payment status is PAID, update the related order status to "Order Paid"
 	if strings.EqualFold(*invoice.Payment_status, "PAID") {
@@ -203,6 +378,23 @@ func CreateInvoice(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, `{"success": false, "message": "Failed to update order status to 'Order Paid' after payment"}`, http.StatusInternalServerError)
 			return
 		}
+
+		// The sale is settled, so the customer earns their points
+		points, err := earnLoyaltyPoints(ctx, invoice, uid)
+		if err != nil {
+			log.Printf("invoice %s: failed to credit loyalty points: %v", invoice.Invoice_id, err)
+		}
+		invoice.Loyalty_points_earned = points
+	}
+
+	// The bill is on the table, or already settled and the table needs cleaning
+	tableEvent := "request_bill"
+	if strings.EqualFold(*invoice.Payment_status, "PAID") {
+		tableEvent = "pay"
+	}
+	if err := advanceOrderTableStatus(ctx, *invoice.Order_id, tableEvent); ignoreNoDocuments(err) != nil {
+		http.Error(w, `{"success": false, "message": "Failed to update table status"}`, http.StatusInternalServerError)
+		return
 	}
 
 	// Construct Success Response
@@ -228,16 +420,136 @@ func UpdateInvoice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The total follows from the order, discounts and taxes when the invoice is created
+	if invoice.TotalPrice != 0 {
+		http.Error(w, `{"success": false, "message": "total_price cannot be changed; void the invoice and raise a new one"}`, http.StatusBadRequest)
+		return
+	}
+
+	if invoice.Payment_status != nil && *invoice.Payment_status != "PENDING" && *invoice.Payment_status != "PAID" {
+		http.Error(w, `{"success": false, "message": "Payment status can only be set to PENDING or PAID; use the void and refund endpoints"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Voided and refunded invoices are final
+	var existingInvoice models.Invoice
+	err := invoiceCollection.FindOne(ctx, activeFilter(bson.M{"invoice_id": invoiceId})).Decode(&existingInvoice)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Invoice not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving invoice"}`, http.StatusInternalServerError)
+		return
+	}
+	if existingInvoice.Payment_status != nil && slices.Contains(lockedInvoiceStatuses, *existingInvoice.Payment_status) {
+		http.Error(w, `{"success": false, "message": "Invoice is `+*existingInvoice.Payment_status+` and can no longer be changed"}`, http.StatusConflict)
+		return
+	}
+	// So are invoices of a business date that has had its Z-report
+	closed, err := businessDateClosed(ctx, existingInvoice.Outlet_id, existingInvoice.Created_at)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking business date"}`, http.StatusInternalServerError)
+		return
+	}
+	if closed {
+		http.Error(w, `{"success": false, "message": "Business date `+businessDate(existingInvoice.Created_at)+` is closed; invoices from it can no longer be changed"}`, http.StatusConflict)
+		return
+	}
+
+	// Check the payment as it will be after this update; a new method replaces the old details
+	existingStatus, existingMethod := "", ""
+	if existingInvoice.Payment_status != nil {
+		existingStatus = *existingInvoice.Payment_status
+	}
+	if existingInvoice.Payment_method != nil {
+		existingMethod = *existingInvoice.Payment_method
+	}
+	paymentStatus, paymentMethod, paymentDetails := existingStatus, existingMethod, existingInvoice.Payment_details
+	if invoice.Payment_status != nil {
+		paymentStatus = *invoice.Payment_status
+	}
+	// Money taken stays with the invoice; giving it back is a refund
+	if existingStatus == "PAID" && paymentStatus != "PAID" {
+		http.Error(w, `{"success": false, "message": "A paid invoice cannot be set back to PENDING; refund it instead"}`, http.StatusConflict)
+		return
+	}
+	if invoice.Payment_method != nil {
+		paymentMethod = *invoice.Payment_method
+		paymentDetails = invoice.Payment_details
+	} else if invoice.Payment_details != nil {
+		paymentDetails = invoice.Payment_details
+	}
+	if message := validatePaymentDetails(paymentMethod, paymentDetails, paymentStatus == "PAID"); message != "" {
+		http.Error(w, `{"success": false, "message": "`+message+`"}`, http.StatusBadRequest)
+		return
+	}
+	// A tip comes with the payment, as an amount or a percentage of the subtotal
+	tipGiven := invoice.Tip_amount != 0 || invoice.Tip_percent != 0
+	tipAmount := existingInvoice.Tip_amount
+	var tipPercent float64
+	if tipGiven {
+		subtotal := existingInvoice.Subtotal - existingInvoice.Discount_amount
+		if existingInvoice.Subtotal == 0 {
+			subtotal = existingInvoice.TotalPrice // invoices from before taxes were split out
+		}
+		var message string
+		tipAmount, tipPercent, message = resolveTip(subtotal, invoice.Tip_amount, invoice.Tip_percent)
+		if message != "" {
+			http.Error(w, `{"success": false, "message": "`+message+`"}`, http.StatusBadRequest)
+			return
+		}
+		if paymentStatus != "PAID" {
+			http.Error(w, `{"success": false, "message": "A tip can only be added when the invoice is paid"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	paymentChanged := invoice.Payment_method != nil || invoice.Payment_details != nil
+	if existingStatus == "PAID" && (existingMethod == "GIFT_CARD" || paymentMethod == "GIFT_CARD") &&
+		(paymentChanged || tipGiven || paymentStatus != "PAID") {
+		http.Error(w, `{"success": false, "message": "A gift card payment cannot be changed; refund the invoice instead"}`, http.StatusConflict)
+		return
+	}
+
+	// Paying by gift card takes the total off the card now; it is credited back if the update fails
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	var giftCardCharge float64
+	if existingStatus != "PAID" && paymentStatus == "PAID" && paymentMethod == "GIFT_CARD" {
+		giftCardCharge = helper.RoundMoney(existingInvoice.TotalPrice + tipAmount)
+		if _, err := redeemGiftCard(ctx, paymentDetails.Gift_card_code, giftCardCharge, invoiceId, uid); err != nil {
+			giftCardErrorResponse(w, err)
+			return
+		}
+	}
+	refundGiftCardCharge := func() {
+		if giftCardCharge == 0 {
+			return
+		}
+		if _, err := creditGiftCard(ctx, paymentDetails.Gift_card_code, giftCardCharge, invoiceId, uid); err != nil {
+			log.Printf("invoice %s: failed to credit gift card back after update error: %v", invoiceId, err)
+		}
+	}
+
 	// Initialize update object
 	updateObj := bson.D{
 		{Key: "updated_at", Value: time.Now()},
 	}
 	updateOrderStatus := false
 
-	// Update payment_method, payment_status, payment_date, and total_price if provided
+	// Update payment_method, payment_status and payment_date if provided
 	if invoice.Payment_method != nil {
 		updateObj = append(updateObj, bson.E{Key: "payment_method", Value: invoice.Payment_method})
 	}
+	if paymentChanged {
+		updateObj = append(updateObj, bson.E{Key: "payment_details", Value: paymentDetails})
+	}
+	if tipGiven {
+		updateObj = append(updateObj,
+			bson.E{Key: "tip_amount", Value: tipAmount},
+			bson.E{Key: "tip_percent", Value: tipPercent},
+			bson.E{Key: "tip_server_id", Value: orderServerId(ctx, existingInvoice.Order_id)},
+		)
+	}
 	if invoice.Payment_status != nil {
 		updateObj = append(updateObj, bson.E{Key: "payment_status", Value: invoice.Payment_status})
 		if *invoice.Payment_status == "PAID" {
@@ -246,22 +558,27 @@ func UpdateInvoice(w http.ResponseWriter, r *http.Request) {
 	}
 	if !invoice.Payment_date.IsZero() {
 		updateObj = append(updateObj, bson.E{Key: "payment_date", Value: invoice.Payment_date})
-	}
-	if invoice.TotalPrice > 0 {
-		updateObj = append(updateObj, bson.E{Key: "total_price", Value: invoice.TotalPrice})
+	} else if existingStatus != "PAID" && paymentStatus == "PAID" {
+		updateObj = append(updateObj, bson.E{Key: "payment_date", Value: time.Now()})
 	}
 
 	// Update the invoice in the database
-	filter := bson.M{"invoice_id": invoiceId}
+	filter := activeFilter(bson.M{"invoice_id": invoiceId, "payment_status": bson.M{"$nin": lockedInvoiceStatuses}})
+	if giftCardCharge > 0 || paymentStatus != "PAID" {
+		// Only settle with the card, or leave the invoice pending, if nobody settled it in the meantime
+		filter["payment_status"] = bson.M{"$nin": append(slices.Clone(lockedInvoiceStatuses), "PAID")}
+	}
 	opt := options.Update().SetUpsert(false)
 
 	result, err := invoiceCollection.UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: updateObj}}, opt)
 	if err != nil {
+		refundGiftCardCharge()
 		http.Error(w, `{"success": false, "message": "Invoice update failed"}`, http.StatusInternalServerError)
 		return
 	}
 	if result.MatchedCount == 0 {
-		http.Error(w, `{"success": false, "message": "Invoice not found"}`, http.StatusNotFound)
+		refundGiftCardCharge()
+		http.Error(w, `{"success": false, "message": "Invoice was changed by another request, try again"}`, http.StatusConflict)
 		return
 	}
 
@@ -274,16 +591,21 @@ func UpdateInvoice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// If payment_status is updated to PAID, update the corresponding order status
-	if updateOrderStatus {
-		_, err := orderCollection.UpdateOne(ctx,
-			bson.M{"order_id": updatedInvoice.Order_id},
-			bson.M{"$set": bson.M{"status": "Order Paid", "updated_at": time.Now()}},
-		)
-		if err != nil {
+	if updateOrderStatus && updatedInvoice.Order_id != nil {
+		if err := markOrderPaid(ctx, *updatedInvoice.Order_id); err != nil {
 			http.Error(w, `{"success": false, "message": "Failed to update order status"}`, http.StatusInternalServerError)
 			return
 		}
 	}
+	if existingStatus != "PAID" && paymentStatus == "PAID" {
+		points, err := earnLoyaltyPoints(ctx, updatedInvoice, uid)
+		if err != nil {
+			log.Printf("invoice %s: failed to credit loyalty points: %v", invoiceId, err)
+		}
+		if points > 0 {
+			updatedInvoice.Loyalty_points_earned = points
+		}
+	}
 
 	response := map[string]interface{}{
 		"success": true,
@@ -302,7 +624,7 @@ func DeleteInvoice(w http.ResponseWriter, r *http.Request) {
 
 	invoiceId := mux.Vars(r)["invoice_id"]
 	var invoice models.Invoice
-	err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+	err := invoiceCollection.FindOne(ctx, activeFilter(bson.M{"invoice_id": invoiceId})).Decode(&invoice)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		http.Error(w, `{"success": false, "message": "Invoice not found"}`, http.StatusNotFound)
 		return
@@ -311,12 +633,24 @@ func DeleteInvoice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := invoiceCollection.DeleteOne(ctx, bson.M{"invoice_id": invoiceId})
-	if err != nil || result.DeletedCount == 0 {
+	// Settled invoices are financial records; they are voided or refunded, never deleted
+	if invoice.Payment_status != nil && *invoice.Payment_status != "PENDING" {
+		http.Error(w, `{"success": false, "message": "Only pending invoices can be deleted; void or refund it instead"}`, http.StatusConflict)
+		return
+	}
+
+	result, err := softDelete(ctx, r, invoiceCollection, bson.M{"invoice_id": invoiceId}, time.Now())
+	if err != nil || result.MatchedCount == 0 {
 		http.Error(w, `{"success": false, "message": "Error deleting invoice"}`, http.StatusInternalServerError)
 		return
 	}
 
+	// Points redeemed on the bill go back to the customer, and are taken again on restore
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	if err := refundLoyaltyPoints(ctx, invoice, invoice.TotalPrice, true, uid); err != nil {
+		log.Printf("invoice %s: failed to give loyalty points back after delete: %v", invoiceId, err)
+	}
+
 	response := map[string]interface{}{
 		"success": true,
 		"message": "Invoice deleted successfully",
@@ -326,6 +660,87 @@ func DeleteInvoice(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// RestoreInvoice restores a deleted invoice whose order is still active.
+func RestoreInvoice(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	invoiceId := mux.Vars(r)["invoice_id"]
+	var invoice models.Invoice
+	err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": invoiceId, "deleted_at": bson.M{"$ne": nil}}).Decode(&invoice)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Deleted invoice not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving invoice"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if invoice.Order_id != nil {
+		orderCount, err := orderCollection.CountDocuments(ctx, activeFilter(bson.M{"order_id": *invoice.Order_id}))
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Error checking order"}`, http.StatusInternalServerError)
+			return
+		}
+		if orderCount == 0 {
+			http.Error(w, `{"success": false, "message": "The invoice's order is deleted; restore the order first"}`, http.StatusConflict)
+			return
+		}
+
+		invoiceCount, err := invoiceCollection.CountDocuments(ctx, activeFilter(bson.M{"order_id": *invoice.Order_id}))
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Error checking order invoices"}`, http.StatusInternalServerError)
+			return
+		}
+		if invoiceCount > 0 {
+			http.Error(w, `{"success": false, "message": "The order already has another invoice"}`, http.StatusConflict)
+			return
+		}
+	}
+
+	// The points redeemed on the bill were given back on delete
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	var retaken int64
+	if invoice.Loyalty_points_redeemed > 0 {
+		moved, err := invoiceLoyaltyPoints(ctx, invoiceId)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Error checking loyalty points"}`, http.StatusInternalServerError)
+			return
+		}
+		if returned := moved[loyaltyReturn] - (moved[loyaltyRedeem] - invoice.Loyalty_points_redeemed); returned > 0 {
+			if _, err := takeLoyaltyPoints(ctx, invoice.Customer_id, loyaltyRedeem, returned, invoiceId, uid); errors.Is(err, errLoyaltyInsufficient) {
+				http.Error(w, `{"success": false, "message": "The customer no longer has the loyalty points redeemed on this invoice"}`, http.StatusConflict)
+				return
+			} else if err != nil {
+				http.Error(w, `{"success": false, "message": "Failed to redeem loyalty points"}`, http.StatusInternalServerError)
+				return
+			}
+			retaken = returned
+		}
+	}
+
+	if _, err := restoreDeleted(ctx, invoiceCollection, bson.M{"invoice_id": invoiceId}); err != nil {
+		if retaken > 0 {
+			if _, creditErr := creditLoyaltyPoints(ctx, invoice.Customer_id, loyaltyReturn, retaken, invoiceId, "", uid); creditErr != nil {
+				log.Printf("invoice %s: failed to give loyalty points back after restore error: %v", invoiceId, creditErr)
+			}
+		}
+		http.Error(w, `{"success": false, "message": "Error restoring invoice"}`, http.StatusInternalServerError)
+		return
+	}
+	invoice.Deleted_at = nil
+	invoice.Deleted_by = nil
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Invoice restored successfully",
+		"data":    invoice,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // Get Invoice by Order ID
 func GetInvoiceByOrderId(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
@@ -338,7 +753,7 @@ func GetInvoiceByOrderId(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var invoice models.Invoice
-	err := invoiceCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&invoice)
+	err := invoiceCollection.FindOne(ctx, activeFilter(bson.M{"order_id": orderId})).Decode(&invoice)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		http.Error(w, `{"success": false, "message": "Invoice not found"}`, http.StatusNotFound)
 		return
@@ -383,7 +798,8 @@ func GetInvoicesByUserId(w http.ResponseWriter, r *http.Request) {
 	startIndex := (page - 1) * recordPerPage
 
 	// MongoDB aggregation pipeline for pagination
-	matchStage := bson.D{{Key: "$match", Value: bson.D{{Key: "user_id", Value: userId}}}}
+	filter := listFilter(r, bson.M{"user_id": userId})
+	matchStage := bson.D{{Key: "$match", Value: filter}}
 	skipStage := bson.D{{Key: "$skip", Value: startIndex}}
 	limitStage := bson.D{{Key: "$limit", Value: int64(recordPerPage)}}
 
@@ -406,7 +822,7 @@ func GetInvoicesByUserId(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get total invoice count for the given user_id
-	totalInvoices, err := invoiceCollection.CountDocuments(ctx, bson.M{"user_id": userId})
+	totalInvoices, err := invoiceCollection.CountDocuments(ctx, filter)
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error retrieving total invoice count"}`, http.StatusInternalServerError)
 		return
@@ -446,7 +862,7 @@ func GetPendingInvoices(w http.ResponseWriter, r *http.Request) {
 	skip := (page - 1) * recordPerPage
 
 	// Query filter and options
-	filter := bson.M{"payment_status": "PENDING"}
+	filter := listFilter(r, bson.M{"payment_status": "PENDING"})
 	findOptions := options.Find()
 	findOptions.SetSkip(int64(skip))
 	findOptions.SetLimit(int64(recordPerPage))
@@ -511,7 +927,7 @@ func GetPaidInvoices(w http.ResponseWriter, r *http.Request) {
 	skip := (page - 1) * recordPerPage
 
 	// Filter and pagination options
-	filter := bson.M{"payment_status": "PAID"}
+	filter := listFilter(r, bson.M{"payment_status": "PAID"})
 	findOptions := options.Find()
 	findOptions.SetSkip(int64(skip))
 	findOptions.SetLimit(int64(recordPerPage))
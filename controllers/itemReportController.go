@@ -0,0 +1,477 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Orders that never went ahead do not count as sales
+var unsoldOrderStatuses = bson.A{"Order Cancelled", "Order Rejected"}
+
+// soldLinesPipeline unwinds the priced lines of order items rung in between from and to at the
+// caller's outlets, leaving out cancelled, rejected and deleted orders. Order items from before
+// priced lines only have the food name to quantity map, so those are matched to a food by name and
+// priced at its current price, as on their receipts
+func soldLinesPipeline(r *http.Request, from, to time.Time) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "order"},
+			{Key: "localField", Value: "order_id"},
+			{Key: "foreignField", Value: "order_id"},
+			{Key: "as", Value: "order"},
+		}}},
+		{{Key: "$match", Value: outletScopeField(r, bson.M{
+			"order.status":     bson.M{"$nin": unsoldOrderStatuses},
+			"order.deleted_at": nil,
+		}, "order.outlet_id")}},
+		{{Key: "$set", Value: bson.D{{Key: "lines", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$gt", Value: bson.A{bson.D{{Key: "$size", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$lines", bson.A{}}}}}}, 0}}},
+			"$lines",
+			bson.D{{Key: "$map", Value: bson.D{
+				{Key: "input", Value: bson.D{{Key: "$objectToArray", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$items", bson.D{}}}}}}},
+				{Key: "as", Value: "item"},
+				{Key: "in", Value: bson.D{{Key: "name", Value: "$$item.k"}, {Key: "quantity", Value: "$$item.v"}}},
+			}}},
+		}}}}}}},
+		{{Key: "$unwind", Value: "$lines"}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "food"},
+			{Key: "localField", Value: "lines.name"},
+			{Key: "foreignField", Value: "name"},
+			{Key: "as", Value: "line_food"},
+		}}},
+		{{Key: "$set", Value: bson.D{
+			{Key: "lines.food_id", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$lines.food_id", bson.D{{Key: "$arrayElemAt", Value: bson.A{"$line_food.food_id", 0}}}, ""}}}},
+			{Key: "lines.amount", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$lines.amount", bson.D{{Key: "$multiply", Value: bson.A{
+				"$lines.quantity",
+				bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$arrayElemAt", Value: bson.A{"$line_food.price", 0}}}, 0}}},
+			}}}}}}},
+		}}},
+		{{Key: "$unset", Value: "line_food"}},
+	}
+}
+
+// withFoodAndMenu sums the sold lines per food and joins each food to its menu
+func withFoodAndMenu(pipeline mongo.Pipeline) mongo.Pipeline {
+	return append(pipeline,
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$lines.food_id"},
+			{Key: "name", Value: bson.D{{Key: "$last", Value: "$lines.name"}}},
+			{Key: "quantity", Value: bson.D{{Key: "$sum", Value: "$lines.quantity"}}},
+			{Key: "revenue", Value: bson.D{{Key: "$sum", Value: "$lines.amount"}}},
+			{Key: "orders", Value: bson.D{{Key: "$addToSet", Value: "$order_id"}}},
+		}}},
+		bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "food"},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "food_id"},
+			{Key: "as", Value: "food"},
+		}}},
+		bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "menu"},
+			{Key: "localField", Value: "food.menu_id"},
+			{Key: "foreignField", Value: "menu_id"},
+			{Key: "as", Value: "menu"},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "name", Value: 1},
+			{Key: "quantity", Value: 1},
+			{Key: "revenue", Value: 1},
+			{Key: "orders", Value: 1},
+			{Key: "cost", Value: bson.D{{Key: "$arrayElemAt", Value: bson.A{"$food.cost", 0}}}},
+			{Key: "menu_id", Value: bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$arrayElemAt", Value: bson.A{"$menu.menu_id", 0}}}, ""}}}},
+			{Key: "menu_name", Value: bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$arrayElemAt", Value: bson.A{"$menu.name", 0}}}, "Unknown"}}}},
+			{Key: "category", Value: bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$arrayElemAt", Value: bson.A{"$menu.category", 0}}}, "Unknown"}}}},
+		}}},
+	)
+}
+
+type itemSalesRow struct {
+	Key      string  `json:"key" bson:"_id"`
+	Name     string  `json:"name" bson:"name"`
+	Quantity int64   `json:"quantity" bson:"quantity"`
+	Revenue  float64 `json:"revenue" bson:"revenue"`
+	Orders   int64   `json:"orders" bson:"orders"`
+	Share    float64 `json:"revenue_share"` // percent of revenue in the range
+}
+
+// GetItemSalesReport reports quantity sold and revenue per food, menu or category
+// (?group_by=food|menu|category, default food) over ?from=&to=
+func GetItemSalesReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	from, to, message := reportRange(r)
+	if message != "" {
+		http.Error(w, `{"success": false, "message": "`+message+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	groupBy := r.URL.Query().Get("group_by")
+	var key, name string
+	switch groupBy {
+	case "", "food":
+		groupBy, key, name = "food", "$_id", "$name"
+	case "menu":
+		key, name = "$menu_id", "$menu_name"
+	case "category":
+		key, name = "$category", "$category"
+	default:
+		http.Error(w, `{"success": false, "message": "group_by must be food, menu or category"}`, http.StatusBadRequest)
+		return
+	}
+
+	pipeline := append(withFoodAndMenu(soldLinesPipeline(r, from, to)),
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: key},
+			{Key: "name", Value: bson.D{{Key: "$first", Value: name}}},
+			{Key: "quantity", Value: bson.D{{Key: "$sum", Value: "$quantity"}}},
+			{Key: "revenue", Value: bson.D{{Key: "$sum", Value: "$revenue"}}},
+			{Key: "orders", Value: bson.D{{Key: "$push", Value: "$orders"}}},
+		}}},
+		// Count each order once even if several of its foods fall in the group
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "name", Value: 1},
+			{Key: "quantity", Value: 1},
+			{Key: "revenue", Value: 1},
+			{Key: "orders", Value: bson.D{{Key: "$size", Value: bson.D{{Key: "$reduce", Value: bson.D{
+				{Key: "input", Value: "$orders"},
+				{Key: "initialValue", Value: bson.A{}},
+				{Key: "in", Value: bson.D{{Key: "$setUnion", Value: bson.A{"$$value", "$$this"}}}},
+			}}}}}},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "revenue", Value: -1}}}},
+	)
+
+	cursor, err := orderItemCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error building item sales report"}`, http.StatusInternalServerError)
+		return
+	}
+	rows := []itemSalesRow{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		http.Error(w, `{"success": false, "message": "Error building item sales report"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var totalRevenue float64
+	var totalQuantity int64
+	for _, row := range rows {
+		totalRevenue += row.Revenue
+		totalQuantity += row.Quantity
+	}
+	for i := range rows {
+		rows[i].Revenue = helper.RoundMoney(rows[i].Revenue)
+		if totalRevenue > 0 {
+			rows[i].Share = helper.RoundMoney(rows[i].Revenue / totalRevenue * 100)
+		}
+	}
+
+	if format != "" {
+		header := []interface{}{"key", "name", "quantity", "revenue", "orders", "revenue_share"}
+		exportRows := make([][]interface{}, len(rows))
+		for i, row := range rows {
+			exportRows[i] = []interface{}{row.Key, row.Name, row.Quantity, row.Revenue, row.Orders, row.Share}
+		}
+		writeExport(w, format, "item-sales-"+groupBy, header, exportRows)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Item sales report generated successfully",
+		"data": map[string]interface{}{
+			"from":           from.Format("2006-01-02"),
+			"to":             to.AddDate(0, 0, -1).Format("2006-01-02"),
+			"group_by":       groupBy,
+			"items":          rows,
+			"total_quantity": totalQuantity,
+			"total_revenue":  helper.RoundMoney(totalRevenue),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+type heatmapCell struct {
+	Weekday  int     `json:"weekday"` // 1 = Monday ... 7 = Sunday
+	Hour     int     `json:"hour"`
+	Quantity int64   `json:"quantity"`
+	Revenue  float64 `json:"revenue"`
+}
+
+// GetItemHeatmap reports quantity sold and revenue by weekday and hour over ?from=&to=,
+// for everything or for one ?food_id=
+func GetItemHeatmap(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	from, to, message := reportRange(r)
+	if message != "" {
+		http.Error(w, `{"success": false, "message": "`+message+`"}`, http.StatusBadRequest)
+		return
+	}
+	timezone := reportTimezone()
+
+	pipeline := soldLinesPipeline(r, from, to)
+	if foodId := r.URL.Query().Get("food_id"); foodId != "" {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"lines.food_id": foodId}}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "weekday", Value: bson.D{{Key: "$isoDayOfWeek", Value: bson.D{{Key: "date", Value: "$created_at"}, {Key: "timezone", Value: timezone}}}}},
+				{Key: "hour", Value: bson.D{{Key: "$hour", Value: bson.D{{Key: "date", Value: "$created_at"}, {Key: "timezone", Value: timezone}}}}},
+			}},
+			{Key: "quantity", Value: bson.D{{Key: "$sum", Value: "$lines.quantity"}}},
+			{Key: "revenue", Value: bson.D{{Key: "$sum", Value: "$lines.amount"}}},
+		}}},
+	)
+
+	cursor, err := orderItemCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error building heatmap"}`, http.StatusInternalServerError)
+		return
+	}
+	var results []struct {
+		ID struct {
+			Weekday int `bson:"weekday"`
+			Hour    int `bson:"hour"`
+		} `bson:"_id"`
+		Quantity int64   `bson:"quantity"`
+		Revenue  float64 `bson:"revenue"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		http.Error(w, `{"success": false, "message": "Error building heatmap"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// A full weekday x hour grid, so quiet hours show up as zeros
+	cells := make([]heatmapCell, 0, 7*24)
+	index := make(map[[2]int]int)
+	for weekday := 1; weekday <= 7; weekday++ {
+		for hour := 0; hour < 24; hour++ {
+			index[[2]int{weekday, hour}] = len(cells)
+			cells = append(cells, heatmapCell{Weekday: weekday, Hour: hour})
+		}
+	}
+	for _, result := range results {
+		if i, ok := index[[2]int{result.ID.Weekday, result.ID.Hour}]; ok {
+			cells[i].Quantity = result.Quantity
+			cells[i].Revenue = helper.RoundMoney(result.Revenue)
+		}
+	}
+
+	if format != "" {
+		header := []interface{}{"weekday", "hour", "quantity", "revenue"}
+		rows := make([][]interface{}, len(cells))
+		for i, cell := range cells {
+			rows[i] = []interface{}{cell.Weekday, cell.Hour, cell.Quantity, cell.Revenue}
+		}
+		writeExport(w, format, "item-heatmap", header, rows)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Heatmap generated successfully",
+		"data": map[string]interface{}{
+			"from":  from.Format("2006-01-02"),
+			"to":    to.AddDate(0, 0, -1).Format("2006-01-02"),
+			"cells": cells,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Menu engineering classes
+const (
+	menuClassStar      = "STAR"      // popular and profitable
+	menuClassPlowhorse = "PLOWHORSE" // popular, low margin
+	menuClassPuzzle    = "PUZZLE"    // profitable, rarely ordered
+	menuClassDog       = "DOG"       // neither
+)
+
+type menuEngineeringItem struct {
+	Food_id             string  `json:"food_id"`
+	Name                string  `json:"name"`
+	Menu_name           string  `json:"menu_name"`
+	Category            string  `json:"category"`
+	Quantity            int64   `json:"quantity"`
+	Popularity          float64 `json:"popularity"` // percent of all items sold
+	Average_price       float64 `json:"average_price"`
+	Unit_cost           float64 `json:"unit_cost"`
+	Cost_estimated      bool    `json:"cost_estimated"` // no recipe cost on the food, default_cost_percent used
+	Contribution_margin float64 `json:"contribution_margin"`
+	Total_margin        float64 `json:"total_margin"`
+	High_popularity     bool    `json:"high_popularity"`
+	High_margin         bool    `json:"high_margin"`
+	Class               string  `json:"class"`
+}
+
+// GetMenuEngineeringReport classifies each food sold over ?from=&to= as a star, plowhorse,
+// puzzle or dog. Popularity is high at 70% of an equal share or more; margin is high at the
+// quantity-weighted average contribution margin or more. Foods without a recipe cost use
+// ?default_cost_percent= of their price (0 if not given). Narrow it with ?menu_id= or ?category=.
+func GetMenuEngineeringReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	from, to, message := reportRange(r)
+	if message != "" {
+		http.Error(w, `{"success": false, "message": "`+message+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	var defaultCostPercent float64
+	if value := r.URL.Query().Get("default_cost_percent"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 || parsed > 100 {
+			http.Error(w, `{"success": false, "message": "default_cost_percent must be between 0 and 100"}`, http.StatusBadRequest)
+			return
+		}
+		defaultCostPercent = parsed
+	}
+
+	pipeline := withFoodAndMenu(soldLinesPipeline(r, from, to))
+	if menuId := r.URL.Query().Get("menu_id"); menuId != "" {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"menu_id": menuId}}})
+	}
+	if category := r.URL.Query().Get("category"); category != "" {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"category": category}}})
+	}
+
+	cursor, err := orderItemCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error building menu engineering report"}`, http.StatusInternalServerError)
+		return
+	}
+	var foods []struct {
+		Food_id   string   `bson:"_id"`
+		Name      string   `bson:"name"`
+		Quantity  int64    `bson:"quantity"`
+		Revenue   float64  `bson:"revenue"`
+		Cost      *float64 `bson:"cost"`
+		Menu_name string   `bson:"menu_name"`
+		Category  string   `bson:"category"`
+	}
+	if err := cursor.All(ctx, &foods); err != nil {
+		http.Error(w, `{"success": false, "message": "Error building menu engineering report"}`, http.StatusInternalServerError)
+		return
+	}
+
+	items := []menuEngineeringItem{}
+	var totalQuantity int64
+	var totalMargin float64
+	for _, food := range foods {
+		if food.Quantity <= 0 {
+			continue
+		}
+		item := menuEngineeringItem{
+			Food_id:       food.Food_id,
+			Name:          food.Name,
+			Menu_name:     food.Menu_name,
+			Category:      food.Category,
+			Quantity:      food.Quantity,
+			Average_price: food.Revenue / float64(food.Quantity),
+		}
+		if food.Cost != nil {
+			item.Unit_cost = *food.Cost
+		} else {
+			item.Unit_cost = item.Average_price * defaultCostPercent / 100
+			item.Cost_estimated = true
+		}
+		item.Contribution_margin = item.Average_price - item.Unit_cost
+		item.Total_margin = item.Contribution_margin * float64(item.Quantity)
+
+		totalQuantity += item.Quantity
+		totalMargin += item.Total_margin
+		items = append(items, item)
+	}
+
+	var popularityThreshold, marginThreshold float64
+	if len(items) > 0 && totalQuantity > 0 {
+		popularityThreshold = 0.7 * 100 / float64(len(items))
+		marginThreshold = totalMargin / float64(totalQuantity)
+	}
+	for i := range items {
+		item := &items[i]
+		item.Popularity = float64(item.Quantity) / float64(totalQuantity) * 100
+		item.High_popularity = item.Popularity >= popularityThreshold
+		item.High_margin = item.Contribution_margin >= marginThreshold
+		switch {
+		case item.High_popularity && item.High_margin:
+			item.Class = menuClassStar
+		case item.High_popularity:
+			item.Class = menuClassPlowhorse
+		case item.High_margin:
+			item.Class = menuClassPuzzle
+		default:
+			item.Class = menuClassDog
+		}
+
+		item.Popularity = helper.RoundMoney(item.Popularity)
+		item.Average_price = helper.RoundMoney(item.Average_price)
+		item.Unit_cost = helper.RoundMoney(item.Unit_cost)
+		item.Contribution_margin = helper.RoundMoney(item.Contribution_margin)
+		item.Total_margin = helper.RoundMoney(item.Total_margin)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Total_margin > items[j].Total_margin })
+
+	if format != "" {
+		header := []interface{}{
+			"food_id", "name", "menu_name", "category", "quantity", "popularity", "average_price",
+			"unit_cost", "cost_estimated", "contribution_margin", "total_margin", "class",
+		}
+		rows := make([][]interface{}, len(items))
+		for i, item := range items {
+			rows[i] = []interface{}{
+				item.Food_id, item.Name, item.Menu_name, item.Category, item.Quantity, item.Popularity, item.Average_price,
+				item.Unit_cost, item.Cost_estimated, item.Contribution_margin, item.Total_margin, item.Class,
+			}
+		}
+		writeExport(w, format, "menu-engineering", header, rows)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Menu engineering report generated successfully",
+		"data": map[string]interface{}{
+			"from":                 from.Format("2006-01-02"),
+			"to":                   to.AddDate(0, 0, -1).Format("2006-01-02"),
+			"items":                items,
+			"total_quantity":       totalQuantity,
+			"total_margin":         helper.RoundMoney(totalMargin),
+			"popularity_threshold": helper.RoundMoney(popularityThreshold),
+			"margin_threshold":     helper.RoundMoney(marginThreshold),
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
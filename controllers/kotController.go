@@ -0,0 +1,685 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var kotCollection *mongo.Collection = database.OpenCollection(database.Client, "kot")
+var kitchenStationCollection *mongo.Collection = database.OpenCollection(database.Client, "kitchen_station")
+
+const (
+	kotActive    = "ACTIVE"
+	kotCancelled = "CANCELLED"
+)
+
+// kitchenRouting maps lower-cased menu categories to the station that prepares them
+type kitchenRouting struct {
+	byCategory map[string]models.KitchenStation
+	fallback   models.KitchenStation
+}
+
+// loadKitchenRouting reads the stations of an outlet, "" meaning those without one
+func loadKitchenRouting(ctx context.Context, outletId string) (kitchenRouting, error) {
+	kitchenName := "Kitchen"
+	routing := kitchenRouting{
+		byCategory: make(map[string]models.KitchenStation),
+		fallback:   models.KitchenStation{Name: &kitchenName},
+	}
+
+	cursor, err := kitchenStationCollection.Find(ctx, sameOutlet(bson.M{}, outletId))
+	if err != nil {
+		return routing, err
+	}
+	defer cursor.Close(ctx)
+
+	var stations []models.KitchenStation
+	if err := cursor.All(ctx, &stations); err != nil {
+		return routing, err
+	}
+
+	for _, station := range stations {
+		if station.Default {
+			routing.fallback = station
+		}
+		for _, category := range station.Categories {
+			routing.byCategory[strings.ToLower(category)] = station
+		}
+	}
+	return routing, nil
+}
+
+// stationForFood routes a food to the station that prepares its menu's category
+func (routing kitchenRouting) stationForFood(ctx context.Context, foodId string) models.KitchenStation {
+	var food models.Food
+	if err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&food); err != nil || food.Menu_id == nil {
+		return routing.fallback
+	}
+	var menu models.Menu
+	if err := menuCollection.FindOne(ctx, bson.M{"menu_id": *food.Menu_id}).Decode(&menu); err != nil {
+		return routing.fallback
+	}
+	if station, ok := routing.byCategory[strings.ToLower(menu.Category)]; ok {
+		return station
+	}
+	return routing.fallback
+}
+
+// generateKOTs splits a new order item batch into one numbered ticket per kitchen station
+// and sends each ticket to its station printer in the background.
+func generateKOTs(ctx context.Context, r *http.Request, orderItem models.OrderItem) ([]models.Kot, error) {
+	var items []models.KotItem
+	for _, line := range orderItem.Lines {
+		items = append(items, models.KotItem{Food_id: line.Food_id, Name: line.Name, Quantity: line.Quantity})
+	}
+	return issueKOTs(ctx, r, orderItem, items, false)
+}
+
+// generateAmendmentKOTs tells each station about quantities changed on an order item after its
+// tickets were printed: more of a food is cooked, less is taken off the line.
+func generateAmendmentKOTs(ctx context.Context, r *http.Request, orderItem models.OrderItem, changes []orderLineChange) ([]models.Kot, error) {
+	var items []models.KotItem
+	for _, change := range changes {
+		items = append(items, models.KotItem{Food_id: change.Food_id, Name: change.Name, Quantity: change.To - change.From})
+	}
+	return issueKOTs(ctx, r, orderItem, items, true)
+}
+
+// issueKOTs records and prints one ticket per station for the items
+func issueKOTs(ctx context.Context, r *http.Request, orderItem models.OrderItem, items []models.KotItem, amendment bool) ([]models.Kot, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	// Tickets go to the stations of the order's outlet
+	var order models.Order
+	if err := orderCollection.FindOne(ctx, bson.M{"order_id": orderItem.Order_id}).Decode(&order); err != nil {
+		return nil, err
+	}
+	routing, err := loadKitchenRouting(ctx, order.Outlet_id)
+	if err != nil {
+		return nil, err
+	}
+
+	tableNumber := 0
+	var table models.Table
+	if err := tableCollection.FindOne(ctx, bson.M{"table_id": orderItem.Table_id}).Decode(&table); err == nil && table.Table_number != nil {
+		tableNumber = *table.Table_number
+	}
+
+	_, firstName, lastName, uid := middleware.GetUserFromContext(r)
+	waiterName := strings.TrimSpace(firstName + " " + lastName)
+	if uid == "" {
+		waiterName = "Guest (QR)"
+	}
+
+	// Group the items by station, keeping the order they were rung in
+	var stations []models.KitchenStation
+	itemsByStation := make(map[string][]models.KotItem)
+	for _, item := range items {
+		station := routing.stationForFood(ctx, item.Food_id)
+		if _, seen := itemsByStation[station.Station_id]; !seen {
+			stations = append(stations, station)
+		}
+		itemsByStation[station.Station_id] = append(itemsByStation[station.Station_id], item)
+	}
+
+	// KOT numbers run per outlet and day; data from before outlets keeps the original counter
+	now := time.Now()
+	kotDate := now.Format("2006-01-02")
+	sequence := "kot:" + kotDate
+	if order.Outlet_id != "" {
+		sequence = "kot:" + order.Outlet_id + ":" + kotDate
+	}
+	var kots []models.Kot
+	for _, station := range stations {
+		number, err := helper.NextSequence(ctx, sequence)
+		if err != nil {
+			return kots, err
+		}
+
+		kot := models.Kot{
+			ID:            primitive.NewObjectID(),
+			Kot_number:    number,
+			Kot_date:      kotDate,
+			Order_id:      orderItem.Order_id,
+			Order_item_id: orderItem.Order_item_id,
+			Table_id:      orderItem.Table_id,
+			Table_number:  tableNumber,
+			Waiter_id:     uid,
+			Waiter_name:   waiterName,
+			Station_id:    station.Station_id,
+			Station_name:  *station.Name,
+			Items:         itemsByStation[station.Station_id],
+			Amendment:     amendment,
+			Notes:         orderItem.Notes,
+			Status:        kotActive,
+			Created_at:    now,
+		}
+		kot.Kot_id = kot.ID.Hex()
+
+		if _, err := kotCollection.InsertOne(ctx, kot); err != nil {
+			return kots, err
+		}
+		kots = append(kots, kot)
+
+		go printKOT(kot, station, "")
+	}
+	return kots, nil
+}
+
+// printKOT sends a ticket to its station printer and records the outcome on the KOT.
+// It runs outside the request, so it uses its own context.
+func printKOT(kot models.Kot, station models.KitchenStation, banner string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var update bson.M
+	if station.Printer_address == "" {
+		update = bson.M{"$set": bson.M{"print_error": "No printer configured for station " + kot.Station_name}}
+	} else if err := helper.SendToPrinter(station.Printer_address, helper.RenderKOTESCPOS(kot, station.Paper_width, banner)); err != nil {
+		log.Printf("kot %d: printing to %s failed: %v", kot.Kot_number, station.Printer_address, err)
+		update = bson.M{"$set": bson.M{"print_error": err.Error()}}
+	} else {
+		update = bson.M{
+			"$set":   bson.M{"last_printed_at": time.Now()},
+			"$inc":   bson.M{"print_count": 1},
+			"$unset": bson.M{"print_error": ""},
+		}
+	}
+
+	if _, err := kotCollection.UpdateOne(ctx, bson.M{"kot_id": kot.Kot_id}, update); err != nil {
+		log.Printf("kot %d: failed to record print status: %v", kot.Kot_number, err)
+	}
+}
+
+// kotStation returns the station a ticket was routed to, or an unprintable placeholder if it is gone
+func kotStation(ctx context.Context, kot models.Kot) models.KitchenStation {
+	var station models.KitchenStation
+	if kot.Station_id == "" || kitchenStationCollection.FindOne(ctx, bson.M{"station_id": kot.Station_id}).Decode(&station) != nil {
+		return models.KitchenStation{Station_id: kot.Station_id, Name: &kot.Station_name}
+	}
+	return station
+}
+
+// cancelKOT marks an active ticket cancelled and prints a cancellation slip at its station
+func cancelKOT(ctx context.Context, r *http.Request, kot models.Kot, reason string) (models.Kot, error) {
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"status":        kotCancelled,
+		"cancel_reason": reason,
+		"cancelled_by":  uid,
+		"cancelled_at":  now,
+	}}
+
+	result, err := kotCollection.UpdateOne(ctx, bson.M{"kot_id": kot.Kot_id, "status": kotActive}, update)
+	if err != nil {
+		return kot, err
+	}
+	if result.MatchedCount == 0 {
+		return kot, mongo.ErrNoDocuments
+	}
+
+	kot.Status = kotCancelled
+	kot.Cancel_reason = reason
+	kot.Cancelled_by = uid
+	kot.Cancelled_at = &now
+
+	go printKOT(kot, kotStation(ctx, kot), helper.KotCancelled)
+	return kot, nil
+}
+
+// cancelOrderItemKOTs cancels every active ticket printed for an order item
+func cancelOrderItemKOTs(ctx context.Context, r *http.Request, orderItemId, reason string) error {
+	cursor, err := kotCollection.Find(ctx, bson.M{"order_item_id": orderItemId, "status": kotActive})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var kots []models.Kot
+	if err := cursor.All(ctx, &kots); err != nil {
+		return err
+	}
+	for _, kot := range kots {
+		if _, err := cancelKOT(ctx, r, kot, reason); ignoreNoDocuments(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderHasPendingInvoice reports whether an order has been billed but not settled
+func orderHasPendingInvoice(ctx context.Context, orderId string) (bool, error) {
+	count, err := invoiceCollection.CountDocuments(ctx, activeFilter(bson.M{"order_id": orderId, "payment_status": "PENDING"}))
+	return count > 0, err
+}
+
+// voidKOTItems takes a cancelled ticket's items off its order item, deleting the order item once it is empty
+func voidKOTItems(ctx context.Context, kot models.Kot) error {
+	var orderItem models.OrderItem
+	if err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": kot.Order_item_id}).Decode(&orderItem); err != nil {
+		return ignoreNoDocuments(err)
+	}
+
+	voided := make(map[string]int)
+	for _, item := range kot.Items {
+		voided[item.Food_id] += item.Quantity
+	}
+
+	var lines []models.OrderLine
+	var totalPrice float64
+	for _, line := range orderItem.Lines {
+		if quantity, ok := voided[line.Food_id]; ok {
+			line.Quantity -= quantity
+			orderItem.Items[line.Name] -= quantity
+			if orderItem.Items[line.Name] <= 0 {
+				delete(orderItem.Items, line.Name)
+			}
+			if line.Quantity <= 0 {
+				continue
+			}
+			line.Amount = helper.RoundMoney(line.Unit_price * float64(line.Quantity))
+		}
+		lines = append(lines, line)
+		totalPrice += line.Amount
+	}
+
+	if len(lines) == 0 {
+		_, err := orderItemCollection.DeleteOne(ctx, bson.M{"order_item_id": kot.Order_item_id})
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"items":       orderItem.Items,
+		"lines":       lines,
+		"total_price": helper.RoundMoney(totalPrice),
+		"updated_at":  time.Now(),
+	}}
+	_, err := orderItemCollection.UpdateOne(ctx, bson.M{"order_item_id": kot.Order_item_id}, update)
+	return err
+}
+
+// GetKOTs lists tickets for a day (?date=YYYY-MM-DD, default today), optionally by station and status
+func GetKOTs(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	kotDate := r.URL.Query().Get("date")
+	if kotDate == "" {
+		kotDate = time.Now().Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", kotDate); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid date, expected YYYY-MM-DD"}`, http.StatusBadRequest)
+		return
+	}
+
+	filter := bson.M{"kot_date": kotDate}
+	if stationId := r.URL.Query().Get("station_id"); stationId != "" {
+		filter["station_id"] = stationId
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filter["status"] = strings.ToUpper(status)
+	}
+
+	// Tickets belong to the outlet of their order
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "order"},
+			{Key: "localField", Value: "order_id"},
+			{Key: "foreignField", Value: "order_id"},
+			{Key: "as", Value: "order"},
+		}}},
+		{{Key: "$match", Value: outletScopeField(r, bson.M{}, "order.outlet_id")}},
+		{{Key: "$unset", Value: "order"}},
+		{{Key: "$sort", Value: bson.D{{Key: "kot_number", Value: 1}}}},
+	}
+	cursor, err := kotCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving KOTs"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	kots := []models.Kot{}
+	if err := cursor.All(ctx, &kots); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding KOTs"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "KOTs retrieved successfully",
+		"data":    kots,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetOrderKOTs lists every ticket printed for an order
+func GetOrderKOTs(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	orderId := mux.Vars(r)["order_id"]
+
+	cursor, err := kotCollection.Find(ctx, bson.M{"order_id": orderId}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving KOTs"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	kots := []models.Kot{}
+	if err := cursor.All(ctx, &kots); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding KOTs"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "KOTs retrieved successfully",
+		"data":    kots,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReprintKOT sends a ticket to its station printer again, marked as a reprint
+func ReprintKOT(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	kotId := mux.Vars(r)["kot_id"]
+
+	var kot models.Kot
+	err := kotCollection.FindOne(ctx, bson.M{"kot_id": kotId}).Decode(&kot)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "KOT not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving KOT"}`, http.StatusInternalServerError)
+		return
+	}
+
+	banner := helper.KotReprint
+	if kot.Status == kotCancelled {
+		banner = helper.KotCancelled
+	}
+	go printKOT(kot, kotStation(ctx, kot), banner)
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "KOT sent to the printer",
+		"data":    kot,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CancelKOT voids the items on a ticket: the ticket is cancelled, the kitchen gets a
+// cancellation slip and the items are taken off the order.
+func CancelKOT(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	kotId := mux.Vars(r)["kot_id"]
+
+	var requestBody struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || strings.TrimSpace(requestBody.Reason) == "" {
+		http.Error(w, `{"success": false, "message": "A reason is required to cancel a KOT"}`, http.StatusBadRequest)
+		return
+	}
+
+	var kot models.Kot
+	err := kotCollection.FindOne(ctx, bson.M{"kot_id": kotId}).Decode(&kot)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "KOT not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving KOT"}`, http.StatusInternalServerError)
+		return
+	}
+	if kot.Status != kotActive {
+		http.Error(w, `{"success": false, "message": "KOT is already cancelled"}`, http.StatusConflict)
+		return
+	}
+
+	// Items on a settled order cannot be voided any more
+	var order models.Order
+	if err := orderCollection.FindOne(ctx, bson.M{"order_id": kot.Order_id}).Decode(&order); err == nil && slices.Contains(closedOrderStatuses, order.Status) {
+		http.Error(w, `{"success": false, "message": "Order is already closed: `+order.Status+`"}`, http.StatusConflict)
+		return
+	}
+	// Nor on a billed order, whose invoice would no longer match it
+	billed, err := orderHasPendingInvoice(ctx, kot.Order_id)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking order invoices"}`, http.StatusInternalServerError)
+		return
+	}
+	if billed {
+		http.Error(w, `{"success": false, "message": "Order has a pending invoice; void it before changing the order"}`, http.StatusConflict)
+		return
+	}
+
+	kot, err = cancelKOT(ctx, r, kot, requestBody.Reason)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "KOT is already cancelled"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to cancel KOT"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := voidKOTItems(ctx, kot); err != nil {
+		http.Error(w, `{"success": false, "message": "KOT cancelled but its items could not be removed from the order"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "KOT cancelled successfully",
+		"data":    kot,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetKitchenStations lists the caller's outlet's kitchen stations and the categories they prepare
+func GetKitchenStations(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	cursor, err := kitchenStationCollection.Find(ctx, outletScope(r, bson.M{}), options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving kitchen stations"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	stations := []models.KitchenStation{}
+	if err := cursor.All(ctx, &stations); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding kitchen stations"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Kitchen stations retrieved successfully",
+		"data":    stations,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// clearOtherDefaultStations keeps a single default station per outlet
+func clearOtherDefaultStations(ctx context.Context, outletId, stationId string) error {
+	_, err := kitchenStationCollection.UpdateMany(ctx,
+		sameOutlet(bson.M{"station_id": bson.M{"$ne": stationId}, "default": true}, outletId),
+		bson.M{"$set": bson.M{"default": false, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// CreateKitchenStation adds a station with its printer and categories to the caller's outlet
+func CreateKitchenStation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var station models.KitchenStation
+	if err := json.NewDecoder(r.Body).Decode(&station); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if validationErr := validate.Struct(station); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "`+validationErr.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	if station.Printer_address != "" && !helper.ValidPrinterAddress(station.Printer_address) {
+		http.Error(w, `{"success": false, "message": "printer_address must be ip:port on a private network, e.g. 192.168.1.50:9100"}`, http.StatusBadRequest)
+		return
+	}
+	if station.Paper_width == 0 {
+		station.Paper_width = 80
+	}
+
+	outletId, outletExists, err := checkRequestOutlet(ctx, r)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking outlet"}`, http.StatusInternalServerError)
+		return
+	}
+	if !outletExists {
+		http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusBadRequest)
+		return
+	}
+
+	station.ID = primitive.NewObjectID()
+	station.Outlet_id = outletId
+	station.Station_id = station.ID.Hex()
+	station.Created_at = time.Now()
+	station.Updated_at = time.Now()
+
+	if _, err := kitchenStationCollection.InsertOne(ctx, station); err != nil {
+		http.Error(w, `{"success": false, "message": "Kitchen station creation failed"}`, http.StatusInternalServerError)
+		return
+	}
+	if station.Default {
+		if err := clearOtherDefaultStations(ctx, station.Outlet_id, station.Station_id); err != nil {
+			http.Error(w, `{"success": false, "message": "Failed to update the default station"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Kitchen station created successfully",
+		"data":    station,
+	})
+}
+
+// UpdateKitchenStation replaces a station's name, categories and printer settings
+func UpdateKitchenStation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	stationId := mux.Vars(r)["station_id"]
+
+	var station models.KitchenStation
+	if err := json.NewDecoder(r.Body).Decode(&station); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if validationErr := validate.Struct(station); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "`+validationErr.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	if station.Printer_address != "" && !helper.ValidPrinterAddress(station.Printer_address) {
+		http.Error(w, `{"success": false, "message": "printer_address must be ip:port on a private network, e.g. 192.168.1.50:9100"}`, http.StatusBadRequest)
+		return
+	}
+	if station.Paper_width == 0 {
+		station.Paper_width = 80
+	}
+
+	update := bson.M{"$set": bson.M{
+		"name":            station.Name,
+		"categories":      station.Categories,
+		"printer_address": station.Printer_address,
+		"paper_width":     station.Paper_width,
+		"default":         station.Default,
+		"updated_at":      time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedStation models.KitchenStation
+	err := kitchenStationCollection.FindOneAndUpdate(ctx, bson.M{"station_id": stationId}, update, opts).Decode(&updatedStation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Kitchen station not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Kitchen station update failed"}`, http.StatusInternalServerError)
+		return
+	}
+	if updatedStation.Default {
+		if err := clearOtherDefaultStations(ctx, updatedStation.Outlet_id, stationId); err != nil {
+			http.Error(w, `{"success": false, "message": "Failed to update the default station"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Kitchen station updated successfully",
+		"data":    updatedStation,
+	})
+}
+
+// DeleteKitchenStation removes a station; its categories fall back to the default station
+func DeleteKitchenStation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	stationId := mux.Vars(r)["station_id"]
+
+	result, err := kitchenStationCollection.DeleteOne(ctx, bson.M{"station_id": stationId})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error deleting kitchen station"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, `{"success": false, "message": "Kitchen station not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Kitchen station deleted successfully",
+	})
+}
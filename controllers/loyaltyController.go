@@ -0,0 +1,529 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var loyaltyAccountCollection *mongo.Collection = database.OpenCollection(database.Client, "loyalty_account")
+var loyaltyLedgerCollection *mongo.Collection = database.OpenCollection(database.Client, "loyalty_ledger")
+
+// Loyalty ledger entry types
+const (
+	loyaltyEarn    = "EARN"
+	loyaltyRedeem  = "REDEEM"
+	loyaltyReverse = "REVERSE" // earned points taken back on a refund
+	loyaltyReturn  = "RETURN"  // redeemed points given back on a refund, void or delete
+	loyaltyExpire  = "EXPIRE"
+)
+
+// Points expiring within this window are flagged on the balance
+const loyaltyExpiryWarning = 30 * 24 * time.Hour
+
+var errLoyaltyInsufficient = errors.New("not enough loyalty points")
+
+// orderCustomerId returns the customer of an order, or "" when the party is not known
+func orderCustomerId(ctx context.Context, orderId *string) string {
+	if orderId == nil {
+		return ""
+	}
+	var order models.Order
+	if err := orderCollection.FindOne(ctx, bson.M{"order_id": *orderId}).Decode(&order); err != nil {
+		return ""
+	}
+	if order.Customer_id != nil {
+		return *order.Customer_id
+	}
+	// Orders from before customer_id were placed by the customer themselves
+	if order.User_id != nil {
+		if msg, err := checkCustomer(ctx, *order.User_id); err == nil && msg == "" {
+			return *order.User_id
+		}
+	}
+	return ""
+}
+
+// inLoyaltyTransaction runs fn in a transaction, so the account, its lots and the ledger move
+// together. A ctx that is already in a session keeps that transaction rather than nesting one.
+func inLoyaltyTransaction(ctx context.Context, fn func(sessCtx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	session, err := database.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+func recordLoyaltyEntry(ctx context.Context, entry models.LoyaltyEntry) (models.LoyaltyEntry, error) {
+	entry.ID = primitive.NewObjectID()
+	entry.Entry_id = entry.ID.Hex()
+	entry.Created_at = time.Now()
+	_, err := loyaltyLedgerCollection.InsertOne(ctx, entry)
+	return entry, err
+}
+
+// useLoyaltyPoints takes points off the customer's oldest unexpired earnings, so the points used
+// are the ones that would expire first. It fails when the lots cannot cover the points.
+func useLoyaltyPoints(ctx context.Context, userId string, points int64) error {
+	filter := bson.M{"user_id": userId, "remaining": bson.M{"$gt": 0}, "expires_at": bson.M{"$gt": time.Now()}}
+	opts := options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}, {Key: "created_at", Value: 1}})
+	cursor, err := loyaltyLedgerCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	var lots []models.LoyaltyEntry
+	if err := cursor.All(ctx, &lots); err != nil {
+		return err
+	}
+	for _, lot := range lots {
+		if points <= 0 {
+			break
+		}
+		take := min(points, lot.Remaining)
+		result, err := loyaltyLedgerCollection.UpdateOne(ctx,
+			bson.M{"entry_id": lot.Entry_id, "remaining": bson.M{"$gte": take}},
+			bson.M{"$inc": bson.M{"remaining": -take}},
+		)
+		if err != nil {
+			return err
+		}
+		if result.ModifiedCount == 1 {
+			points -= take
+		}
+	}
+	if points > 0 {
+		return errLoyaltyInsufficient
+	}
+	return nil
+}
+
+// creditLoyaltyPoints adds points to a customer's balance that expire after LOYALTY_EXPIRY_DAYS.
+// Only earned points count towards the tier.
+func creditLoyaltyPoints(ctx context.Context, userId, entryType string, points int64, invoiceId, tier, uid string) (models.LoyaltyEntry, error) {
+	now := time.Now()
+	inc := bson.M{"balance": points}
+	if entryType == loyaltyEarn {
+		inc["lifetime_points"] = points
+	}
+	update := bson.M{
+		"$inc":         inc,
+		"$set":         bson.M{"updated_at": now},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	expiresAt := now.Add(helper.LoyaltyExpiry())
+
+	var entry models.LoyaltyEntry
+	err := inLoyaltyTransaction(ctx, func(sessCtx context.Context) error {
+		var account models.LoyaltyAccount
+		if err := loyaltyAccountCollection.FindOneAndUpdate(sessCtx, bson.M{"user_id": userId}, update, opts).Decode(&account); err != nil {
+			return err
+		}
+		var err error
+		entry, err = recordLoyaltyEntry(sessCtx, models.LoyaltyEntry{
+			User_id:       userId,
+			Type:          entryType,
+			Points:        points,
+			Balance_after: account.Balance,
+			Remaining:     points,
+			Expires_at:    &expiresAt,
+			Invoice_id:    invoiceId,
+			Tier:          tier,
+			Created_by:    uid,
+		})
+		return err
+	})
+	return entry, err
+}
+
+// takeLoyaltyPoints takes points off a customer's balance and lots, failing without a change if
+// either cannot cover them. Reversed points also come off the lifetime points.
+func takeLoyaltyPoints(ctx context.Context, userId, entryType string, points int64, invoiceId, uid string) (models.LoyaltyEntry, error) {
+	inc := bson.M{"balance": -points}
+	if entryType == loyaltyReverse {
+		inc["lifetime_points"] = -points
+	}
+	update := bson.M{"$inc": inc, "$set": bson.M{"updated_at": time.Now()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var entry models.LoyaltyEntry
+	err := inLoyaltyTransaction(ctx, func(sessCtx context.Context) error {
+		if err := expireLoyaltyPoints(sessCtx, userId); err != nil {
+			return err
+		}
+		var account models.LoyaltyAccount
+		err := loyaltyAccountCollection.FindOneAndUpdate(sessCtx, bson.M{"user_id": userId, "balance": bson.M{"$gte": points}}, update, opts).Decode(&account)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return errLoyaltyInsufficient
+		} else if err != nil {
+			return err
+		}
+
+		if err := useLoyaltyPoints(sessCtx, userId, points); err != nil {
+			return err
+		}
+		entry, err = recordLoyaltyEntry(sessCtx, models.LoyaltyEntry{
+			User_id:       userId,
+			Type:          entryType,
+			Points:        -points,
+			Balance_after: account.Balance,
+			Invoice_id:    invoiceId,
+			Created_by:    uid,
+		})
+		return err
+	})
+	return entry, err
+}
+
+// expireLoyaltyPoints writes off what is left of a customer's expired earnings. It runs whenever
+// the balance is read, so expired points never show.
+func expireLoyaltyPoints(ctx context.Context, userId string) error {
+	filter := bson.M{"user_id": userId, "remaining": bson.M{"$gt": 0}, "expires_at": bson.M{"$lte": time.Now()}}
+	cursor, err := loyaltyLedgerCollection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	var lots []models.LoyaltyEntry
+	if err := cursor.All(ctx, &lots); err != nil {
+		return err
+	}
+	for _, lot := range lots {
+		// Whoever zeroes the lot writes it off, together with the balance and the ledger entry
+		err := inLoyaltyTransaction(ctx, func(sessCtx context.Context) error {
+			result, err := loyaltyLedgerCollection.UpdateOne(sessCtx,
+				bson.M{"entry_id": lot.Entry_id, "remaining": lot.Remaining},
+				bson.M{"$set": bson.M{"remaining": 0}},
+			)
+			if err != nil || result.ModifiedCount == 0 {
+				return err
+			}
+
+			var account models.LoyaltyAccount
+			update := bson.M{"$inc": bson.M{"balance": -lot.Remaining}, "$set": bson.M{"updated_at": time.Now()}}
+			opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+			if err := loyaltyAccountCollection.FindOneAndUpdate(sessCtx, bson.M{"user_id": userId}, update, opts).Decode(&account); err != nil {
+				return err
+			}
+			_, err = recordLoyaltyEntry(sessCtx, models.LoyaltyEntry{
+				User_id:       userId,
+				Type:          loyaltyExpire,
+				Points:        -lot.Remaining,
+				Balance_after: account.Balance,
+				Invoice_id:    lot.Invoice_id,
+			})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loyaltyAccountOf returns a customer's points after writing off expired ones, empty when they have none yet
+func loyaltyAccountOf(ctx context.Context, userId string) (models.LoyaltyAccount, error) {
+	account := models.LoyaltyAccount{User_id: userId}
+	if err := expireLoyaltyPoints(ctx, userId); err != nil {
+		return account, err
+	}
+	err := loyaltyAccountCollection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&account)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return account, nil
+	}
+	return account, err
+}
+
+// earnLoyaltyPoints credits the customer of a paid invoice with points on what they spent before
+// taxes, at their tier's rate. An invoice earns once however often it is marked paid. It returns
+// the points earned.
+func earnLoyaltyPoints(ctx context.Context, invoice models.Invoice, uid string) (int64, error) {
+	if invoice.Customer_id == "" || invoice.Loyalty_points_earned > 0 {
+		return 0, nil
+	}
+	account, err := loyaltyAccountOf(ctx, invoice.Customer_id)
+	if err != nil {
+		return 0, err
+	}
+	tier, _ := helper.LoyaltyTierFor(account.Lifetime_points)
+
+	spent := invoice.Subtotal - invoice.Discount_amount
+	if invoice.Subtotal == 0 {
+		spent = invoice.TotalPrice // invoices from before taxes were split out
+	}
+	points := helper.LoyaltyPointsFor(spent, tier)
+	if points == 0 {
+		return 0, nil
+	}
+
+	// Claim the points on the invoice in the same transaction as the credit, so a redelivered
+	// payment never earns twice and a failed credit leaves the invoice unclaimed
+	var earned int64
+	err = inLoyaltyTransaction(ctx, func(sessCtx context.Context) error {
+		earned = 0
+		result, err := invoiceCollection.UpdateOne(sessCtx,
+			bson.M{"invoice_id": invoice.Invoice_id, "loyalty_points_earned": bson.M{"$in": bson.A{0, nil}}},
+			bson.M{"$set": bson.M{"loyalty_points_earned": points}},
+		)
+		if err != nil || result.ModifiedCount == 0 {
+			return err
+		}
+		if _, err := creditLoyaltyPoints(sessCtx, invoice.Customer_id, loyaltyEarn, points, invoice.Invoice_id, tier.Name, uid); err != nil {
+			return err
+		}
+		earned = points
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return earned, nil
+}
+
+// invoiceLoyaltyPoints totals the points an invoice moved by entry type, as positive numbers
+func invoiceLoyaltyPoints(ctx context.Context, invoiceId string) (map[string]int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"invoice_id": invoiceId}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$type"},
+			{Key: "points", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$abs", Value: "$points"}}}}},
+		}}},
+	}
+	cursor, err := loyaltyLedgerCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var totals []struct {
+		Type   string `bson:"_id"`
+		Points int64  `bson:"points"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, err
+	}
+	points := make(map[string]int64, len(totals))
+	for _, total := range totals {
+		points[total.Type] = total.Points
+	}
+	return points, nil
+}
+
+// refundLoyaltyPoints undoes the points of the refunded share of an invoice: earned points are
+// taken back, as far as the balance allows, and redeemed points are given back. A full refund, void
+// or delete undoes whatever is left.
+func refundLoyaltyPoints(ctx context.Context, invoice models.Invoice, amount float64, full bool, uid string) error {
+	if invoice.Customer_id == "" {
+		return nil
+	}
+	moved, err := invoiceLoyaltyPoints(ctx, invoice.Invoice_id)
+	if err != nil {
+		return err
+	}
+	share := func(points, outstanding int64) int64 {
+		if full || invoice.TotalPrice <= 0 {
+			return outstanding
+		}
+		return min(int64(math.Round(float64(points)*amount/invoice.TotalPrice)), outstanding)
+	}
+
+	if reverse := share(invoice.Loyalty_points_earned, moved[loyaltyEarn]-moved[loyaltyReverse]); reverse > 0 {
+		account, err := loyaltyAccountOf(ctx, invoice.Customer_id)
+		if err != nil {
+			return err
+		}
+		// Points already spent cannot be taken back
+		if reverse = min(reverse, account.Balance); reverse > 0 {
+			if _, err := takeLoyaltyPoints(ctx, invoice.Customer_id, loyaltyReverse, reverse, invoice.Invoice_id, uid); err != nil {
+				return err
+			}
+		}
+	}
+	if give := share(invoice.Loyalty_points_redeemed, moved[loyaltyRedeem]-moved[loyaltyReturn]); give > 0 {
+		if _, err := creditLoyaltyPoints(ctx, invoice.Customer_id, loyaltyReturn, give, invoice.Invoice_id, "", uid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loyaltyBalanceResponse writes a customer's balance, tier and the points about to expire
+func loyaltyBalanceResponse(ctx context.Context, w http.ResponseWriter, userId string) {
+	account, err := loyaltyAccountOf(ctx, userId)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving loyalty points"}`, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	expiringBefore := now.Add(loyaltyExpiryWarning)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userId, "remaining": bson.M{"$gt": 0}, "expires_at": bson.M{"$gt": now, "$lte": expiringBefore}}}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: nil}, {Key: "points", Value: bson.D{{Key: "$sum", Value: "$remaining"}}}}}},
+	}
+	cursor, err := loyaltyLedgerCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving expiring points"}`, http.StatusInternalServerError)
+		return
+	}
+	var expiring []struct {
+		Points int64 `bson:"points"`
+	}
+	if err := cursor.All(ctx, &expiring); err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving expiring points"}`, http.StatusInternalServerError)
+		return
+	}
+	var expiringPoints int64
+	if len(expiring) > 0 {
+		expiringPoints = expiring[0].Points
+	}
+
+	tier, next := helper.LoyaltyTierFor(account.Lifetime_points)
+	data := map[string]interface{}{
+		"user_id":         userId,
+		"balance":         account.Balance,
+		"balance_value":   helper.RoundMoney(float64(account.Balance) * helper.LoyaltyPointValue()),
+		"lifetime_points": account.Lifetime_points,
+		"tier":            tier,
+		"earn_rate":       helper.LoyaltyEarnRate(),
+		"point_value":     helper.LoyaltyPointValue(),
+		"expiring_soon": map[string]interface{}{
+			"points": expiringPoints,
+			"before": expiringBefore,
+		},
+	}
+	if next != nil {
+		data["next_tier"] = next
+		data["points_to_next_tier"] = next.Min_points - account.Lifetime_points
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Loyalty points retrieved successfully",
+		"data":    data,
+	})
+}
+
+// loyaltyHistoryResponse writes a page of a customer's ledger, newest first
+func loyaltyHistoryResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, userId string) {
+	recordPerPage, err := strconv.Atoi(r.URL.Query().Get("recordPerPage"))
+	if err != nil || recordPerPage < 1 {
+		recordPerPage = 10
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	if err := expireLoyaltyPoints(ctx, userId); err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving loyalty points"}`, http.StatusInternalServerError)
+		return
+	}
+
+	filter := bson.M{"user_id": userId}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * recordPerPage)).
+		SetLimit(int64(recordPerPage))
+	cursor, err := loyaltyLedgerCollection.Find(ctx, filter, opts)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving loyalty history"}`, http.StatusInternalServerError)
+		return
+	}
+	entries := []models.LoyaltyEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding loyalty history"}`, http.StatusInternalServerError)
+		return
+	}
+
+	totalCount, err := loyaltyLedgerCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving total entry count"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Loyalty history retrieved successfully",
+		"data":    entries,
+		"pagination": map[string]interface{}{
+			"current_page":     page,
+			"records_per_page": recordPerPage,
+			"total_entries":    totalCount,
+			"total_pages":      (totalCount + int64(recordPerPage) - 1) / int64(recordPerPage),
+		},
+	})
+}
+
+// customerForLoyalty checks the {user_id} of the request is a customer, answering for it if not
+func customerForLoyalty(ctx context.Context, w http.ResponseWriter, r *http.Request) (string, bool) {
+	userId := mux.Vars(r)["user_id"]
+	msg, err := checkCustomer(ctx, userId)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking customer"}`, http.StatusInternalServerError)
+		return "", false
+	}
+	if msg != "" {
+		http.Error(w, `{"success": false, "message": "`+msg+`"}`, http.StatusNotFound)
+		return "", false
+	}
+	return userId, true
+}
+
+// GetCustomerLoyalty returns a customer's points balance and tier
+func GetCustomerLoyalty(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	if userId, ok := customerForLoyalty(ctx, w, r); ok {
+		loyaltyBalanceResponse(ctx, w, userId)
+	}
+}
+
+// GetCustomerLoyaltyHistory lists a customer's points earned, redeemed, reversed and expired
+func GetCustomerLoyaltyHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	if userId, ok := customerForLoyalty(ctx, w, r); ok {
+		loyaltyHistoryResponse(ctx, w, r, userId)
+	}
+}
+
+// GetMyLoyalty returns the signed-in customer's points balance and tier
+func GetMyLoyalty(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	loyaltyBalanceResponse(ctx, w, uid)
+}
+
+// GetMyLoyaltyHistory lists the signed-in customer's points history
+func GetMyLoyaltyHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	loyaltyHistoryResponse(ctx, w, r, uid)
+}
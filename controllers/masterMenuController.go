@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var foodOverrideCollection *mongo.Collection = database.OpenCollection(database.Client, "food_override")
+
+// Menus and foods without an outlet make up the master menu every outlet inherits. Outlets add their
+// own menus and foods next to it, and change master foods for themselves through a food override.
+
+// canEditMasterMenu reports whether the caller may create or change master menus and foods,
+// which head office alone does; users without an outlet are not head office by that alone
+func canEditMasterMenu(r *http.Request) bool {
+	_, headOffice := middleware.GetOutletFromContext(r)
+	return headOffice
+}
+
+// masterOrOutlet matches the master menu plus the outlet's own menus and foods; "" is the master alone
+func masterOrOutlet(filter bson.M, outletId string) bson.M {
+	if outletId == "" {
+		filter["outlet_id"] = nil
+	} else {
+		filter["outlet_id"] = bson.M{"$in": bson.A{outletId, nil}}
+	}
+	return filter
+}
+
+// menuScope restricts menus or foods to the ones the caller's outlet sees
+func menuScope(r *http.Request, filter bson.M) bson.M {
+	if _, scoped := outletScopeValue(r); !scoped {
+		return filter
+	}
+	return masterOrOutlet(filter, requestOutletId(r))
+}
+
+// menuListFilter is listFilter for menus and foods, which include the master menu
+func menuListFilter(r *http.Request, filter bson.M) bson.M {
+	return deletedFilter(r, menuScope(r, filter))
+}
+
+// effectiveFoodStages applies an outlet's overrides to the foods coming down a pipeline. Hidden foods
+// are dropped, price and available come from the override where it sets them, and overridden
+// tells whether the outlet changed the food.
+func effectiveFoodStages(outletId string) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "food_override"},
+			{Key: "let", Value: bson.D{{Key: "food_id", Value: "$food_id"}}},
+			{Key: "pipeline", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "$expr", Value: bson.D{{Key: "$and", Value: bson.A{
+					bson.D{{Key: "$eq", Value: bson.A{"$food_id", "$$food_id"}}},
+					bson.D{{Key: "$eq", Value: bson.A{"$outlet_id", outletId}}},
+				}}}}}}},
+			}},
+			{Key: "as", Value: "override"},
+		}}},
+		{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$override"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
+		{{Key: "$match", Value: bson.M{"override.hidden": bson.M{"$ne": true}}}},
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "price", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$override.price", "$price"}}}},
+			{Key: "available", Value: bson.D{{Key: "$ifNull", Value: bson.A{
+				"$override.available", bson.D{{Key: "$ifNull", Value: bson.A{"$available", true}}},
+			}}}},
+			{Key: "overridden", Value: bson.D{{Key: "$ne", Value: bson.A{bson.D{{Key: "$type", Value: "$override"}}, "missing"}}}},
+		}}},
+		{{Key: "$project", Value: bson.D{{Key: "override", Value: 0}}}},
+	}
+}
+
+// effectiveFood loads a food as an outlet sells it. Deleted foods, foods of other outlets and
+// foods the outlet hides come back as mongo.ErrNoDocuments.
+func effectiveFood(ctx context.Context, outletId, foodId string) (models.Food, error) {
+	pipeline := append(mongo.Pipeline{
+		{{Key: "$match", Value: activeFilter(masterOrOutlet(bson.M{"food_id": foodId}, outletId))}},
+	}, effectiveFoodStages(outletId)...)
+	cursor, err := foodCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return models.Food{}, err
+	}
+	var foods []models.Food
+	if err := cursor.All(ctx, &foods); err != nil {
+		return models.Food{}, err
+	}
+	if len(foods) == 0 {
+		return models.Food{}, mongo.ErrNoDocuments
+	}
+	return foods[0], nil
+}
+
+// overrideOutletId is the outlet whose overrides the request works on. Head office must pick one.
+func overrideOutletId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	outletId := requestOutletId(r)
+	if outletId == "" {
+		http.Error(w, `{"success": false, "message": "Overrides belong to an outlet, pick one with the X-Outlet-Id header"}`, http.StatusBadRequest)
+		return "", false
+	}
+	return outletId, true
+}
+
+// GetFoodOverrides lists the caller's outlet's changes to the master menu
+func GetFoodOverrides(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	outletId, ok := overrideOutletId(w, r)
+	if !ok {
+		return
+	}
+
+	cursor, err := foodOverrideCollection.Find(ctx, bson.M{"outlet_id": outletId}, options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving food overrides"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	overrides := []models.FoodOverride{}
+	if err := cursor.All(ctx, &overrides); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding food overrides"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Food overrides retrieved successfully",
+		"data":    overrides,
+	})
+}
+
+// SetFoodOverride sets the caller's outlet's price, availability or hiding of a master food.
+// It replaces any earlier override; fields left out follow the master again.
+func SetFoodOverride(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	outletId, ok := overrideOutletId(w, r)
+	if !ok {
+		return
+	}
+	foodId := mux.Vars(r)["food_id"]
+
+	var override models.FoodOverride
+	if err := json.NewDecoder(r.Body).Decode(&override); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if validationErr := validate.Struct(override); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "`+validationErr.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	if override.Price == nil && override.Available == nil && !override.Hidden {
+		http.Error(w, `{"success": false, "message": "Set price, available or hidden, or delete the override to follow the master"}`, http.StatusBadRequest)
+		return
+	}
+
+	// The outlet's own foods are edited directly
+	count, err := foodCollection.CountDocuments(ctx, activeFilter(bson.M{"food_id": foodId, "outlet_id": nil}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking food"}`, http.StatusInternalServerError)
+		return
+	}
+	if count == 0 {
+		http.Error(w, `{"success": false, "message": "Food not found on the master menu"}`, http.StatusNotFound)
+		return
+	}
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	override.ID = primitive.NilObjectID
+	override.Outlet_id = outletId
+	override.Food_id = foodId
+	override.Updated_by = uid
+	override.Updated_at = time.Now()
+
+	filter := bson.M{"outlet_id": outletId, "food_id": foodId}
+	opts := options.FindOneAndReplace().SetUpsert(true).SetReturnDocument(options.After)
+	if err := foodOverrideCollection.FindOneAndReplace(ctx, filter, override, opts).Decode(&override); err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to save food override"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Food override saved successfully",
+		"data":    override,
+	})
+}
+
+// DeleteFoodOverride drops the caller's outlet's override, so the food follows the master again
+func DeleteFoodOverride(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	outletId, ok := overrideOutletId(w, r)
+	if !ok {
+		return
+	}
+
+	var override models.FoodOverride
+	err := foodOverrideCollection.FindOneAndDelete(ctx, bson.M{"outlet_id": outletId, "food_id": mux.Vars(r)["food_id"]}).Decode(&override)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Food override not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to delete food override"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Food override deleted successfully",
+		"data":    override,
+	})
+}
@@ -37,7 +37,8 @@ func GetMenus(w http.ResponseWriter, r *http.Request) {
 
 	startIndex := (page - 1) * recordPerPage
 
-	matchStage := bson.D{{Key: "$match", Value: bson.D{}}}
+	filter := menuListFilter(r, bson.M{})
+	matchStage := bson.D{{Key: "$match", Value: filter}}
 	skipStage := bson.D{{Key: "$skip", Value: startIndex}}
 	limitStage := bson.D{{Key: "$limit", Value: int64(recordPerPage)}}
 	projectStage := bson.D{
@@ -48,6 +49,8 @@ func GetMenus(w http.ResponseWriter, r *http.Request) {
 			{Key: "category", Value: 1},
 			{Key: "created_at", Value: 1},
 			{Key: "updated_at", Value: 1},
+			{Key: "deleted_at", Value: 1},
+			{Key: "deleted_by", Value: 1},
 		}},
 	}
 
@@ -63,7 +66,7 @@ func GetMenus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	totalMenus, err := menuCollection.CountDocuments(ctx, bson.M{})
+	totalMenus, err := menuCollection.CountDocuments(ctx, filter)
 	if err != nil {
 		http.Error(w, "Error retrieving total menu count", http.StatusInternalServerError)
 		return
@@ -97,7 +100,7 @@ func GetMenu(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var menu models.Menu
-	err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu)
+	err := menuCollection.FindOne(ctx, activeFilter(bson.M{"menu_id": menuId})).Decode(&menu)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		http.Error(w, "Menu not found", http.StatusNotFound)
 		return
@@ -133,8 +136,22 @@ func CreateMenu(w http.ResponseWriter, r *http.Request) {
 	// Generate UniqueID (lowercase version of name)
 	menu.UniqueID = strings.ToLower(menu.Name)
 
-	// Check if a menu with the same UniqueID already exists
-	count, err := menuCollection.CountDocuments(ctx, bson.M{"unique_id": menu.UniqueID})
+	outletId, outletExists, err := checkRequestOutlet(ctx, r)
+	if err != nil {
+		http.Error(w, "Error checking outlet", http.StatusInternalServerError)
+		return
+	}
+	if !outletExists {
+		http.Error(w, "Outlet not found", http.StatusBadRequest)
+		return
+	}
+	if outletId == "" && !canEditMasterMenu(r) {
+		http.Error(w, "The master menu is managed by head office", http.StatusForbidden)
+		return
+	}
+
+	// Check if a menu with the same UniqueID already exists in the outlet
+	count, err := menuCollection.CountDocuments(ctx, activeFilter(sameOutlet(bson.M{"unique_id": menu.UniqueID}, outletId)))
 	if err != nil {
 		http.Error(w, "Error checking menu existence", http.StatusInternalServerError)
 		return
@@ -149,6 +166,7 @@ func CreateMenu(w http.ResponseWriter, r *http.Request) {
 	menu.Updated_at = time.Now()
 	menu.ID = primitive.NewObjectID()
 	menu.Menu_id = menu.ID.Hex()
+	menu.Outlet_id = outletId
 
 	_, err = menuCollection.InsertOne(ctx, menu)
 	if err != nil {
@@ -185,7 +203,7 @@ func UpdateMenu(w http.ResponseWriter, r *http.Request) {
 	newUniqueID := strings.ToLower(menu.Name)
 
 	// Check if a menu with the same UniqueID already exists (excluding current menu)
-	count, err := menuCollection.CountDocuments(ctx, bson.M{"unique_id": newUniqueID, "menu_id": bson.M{"$ne": menuId}})
+	count, err := menuCollection.CountDocuments(ctx, activeFilter(outletScope(r, bson.M{"unique_id": newUniqueID, "menu_id": bson.M{"$ne": menuId}})))
 	if err != nil {
 		http.Error(w, "Error checking menu existence", http.StatusInternalServerError)
 		return
@@ -208,7 +226,7 @@ func UpdateMenu(w http.ResponseWriter, r *http.Request) {
 	updateObj = append(updateObj, bson.E{Key: "updated_at", Value: menu.Updated_at})
 
 	opt := options.Update().SetUpsert(false)
-	result, err := menuCollection.UpdateOne(ctx, bson.M{"menu_id": menuId}, bson.D{{Key: "$set", Value: updateObj}}, opt)
+	result, err := menuCollection.UpdateOne(ctx, activeFilter(bson.M{"menu_id": menuId}), bson.D{{Key: "$set", Value: updateObj}}, opt)
 	if err != nil {
 		http.Error(w, "Error updating menu", http.StatusInternalServerError)
 		return
@@ -240,7 +258,8 @@ func UpdateMenu(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Delete a menu
+// Delete a menu. A menu that still has foods is only deleted with ?cascade=true,
+// which deletes its foods along with it.
 func DeleteMenu(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 	defer cancel()
@@ -249,7 +268,7 @@ func DeleteMenu(w http.ResponseWriter, r *http.Request) {
 
 	// Find the menu before deleting
 	var menu models.Menu
-	err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu)
+	err := menuCollection.FindOne(ctx, activeFilter(bson.M{"menu_id": menuId})).Decode(&menu)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		http.Error(w, "Menu not found", http.StatusNotFound)
 		return
@@ -258,14 +277,33 @@ func DeleteMenu(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Foods must not be left pointing at a deleted menu
+	foodCount, err := foodCollection.CountDocuments(ctx, activeFilter(bson.M{"menu_id": menuId}))
+	if err != nil {
+		http.Error(w, "Error checking menu foods", http.StatusInternalServerError)
+		return
+	}
+	if foodCount > 0 && !cascadeRequested(r) {
+		http.Error(w, "Menu still has "+strconv.FormatInt(foodCount, 10)+" food items; delete them first or pass ?cascade=true", http.StatusConflict)
+		return
+	}
+
+	deletedAt := time.Now()
+	if foodCount > 0 {
+		if _, err := softDelete(ctx, r, foodCollection, bson.M{"menu_id": menuId}, deletedAt); err != nil {
+			http.Error(w, "Error deleting menu foods", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// Delete the menu
-	result, err := menuCollection.DeleteOne(ctx, bson.M{"menu_id": menuId})
+	result, err := softDelete(ctx, r, menuCollection, bson.M{"menu_id": menuId}, deletedAt)
 	if err != nil {
 		http.Error(w, "Error deleting menu", http.StatusInternalServerError)
 		return
 	}
 
-	if result.DeletedCount == 0 {
+	if result.MatchedCount == 0 {
 		http.Error(w, "Menu not found", http.StatusNotFound)
 		return
 	}
@@ -274,11 +312,65 @@ func DeleteMenu(w http.ResponseWriter, r *http.Request) {
 		"success": true,
 		"message": "Menu deleted successfully",
 		"data": map[string]interface{}{
-			"menu_id":    menu.Menu_id,
-			"name":       menu.Name,
-			"category":   menu.Category,
-			"created_at": menu.Created_at,
-			"updated_at": menu.Updated_at,
+			"menu_id":       menu.Menu_id,
+			"name":          menu.Name,
+			"category":      menu.Category,
+			"created_at":    menu.Created_at,
+			"updated_at":    menu.Updated_at,
+			"deleted_at":    deletedAt,
+			"deleted_foods": foodCount,
+		},
+	})
+}
+
+// Restore a deleted menu together with the foods that were deleted with it
+func RestoreMenu(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	menuId := mux.Vars(r)["menu_id"]
+
+	var menu models.Menu
+	err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId, "deleted_at": bson.M{"$ne": nil}}).Decode(&menu)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, "Deleted menu not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Error retrieving menu", http.StatusInternalServerError)
+		return
+	}
+
+	// The name may have been reused in the outlet since the menu was deleted
+	count, err := menuCollection.CountDocuments(ctx, activeFilter(sameOutlet(bson.M{"unique_id": menu.UniqueID}, menu.Outlet_id)))
+	if err != nil {
+		http.Error(w, "Error checking menu existence", http.StatusInternalServerError)
+		return
+	}
+	if count > 0 {
+		http.Error(w, "Another menu with this name already exists", http.StatusConflict)
+		return
+	}
+
+	if _, err := restoreDeleted(ctx, menuCollection, bson.M{"menu_id": menuId}); err != nil {
+		http.Error(w, "Error restoring menu", http.StatusInternalServerError)
+		return
+	}
+
+	// Foods deleted by the cascade share the menu's deletion time
+	foodResult, err := restoreDeleted(ctx, foodCollection, bson.M{"menu_id": menuId, "deleted_at": menu.Deleted_at})
+	if err != nil {
+		http.Error(w, "Error restoring menu foods", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Menu restored successfully",
+		"data": map[string]interface{}{
+			"menu_id":        menu.Menu_id,
+			"name":           menu.Name,
+			"category":       menu.Category,
+			"restored_foods": foodResult.ModifiedCount,
 		},
 	})
 }
@@ -0,0 +1,536 @@
+package controller
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Largest menu file accepted for import
+const maxMenuImportBytes = 5 << 20
+
+// Columns of the flat CSV menu format, one row per food
+var menuCSVColumns = []string{"menu_name", "menu_category", "food_name", "price", "cost", "food_image"}
+
+// Import actions reported per menu and food
+const (
+	importCreate    = "create"
+	importUpdate    = "update"
+	importUnchanged = "unchanged"
+	importError     = "error"
+)
+
+// menuFile is the JSON menu format, the same one GET /menus/export writes
+type menuFile struct {
+	Menus []menuFileMenu `json:"menus"`
+}
+
+type menuFileMenu struct {
+	Name     string         `json:"name"`
+	Category string         `json:"category"`
+	Foods    []menuFileFood `json:"foods"`
+	row      int            // CSV row the menu first appeared on
+}
+
+type menuFileFood struct {
+	Name       string   `json:"name"`
+	Price      *float64 `json:"price"`
+	Cost       *float64 `json:"cost,omitempty"`
+	Food_image *string  `json:"food_image,omitempty"`
+	row        int
+}
+
+type menuImportResult struct {
+	Row     int    `json:"row,omitempty"` // CSV rows only
+	Menu    string `json:"menu"`
+	Food    string `json:"food,omitempty"`
+	Action  string `json:"action"`
+	Message string `json:"message,omitempty"`
+}
+
+// menuImportPlan is what an import will do to one menu and its foods
+type menuImportPlan struct {
+	menu     menuFileMenu
+	existing *models.Menu
+	action   string
+	foods    []foodImportPlan
+}
+
+type foodImportPlan struct {
+	food     menuFileFood
+	existing *models.Food
+	action   string
+}
+
+// parseMenuCSV reads the flat CSV format. Rows of the same menu (by name, ignoring case) are
+// gathered into one menu; a row without a food name just declares the menu.
+func parseMenuCSV(body io.Reader) ([]menuFileMenu, []menuImportResult, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, errors.New("CSV file is empty or unreadable")
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"menu_name", "menu_category"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("CSV header is missing the %s column", required)
+		}
+	}
+	cell := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return helper.UnescapeExportCell(strings.TrimSpace(record[i]))
+		}
+		return ""
+	}
+	money := func(value string) (*float64, error) {
+		if value == "" {
+			return nil, nil
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &parsed, nil
+	}
+
+	var menus []menuFileMenu
+	var results []menuImportResult
+	byName := make(map[string]int)
+	reader.FieldsPerRecord = -1
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("CSV row %d: %v", row, err)
+		}
+
+		menuName, category := cell(record, "menu_name"), cell(record, "menu_category")
+		index, seen := byName[strings.ToLower(menuName)]
+		if !seen {
+			index = len(menus)
+			byName[strings.ToLower(menuName)] = index
+			menus = append(menus, menuFileMenu{Name: menuName, Category: category, row: row})
+		} else if category != "" && !strings.EqualFold(category, menus[index].Category) {
+			results = append(results, menuImportResult{Row: row, Menu: menuName, Action: importError,
+				Message: "menu_category differs from row " + strconv.Itoa(menus[index].row)})
+			continue
+		}
+
+		foodName := cell(record, "food_name")
+		if foodName == "" {
+			continue
+		}
+		food := menuFileFood{Name: foodName, row: row}
+		if food.Price, err = money(cell(record, "price")); err != nil {
+			results = append(results, menuImportResult{Row: row, Menu: menuName, Food: foodName, Action: importError, Message: "price is not a number"})
+			continue
+		}
+		if food.Cost, err = money(cell(record, "cost")); err != nil {
+			results = append(results, menuImportResult{Row: row, Menu: menuName, Food: foodName, Action: importError, Message: "cost is not a number"})
+			continue
+		}
+		if image := cell(record, "food_image"); image != "" {
+			food.Food_image = &image
+		}
+		menus[index].Foods = append(menus[index].Foods, food)
+	}
+	return menus, results, nil
+}
+
+// planMenuImport validates every menu and food and works out whether each is created, updated or
+// left alone. Menus match the outlet's existing ones by name ignoring case (their unique_id); foods
+// match by name within their menu (their unique_food_id), as CreateMenu and CreateFood do.
+func planMenuImport(ctx context.Context, outletId string, menus []menuFileMenu) ([]menuImportPlan, []menuImportResult, error) {
+	var plans []menuImportPlan
+	var results []menuImportResult
+	seenMenus := make(map[string]bool)
+
+	for _, menu := range menus {
+		plan := menuImportPlan{menu: menu, action: importCreate}
+		result := menuImportResult{Row: menu.row, Menu: menu.Name}
+
+		uniqueID := strings.ToLower(menu.Name)
+		if err := validate.Struct(models.Menu{Name: menu.Name, Category: menu.Category}); err != nil {
+			result.Action, result.Message = importError, err.Error()
+		} else if seenMenus[uniqueID] {
+			result.Action, result.Message = importError, "menu appears more than once in the file"
+		} else {
+			var existing models.Menu
+			err := menuCollection.FindOne(ctx, activeFilter(sameOutlet(bson.M{"unique_id": uniqueID}, outletId))).Decode(&existing)
+			if err == nil {
+				plan.existing = &existing
+				plan.action = importUnchanged
+				if existing.Category != menu.Category {
+					plan.action = importUpdate
+				}
+			} else if !errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, nil, err
+			}
+			result.Action = plan.action
+		}
+		seenMenus[uniqueID] = true
+		if result.Action == importError {
+			plan.action = importError
+		}
+		results = append(results, result)
+
+		seenFoods := make(map[string]bool)
+		for _, food := range menu.Foods {
+			foodPlan := foodImportPlan{food: food, action: importCreate}
+			foodResult := menuImportResult{Row: food.row, Menu: menu.Name, Food: food.Name}
+
+			placeholderMenuId := "import"
+			candidate := models.Food{Name: &food.Name, Price: food.Price, Cost: food.Cost, Food_image: food.Food_image, Menu_id: &placeholderMenuId}
+			if err := validate.Struct(candidate); err != nil {
+				foodResult.Action, foodResult.Message = importError, err.Error()
+			} else if *food.Price < 0 {
+				foodResult.Action, foodResult.Message = importError, "price cannot be negative"
+			} else if seenFoods[food.Name] {
+				foodResult.Action, foodResult.Message = importError, "Food item with the same name already exists in this menu"
+			} else {
+				if plan.existing != nil {
+					var existing models.Food
+					err := foodCollection.FindOne(ctx, activeFilter(bson.M{"unique_food_id": plan.existing.Menu_id + "-" + food.Name})).Decode(&existing)
+					if err == nil {
+						foodPlan.existing = &existing
+						foodPlan.action = importUnchanged
+						if foodImportChanges(existing, food) != nil {
+							foodPlan.action = importUpdate
+						}
+					} else if !errors.Is(err, mongo.ErrNoDocuments) {
+						return nil, nil, err
+					}
+				}
+				foodResult.Action = foodPlan.action
+			}
+			seenFoods[food.Name] = true
+			if foodResult.Action == importError {
+				foodPlan.action = importError
+			}
+			plan.foods = append(plan.foods, foodPlan)
+			results = append(results, foodResult)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, results, nil
+}
+
+// foodImportChanges returns the fields an import row changes on an existing food, or nil.
+// Cost and image are only changed when the row gives them.
+func foodImportChanges(existing models.Food, food menuFileFood) bson.M {
+	changes := bson.M{}
+	if existing.Price == nil || *existing.Price != *food.Price {
+		changes["price"] = *food.Price
+	}
+	if food.Cost != nil && (existing.Cost == nil || *existing.Cost != *food.Cost) {
+		changes["cost"] = *food.Cost
+	}
+	if food.Food_image != nil && (existing.Food_image == nil || *existing.Food_image != *food.Food_image) {
+		changes["food_image"] = *food.Food_image
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// applyMenuImport carries out a plan with no errors in it, creating menus and foods in the outlet
+func applyMenuImport(ctx context.Context, outletId string, plans []menuImportPlan) error {
+	now := time.Now()
+	for _, plan := range plans {
+		menuId := ""
+		switch plan.action {
+		case importCreate:
+			menu := models.Menu{
+				ID:         primitive.NewObjectID(),
+				Name:       plan.menu.Name,
+				Category:   plan.menu.Category,
+				UniqueID:   strings.ToLower(plan.menu.Name),
+				Outlet_id:  outletId,
+				Created_at: now,
+				Updated_at: now,
+			}
+			menu.Menu_id = menu.ID.Hex()
+			if _, err := menuCollection.InsertOne(ctx, menu); err != nil {
+				return err
+			}
+			menuId = menu.Menu_id
+		case importUpdate:
+			update := bson.M{"$set": bson.M{"category": plan.menu.Category, "updated_at": now}}
+			if _, err := menuCollection.UpdateOne(ctx, bson.M{"menu_id": plan.existing.Menu_id}, update); err != nil {
+				return err
+			}
+			menuId = plan.existing.Menu_id
+		default:
+			menuId = plan.existing.Menu_id
+		}
+
+		for _, foodPlan := range plan.foods {
+			food := foodPlan.food
+			switch foodPlan.action {
+			case importCreate:
+				name, price := food.Name, *food.Price
+				newFood := models.Food{
+					ID:           primitive.NewObjectID(),
+					Name:         &name,
+					Price:        &price,
+					Cost:         food.Cost,
+					Food_image:   food.Food_image,
+					Menu_id:      &menuId,
+					UniqueFoodID: menuId + "-" + food.Name,
+					Outlet_id:    outletId,
+					Created_at:   now,
+					Updated_at:   now,
+				}
+				newFood.Food_id = newFood.ID.Hex()
+				if _, err := foodCollection.InsertOne(ctx, newFood); err != nil {
+					return err
+				}
+			case importUpdate:
+				changes := foodImportChanges(*foodPlan.existing, food)
+				changes["updated_at"] = now
+				if _, err := foodCollection.UpdateOne(ctx, bson.M{"food_id": foodPlan.existing.Food_id}, bson.M{"$set": changes}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ImportMenus creates and updates menus and foods in bulk from CSV or JSON, the format
+// GET /menus/export writes. The body type comes from ?format=csv|json or the Content-Type.
+// With ?dry_run=true nothing is written; otherwise nothing is written unless every row is valid.
+func ImportMenus(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		format = "json"
+		if mediaType == "text/csv" {
+			format = "csv"
+		}
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	body := http.MaxBytesReader(w, r.Body, maxMenuImportBytes)
+
+	var menus []menuFileMenu
+	var results []menuImportResult
+	switch format {
+	case "csv":
+		parsed, parseResults, err := parseMenuCSV(body)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "`+strings.ReplaceAll(err.Error(), `"`, `'`)+`"}`, http.StatusBadRequest)
+			return
+		}
+		menus, results = parsed, parseResults
+	case "json":
+		var file menuFile
+		if err := json.NewDecoder(body).Decode(&file); err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+		menus = file.Menus
+	default:
+		http.Error(w, `{"success": false, "message": "format must be csv or json"}`, http.StatusBadRequest)
+		return
+	}
+	if len(menus) == 0 && len(results) == 0 {
+		http.Error(w, `{"success": false, "message": "No menus to import"}`, http.StatusBadRequest)
+		return
+	}
+
+	outletId, outletExists, err := checkRequestOutlet(ctx, r)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking outlet"}`, http.StatusInternalServerError)
+		return
+	}
+	if !outletExists {
+		http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusBadRequest)
+		return
+	}
+	if outletId == "" && !canEditMasterMenu(r) {
+		http.Error(w, `{"success": false, "message": "The master menu is managed by head office"}`, http.StatusForbidden)
+		return
+	}
+
+	plans, planResults, err := planMenuImport(ctx, outletId, menus)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking existing menus"}`, http.StatusInternalServerError)
+		return
+	}
+	results = append(results, planResults...)
+
+	summary := map[string]int{importCreate: 0, importUpdate: 0, importUnchanged: 0, importError: 0}
+	for _, result := range results {
+		summary[result.Action]++
+	}
+	valid := summary[importError] == 0
+
+	applied := false
+	if valid && !dryRun {
+		// All or nothing, so a failure part way never leaves half a menu behind
+		session, err := database.Client.StartSession()
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Menu import failed"}`, http.StatusInternalServerError)
+			return
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+			return nil, applyMenuImport(sessCtx, outletId, plans)
+		})
+		if err != nil {
+			log.Printf("menu import: %v", err)
+			http.Error(w, `{"success": false, "message": "Menu import failed"}`, http.StatusInternalServerError)
+			return
+		}
+		applied = true
+	}
+
+	message := "Menu import validated successfully"
+	status := http.StatusOK
+	switch {
+	case !valid:
+		message = "Menu import has errors; nothing was imported"
+		status = http.StatusUnprocessableEntity
+	case applied:
+		message = "Menus imported successfully"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": valid,
+		"message": message,
+		"data": map[string]interface{}{
+			"dry_run": dryRun,
+			"applied": applied,
+			"summary": summary,
+			"results": results,
+		},
+	})
+}
+
+// ExportMenus writes menus and their foods in the import format: JSON by default, or flat CSV
+// with ?format=csv. ?menu_id= exports a single menu, e.g. to copy it to another outlet.
+func ExportMenus(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format != "" && format != "json" && format != helper.ExportCSV {
+		http.Error(w, `{"success": false, "message": "format must be csv or json"}`, http.StatusBadRequest)
+		return
+	}
+
+	filter := activeFilter(outletScope(r, bson.M{}))
+	if menuId := r.URL.Query().Get("menu_id"); menuId != "" {
+		filter["menu_id"] = menuId
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: "name", Value: 1}}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "food"},
+			{Key: "let", Value: bson.D{{Key: "menu_id", Value: "$menu_id"}}},
+			{Key: "pipeline", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{
+					{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$menu_id", "$$menu_id"}}}},
+					{Key: "deleted_at", Value: nil},
+				}}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "name", Value: 1}}}},
+			}},
+			{Key: "as", Value: "foods"},
+		}}},
+	}
+	cursor, err := menuCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving menus"}`, http.StatusInternalServerError)
+		return
+	}
+
+	decode := func(cursor *mongo.Cursor) (menuFileMenu, error) {
+		var doc struct {
+			Name     string        `bson:"name"`
+			Category string        `bson:"category"`
+			Foods    []models.Food `bson:"foods"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return menuFileMenu{}, err
+		}
+		menu := menuFileMenu{Name: doc.Name, Category: doc.Category, Foods: []menuFileFood{}}
+		for _, food := range doc.Foods {
+			if food.Name == nil {
+				continue
+			}
+			menu.Foods = append(menu.Foods, menuFileFood{Name: *food.Name, Price: food.Price, Cost: food.Cost, Food_image: food.Food_image})
+		}
+		return menu, nil
+	}
+
+	if format == helper.ExportCSV {
+		header := make([]interface{}, len(menuCSVColumns))
+		for i, column := range menuCSVColumns {
+			header[i] = column
+		}
+		streamExport(ctx, w, format, "menus", header, cursor, func(cursor *mongo.Cursor) ([][]interface{}, error) {
+			menu, err := decode(cursor)
+			if err != nil {
+				return nil, err
+			}
+			if len(menu.Foods) == 0 {
+				return [][]interface{}{{menu.Name, menu.Category, nil, nil, nil, nil}}, nil
+			}
+			rows := make([][]interface{}, len(menu.Foods))
+			for i, food := range menu.Foods {
+				rows[i] = []interface{}{menu.Name, menu.Category, food.Name, food.Price, food.Cost, food.Food_image}
+			}
+			return rows, nil
+		})
+		return
+	}
+
+	defer cursor.Close(ctx)
+	file := menuFile{Menus: []menuFileMenu{}}
+	for cursor.Next(ctx) {
+		menu, err := decode(cursor)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Error decoding menus"}`, http.StatusInternalServerError)
+			return
+		}
+		file.Menus = append(file.Menus, menu)
+	}
+	if err := cursor.Err(); err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving menus"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="menus-`+time.Now().Format("20060102-150405")+`.json"`)
+	json.NewEncoder(w).Encode(file)
+}
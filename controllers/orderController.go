@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
 	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
 	"github.com/02priyeshraj/Hotel_Management_Backend/models"
 
 	"github.com/gorilla/mux"
@@ -20,6 +22,20 @@ import (
 
 var orderCollection *mongo.Collection = database.OpenCollection(database.Client, "order")
 
+// Orders in these statuses no longer hold their table
+var closedOrderStatuses = []string{"Order Paid", "Order Cancelled", "Order Rejected", "Order Partially Refunded", "Order Refunded"}
+
+var errTableTaken = errors.New("table was taken by another order")
+
+// findOpenOrder returns the latest order on a table that is not yet closed
+func findOpenOrder(ctx context.Context, tableId string) (models.Order, error) {
+	var order models.Order
+	filter := activeFilter(bson.M{"table_id": tableId, "status": bson.M{"$nin": closedOrderStatuses}})
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	err := orderCollection.FindOne(ctx, filter, opts).Decode(&order)
+	return order, err
+}
+
 // Get all orders
 func GetOrders(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
@@ -39,7 +55,8 @@ func GetOrders(w http.ResponseWriter, r *http.Request) {
 	startIndex := (page - 1) * recordPerPage
 
 	// MongoDB Aggregation Pipeline
-	matchStage := bson.D{{Key: "$match", Value: bson.D{}}}
+	filter := listFilter(r, bson.M{})
+	matchStage := bson.D{{Key: "$match", Value: filter}}
 	skipStage := bson.D{{Key: "$skip", Value: startIndex}}
 	limitStage := bson.D{{Key: "$limit", Value: int64(recordPerPage)}}
 	projectStage := bson.D{
@@ -49,9 +66,14 @@ func GetOrders(w http.ResponseWriter, r *http.Request) {
 			{Key: "order_date", Value: 1},
 			{Key: "table_id", Value: 1},
 			{Key: "user_id", Value: 1},
+			{Key: "waiter_id", Value: 1},
+			{Key: "customer_id", Value: 1},
+			{Key: "covers", Value: 1},
 			{Key: "status", Value: 1},
 			{Key: "created_at", Value: 1},
 			{Key: "updated_at", Value: 1},
+			{Key: "deleted_at", Value: 1},
+			{Key: "deleted_by", Value: 1},
 		}},
 	}
 
@@ -70,7 +92,7 @@ func GetOrders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get total order count
-	totalOrders, err := orderCollection.CountDocuments(ctx, bson.M{})
+	totalOrders, err := orderCollection.CountDocuments(ctx, filter)
 	if err != nil {
 		http.Error(w, `{"error": "Error retrieving total order count"}`, http.StatusInternalServerError)
 		return
@@ -104,7 +126,7 @@ func GetOrderById(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var order models.Order
-	err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+	err := orderCollection.FindOne(ctx, activeFilter(bson.M{"order_id": orderId})).Decode(&order)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		http.Error(w, `{"success": false, "message": "Order not found"}`, http.StatusNotFound)
 		return
@@ -118,13 +140,16 @@ func GetOrderById(w http.ResponseWriter, r *http.Request) {
 		"success": true,
 		"message": "Order retrieved successfully",
 		"data": map[string]interface{}{
-			"order_id":   order.Order_id,
-			"user_id":    order.User_id,
-			"table_id":   order.Table_id,
-			"status":     order.Status,
-			"order_date": order.Order_Date,
-			"created_at": order.Created_at,
-			"updated_at": order.Updated_at,
+			"order_id":    order.Order_id,
+			"user_id":     order.User_id,
+			"waiter_id":   order.Waiter_id,
+			"customer_id": order.Customer_id,
+			"covers":      order.Covers,
+			"table_id":    order.Table_id,
+			"status":      order.Status,
+			"order_date":  order.Order_Date,
+			"created_at":  order.Created_at,
+			"updated_at":  order.Updated_at,
 		},
 	}
 
@@ -156,7 +181,8 @@ func GetOrdersByTableId(w http.ResponseWriter, r *http.Request) {
 	startIndex := (page - 1) * recordPerPage
 
 	// MongoDB aggregation pipeline for pagination
-	matchStage := bson.D{{Key: "$match", Value: bson.D{{Key: "table_id", Value: tableId}}}}
+	filter := listFilter(r, bson.M{"table_id": tableId})
+	matchStage := bson.D{{Key: "$match", Value: filter}}
 	skipStage := bson.D{{Key: "$skip", Value: startIndex}}
 	limitStage := bson.D{{Key: "$limit", Value: int64(recordPerPage)}}
 
@@ -179,7 +205,7 @@ func GetOrdersByTableId(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get total order count for the given table_id
-	totalOrders, err := orderCollection.CountDocuments(ctx, bson.M{"table_id": tableId})
+	totalOrders, err := orderCollection.CountDocuments(ctx, filter)
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error retrieving total order count"}`, http.StatusInternalServerError)
 		return
@@ -226,7 +252,8 @@ func GetOrdersByUserId(w http.ResponseWriter, r *http.Request) {
 	startIndex := (page - 1) * recordPerPage
 
 	// MongoDB aggregation pipeline for pagination
-	matchStage := bson.D{{Key: "$match", Value: bson.D{{Key: "user_id", Value: userId}}}}
+	filter := listFilter(r, bson.M{"user_id": userId})
+	matchStage := bson.D{{Key: "$match", Value: filter}}
 	skipStage := bson.D{{Key: "$skip", Value: startIndex}}
 	limitStage := bson.D{{Key: "$limit", Value: int64(recordPerPage)}}
 
@@ -249,7 +276,7 @@ func GetOrdersByUserId(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get total order count for the given user_id
-	totalOrders, err := orderCollection.CountDocuments(ctx, bson.M{"user_id": userId})
+	totalOrders, err := orderCollection.CountDocuments(ctx, filter)
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error retrieving total order count"}`, http.StatusInternalServerError)
 		return
@@ -287,39 +314,93 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 		order.Status = "Order Pending"
 	}
 
+	// The order is rung in by the signed-in user and served by the table's server
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	order.User_id = &uid
+	order.Waiter_id = nil
+
 	// Validate Order Data
-	if validationErr := validate.StructPartial(order, "Order_Date", "Table_id", "User_id"); validationErr != nil {
+	if validationErr := validate.StructPartial(order, "Order_Date", "Table_id", "Covers"); validationErr != nil {
 		http.Error(w, `{"success": false, "message": "%s"}`, http.StatusBadRequest)
 		return
 	}
 
 	// Validate Table ID and check if the table is reserved
 	var table models.Table
-	err := tableCollection.FindOne(ctx, bson.M{"table_id": order.Table_id}).Decode(&table)
+	err := tableCollection.FindOne(ctx, outletScope(r, bson.M{"table_id": order.Table_id})).Decode(&table)
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Invalid table ID, table not found"}`, http.StatusNotFound)
 		return
 	}
 
-	if table.Status != "Reserved" {
-		http.Error(w, `{"success": false, "message": "Table is not reserved. Reserve the table first."}`, http.StatusBadRequest)
+	if _, ok := nextTableStatus(table.Status, "seat"); !ok {
+		http.Error(w, `{"success": false, "message": "Table is not available for seating, current status: `+table.Status+`"}`, http.StatusBadRequest)
 		return
 	}
 
-	// Validate User ID exists
-	count, err := userCollection.CountDocuments(ctx, bson.M{"user_id": order.User_id})
-	if err != nil || count == 0 {
-		http.Error(w, `{"success": false, "message": "Invalid user ID, user not found"}`, http.StatusNotFound)
+	// Orders for merged tables belong on the primary table
+	if table.Merged_into != "" {
+		http.Error(w, `{"success": false, "message": "Table is merged into another table, use the primary table `+table.Merged_into+`"}`, http.StatusBadRequest)
 		return
 	}
 
+	// The customer, if known, gets the visit on their profile
+	if order.Customer_id != nil && *order.Customer_id != "" {
+		if !canActForCustomer(r, *order.Customer_id) {
+			http.Error(w, `{"success": false, "message": "Only staff can place an order for another customer"}`, http.StatusForbidden)
+			return
+		}
+		msg, err := checkCustomer(ctx, *order.Customer_id)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Error checking customer"}`, http.StatusInternalServerError)
+			return
+		}
+		if msg != "" {
+			http.Error(w, `{"success": false, "message": "`+msg+`"}`, http.StatusBadRequest)
+			return
+		}
+	} else {
+		order.Customer_id = nil
+	}
+
+	serverId, err := tableServer(ctx, table)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving table server"}`, http.StatusInternalServerError)
+		return
+	}
+	if serverId != "" && serverId != uid {
+		order.Waiter_id = &serverId
+	}
+
 	// Set timestamps and unique Order ID
 	order.Created_at = time.Now()
 	order.Updated_at = time.Now()
 	order.ID = primitive.NewObjectID()
 	order.Order_id = order.ID.Hex()
+	order.Outlet_id = table.Outlet_id // the order belongs to the table's outlet
 
-	_, err = orderCollection.InsertOne(ctx, order)
+	session, err := database.Client.StartSession()
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Order creation failed"}`, http.StatusInternalServerError)
+		return
+	}
+	defer session.EndSession(ctx)
+
+	// Seat the party first; the order is only saved by the request that got the table
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		seated, err := advanceTableStatus(sessCtx, table.Table_id, "seat", order.Order_id)
+		if err != nil {
+			return nil, err
+		}
+		if !seated {
+			return nil, errTableTaken
+		}
+		return orderCollection.InsertOne(sessCtx, order)
+	})
+	if errors.Is(err, errTableTaken) {
+		http.Error(w, `{"success": false, "message": "Table was seated by another order, try again"}`, http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Order creation failed"}`, http.StatusInternalServerError)
 		return
@@ -350,35 +431,63 @@ func UpdateOrder(w http.ResponseWriter, r *http.Request) {
 
 	updateObj := bson.D{}
 
-	// Validate Table ID before updating
-	if order.Table_id != nil {
-		// Check if the new table exists
-		var table models.Table
-		err := tableCollection.FindOne(ctx, bson.M{"table_id": order.Table_id}).Decode(&table)
-		if err != nil {
-			http.Error(w, `{"success": false, "message": "Invalid table ID, table not found"}`, http.StatusNotFound)
+	var existingOrder models.Order
+	err := orderCollection.FindOne(ctx, activeFilter(bson.M{"order_id": orderId})).Decode(&existingOrder)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Order not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving order"}`, http.StatusInternalServerError)
+		return
+	}
+	// Moving the party has its own checks and table bookkeeping
+	if order.Table_id != nil && (existingOrder.Table_id == nil || *existingOrder.Table_id != *order.Table_id) {
+		http.Error(w, `{"success": false, "message": "Use POST /orders/{order_id}/transfer to move an order to another table"}`, http.StatusBadRequest)
+		return
+	}
+
+	if order.Covers != nil {
+		if validationErr := validate.StructPartial(order, "Covers"); validationErr != nil {
+			http.Error(w, `{"success": false, "message": "Covers must be between 1 and 100"}`, http.StatusBadRequest)
 			return
 		}
+		updateObj = append(updateObj, bson.E{Key: "covers", Value: *order.Covers})
+	}
 
-		// Check if the table is reserved
-		if table.Status != "Reserved" {
-			http.Error(w, `{"success": false, "message": "Table is not reserved. Reserve the table first."}`, http.StatusBadRequest)
+	// Hand the table over to another server
+	if order.Waiter_id != nil {
+		msg, err := checkServer(ctx, *order.Waiter_id, existingOrder.Outlet_id)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Error checking waiter"}`, http.StatusInternalServerError)
+			return
+		}
+		if msg != "" {
+			http.Error(w, `{"success": false, "message": "`+msg+`"}`, http.StatusBadRequest)
 			return
 		}
+		updateObj = append(updateObj, bson.E{Key: "waiter_id", Value: *order.Waiter_id})
+	}
 
-		// Check if the table is already assigned to another order
-		existingOrderCount, err := orderCollection.CountDocuments(ctx, bson.M{"table_id": order.Table_id, "order_id": bson.M{"$ne": orderId}})
+	// Record who the customer is, e.g. once the host has looked them up
+	if order.Customer_id != nil {
+		if *order.Customer_id == "" {
+			http.Error(w, `{"success": false, "message": "customer_id cannot be empty"}`, http.StatusBadRequest)
+			return
+		}
+		if !canActForCustomer(r, *order.Customer_id) {
+			http.Error(w, `{"success": false, "message": "Only staff can put an order on another customer"}`, http.StatusForbidden)
+			return
+		}
+		msg, err := checkCustomer(ctx, *order.Customer_id)
 		if err != nil {
-			http.Error(w, `{"success": false, "message": "Error checking table availability"}`, http.StatusInternalServerError)
+			http.Error(w, `{"success": false, "message": "Error checking customer"}`, http.StatusInternalServerError)
 			return
 		}
-
-		if existingOrderCount > 0 {
-			http.Error(w, `{"success": false, "message": "Table is already assigned to another order."}`, http.StatusBadRequest)
+		if msg != "" {
+			http.Error(w, `{"success": false, "message": "`+msg+`"}`, http.StatusBadRequest)
 			return
 		}
-
-		updateObj = append(updateObj, bson.E{Key: "table_id", Value: order.Table_id})
+		updateObj = append(updateObj, bson.E{Key: "customer_id", Value: *order.Customer_id})
 	}
 
 	// order.Status is ignored here, use UpdateOrderStatus endpoint to update status
@@ -433,7 +542,7 @@ func DeleteOrder(w http.ResponseWriter, r *http.Request) {
 
 	// Find the order before deleting
 	var order models.Order
-	err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+	err := orderCollection.FindOne(ctx, activeFilter(bson.M{"order_id": orderId})).Decode(&order)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		http.Error(w, `{"success": false, "message": "Order not found"}`, http.StatusNotFound)
 		return
@@ -442,9 +551,26 @@ func DeleteOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// An open order still holds its table; it has to be settled or cancelled first
+	if !slices.Contains(closedOrderStatuses, order.Status) {
+		http.Error(w, `{"success": false, "message": "Order is still open; cancel or settle it before deleting"}`, http.StatusConflict)
+		return
+	}
+
+	// Invoices must not be left pointing at a deleted order
+	invoiceCount, err := invoiceCollection.CountDocuments(ctx, activeFilter(bson.M{"order_id": orderId}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking order invoices"}`, http.StatusInternalServerError)
+		return
+	}
+	if invoiceCount > 0 {
+		http.Error(w, `{"success": false, "message": "Order has an invoice; delete the invoice first"}`, http.StatusConflict)
+		return
+	}
+
 	// Delete the order
-	result, err := orderCollection.DeleteOne(ctx, bson.M{"order_id": orderId})
-	if err != nil || result.DeletedCount == 0 {
+	result, err := softDelete(ctx, r, orderCollection, bson.M{"order_id": orderId}, time.Now())
+	if err != nil || result.MatchedCount == 0 {
 		http.Error(w, `{"success": false, "message": "Error deleting order"}`, http.StatusInternalServerError)
 		return
 	}
@@ -460,6 +586,38 @@ func DeleteOrder(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+func RestoreOrder(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	orderId := mux.Vars(r)["order_id"]
+
+	result, err := restoreDeleted(ctx, orderCollection, bson.M{"order_id": orderId})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error restoring order"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"success": false, "message": "Deleted order not found"}`, http.StatusNotFound)
+		return
+	}
+
+	var order models.Order
+	if err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order); err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving restored order"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Order restored successfully",
+		"data":    order,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 	defer cancel()
@@ -489,7 +647,7 @@ func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 
 	// Check if order exists
 	var order models.Order
-	err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+	err := orderCollection.FindOne(ctx, activeFilter(bson.M{"order_id": orderId})).Decode(&order)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		http.Error(w, `{"success": false, "message": "Order not found"}`, http.StatusNotFound)
 		return
@@ -517,6 +675,21 @@ func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Closing the order frees up the table for cleaning
+	tableEvent := ""
+	switch requestBody.Status {
+	case "Order Paid":
+		tableEvent = "pay"
+	case "Order Cancelled", "Order Rejected":
+		tableEvent = "cancel"
+	}
+	if tableEvent != "" {
+		if err := advanceOrderTableStatus(ctx, orderId, tableEvent); ignoreNoDocuments(err) != nil {
+			http.Error(w, `{"success": false, "message": "Failed to update table status"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// Fetch updated order
 	err = orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
 	if err != nil {
@@ -534,3 +707,133 @@ func UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// TransferOrder moves an open order and its items to another table, freeing the old one
+func TransferOrder(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	orderId := mux.Vars(r)["order_id"]
+
+	var requestBody struct {
+		Table_id string `json:"table_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Table_id == "" {
+		http.Error(w, `{"success": false, "message": "table_id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	var order models.Order
+	err := orderCollection.FindOne(ctx, activeFilter(bson.M{"order_id": orderId})).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Order not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving order"}`, http.StatusInternalServerError)
+		return
+	}
+
+	for _, status := range closedOrderStatuses {
+		if order.Status == status {
+			http.Error(w, `{"success": false, "message": "Only open orders can be transferred"}`, http.StatusConflict)
+			return
+		}
+	}
+
+	fromTableId := ""
+	if order.Table_id != nil {
+		fromTableId = *order.Table_id
+	}
+	if fromTableId == requestBody.Table_id {
+		http.Error(w, `{"success": false, "message": "Order is already on this table"}`, http.StatusBadRequest)
+		return
+	}
+
+	var table models.Table
+	if err := tableCollection.FindOne(ctx, sameOutlet(bson.M{"table_id": requestBody.Table_id}, order.Outlet_id)).Decode(&table); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid table ID, table not found"}`, http.StatusNotFound)
+		return
+	}
+	if table.Merged_into != "" {
+		http.Error(w, `{"success": false, "message": "Table is merged into another table, use the primary table `+table.Merged_into+`"}`, http.StatusBadRequest)
+		return
+	}
+	if _, ok := nextTableStatus(table.Status, "seat"); !ok {
+		http.Error(w, `{"success": false, "message": "Table is not available for seating, current status: `+table.Status+`"}`, http.StatusConflict)
+		return
+	}
+	if _, err := findOpenOrder(ctx, table.Table_id); err == nil {
+		http.Error(w, `{"success": false, "message": "Table already has an open order"}`, http.StatusConflict)
+		return
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Error checking table availability"}`, http.StatusInternalServerError)
+		return
+	}
+
+	session, err := database.Client.StartSession()
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Order transfer failed"}`, http.StatusInternalServerError)
+		return
+	}
+	defer session.EndSession(ctx)
+
+	// The order, its items and both tables move together; a table taken since the checks above
+	// aborts the whole transfer
+	now := time.Now()
+	var itemsMoved int64
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if _, err := findOpenOrder(sessCtx, table.Table_id); err == nil {
+			return nil, errTableTaken
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+
+		if _, err := orderCollection.UpdateOne(sessCtx,
+			bson.M{"order_id": orderId},
+			bson.M{"$set": bson.M{"table_id": table.Table_id, "updated_at": now}},
+		); err != nil {
+			return nil, err
+		}
+
+		itemsResult, err := orderItemCollection.UpdateMany(sessCtx,
+			bson.M{"order_id": orderId},
+			bson.M{"$set": bson.M{"table_id": table.Table_id, "updated_at": now}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		itemsMoved = itemsResult.ModifiedCount
+
+		// The guests now occupy the new table and the old one needs cleaning
+		if fromTableId != "" {
+			return nil, moveTableOccupancy(sessCtx, fromTableId, table.Table_id)
+		}
+		seated, err := advanceTableStatus(sessCtx, table.Table_id, "seat", orderId)
+		if err == nil && !seated {
+			err = errTableTaken
+		}
+		return nil, err
+	})
+	if errors.Is(err, errTableTaken) {
+		http.Error(w, `{"success": false, "message": "Table was taken while transferring, try again"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Order transfer failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Order transferred successfully",
+		"data": map[string]interface{}{
+			"order_id":      orderId,
+			"from_table_id": fromTableId,
+			"to_table_id":   table.Table_id,
+			"items_moved":   itemsMoved,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
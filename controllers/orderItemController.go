@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
 	"github.com/02priyeshraj/Hotel_Management_Backend/models"
 
 	"github.com/gorilla/mux"
@@ -21,7 +24,7 @@ import (
 
 var orderItemCollection *mongo.Collection = database.OpenCollection(database.Client, "orderitems")
 
-// GetOrderItems retrieves all order items with food names
+// GetOrderItems retrieves the order items of the caller's outlet with food names
 func GetOrderItems(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 	defer cancel()
@@ -36,7 +39,22 @@ func GetOrderItems(w http.ResponseWriter, r *http.Request) {
 	}
 	startIndex := (page - 1) * recordPerPage
 
-	cursor, err := orderItemCollection.Find(ctx, bson.M{}, options.Find().SetSkip(int64(startIndex)).SetLimit(int64(recordPerPage)))
+	// Order items belong to the outlet of their order
+	scoped := mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "order"},
+			{Key: "localField", Value: "order_id"},
+			{Key: "foreignField", Value: "order_id"},
+			{Key: "as", Value: "order"},
+		}}},
+		{{Key: "$match", Value: outletScopeField(r, bson.M{}, "order.outlet_id")}},
+	}
+	pipeline := append(slices.Clone(scoped),
+		bson.D{{Key: "$unset", Value: "order"}},
+		bson.D{{Key: "$skip", Value: int64(startIndex)}},
+		bson.D{{Key: "$limit", Value: int64(recordPerPage)}},
+	)
+	cursor, err := orderItemCollection.Aggregate(ctx, pipeline)
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error retrieving order items"}`, http.StatusInternalServerError)
 		return
@@ -49,7 +67,16 @@ func GetOrderItems(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	totalCount, _ := orderItemCollection.CountDocuments(ctx, bson.M{})
+	var totalCount int64
+	countCursor, err := orderItemCollection.Aggregate(ctx, append(scoped, bson.D{{Key: "$count", Value: "total"}}))
+	if err == nil {
+		var counts []struct {
+			Total int64 `bson:"total"`
+		}
+		if countCursor.All(ctx, &counts) == nil && len(counts) > 0 {
+			totalCount = counts[0].Total
+		}
+	}
 
 	response := map[string]interface{}{
 		"success": true,
@@ -103,7 +130,7 @@ func CreateOrderItem(w http.ResponseWriter, r *http.Request) {
 
 	// Validate order existence and status
 	var order models.Order
-	err := orderCollection.FindOne(ctx, bson.M{"order_id": orderItem.Order_id}).Decode(&order)
+	err := orderCollection.FindOne(ctx, activeFilter(outletScope(r, bson.M{"order_id": orderItem.Order_id}))).Decode(&order)
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Invalid order ID"}`, http.StatusBadRequest)
 		return
@@ -127,30 +154,16 @@ func CreateOrderItem(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Initialize total price calculation
-	var totalPrice float64
-	transformedItems := make(map[string]int)
-	var missingFoodIDs []string
-
-	for foodID, quantity := range orderItem.Items {
-		var food models.Food
-		err := foodCollection.FindOne(ctx, bson.M{"food_id": foodID}).Decode(&food)
-		if err != nil {
-			missingFoodIDs = append(missingFoodIDs, foodID)
-			continue
-		}
-
-		transformedItems[*food.Name] = quantity
-		totalPrice += (*food.Price) * float64(quantity)
-	}
-
+	// Resolve food names and calculate the total price
+	transformedItems, lines, totalPrice, missingFoodIDs := priceOrderItems(ctx, order.Outlet_id, orderItem.Items)
 	if len(missingFoodIDs) > 0 {
-		http.Error(w, `{"success": false, "message": "Food items not found: `+strings.Join(missingFoodIDs, ", ")+`"}`, http.StatusBadRequest)
+		http.Error(w, `{"success": false, "message": "Food items not found or unavailable: `+strings.Join(missingFoodIDs, ", ")+`"}`, http.StatusBadRequest)
 		return
 	}
 
 	// Assign calculated total price and transformed items
 	orderItem.Items = transformedItems
+	orderItem.Lines = lines
 	orderItem.TotalPrice = totalPrice
 	orderItem.Created_at = time.Now()
 	orderItem.Updated_at = time.Now()
@@ -164,17 +177,134 @@ func CreateOrderItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, err = advanceTableStatus(ctx, orderItem.Table_id, "order", orderItem.Order_id); ignoreNoDocuments(err) != nil {
+		http.Error(w, `{"success": false, "message": "Failed to update table status"}`, http.StatusInternalServerError)
+		return
+	}
+
+	kots, err := generateKOTs(ctx, r, orderItem)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Order item created but kitchen tickets could not be generated"}`, http.StatusInternalServerError)
+		return
+	}
+
 	response := map[string]interface{}{
 		"success": true,
 		"message": "Order item created successfully",
 		"data":    orderItem,
+		"kots":    kots,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// UpdateOrderItem updates an existing order item
+// priceOrderItems maps food IDs to food names, prices each line at the food's current price at the
+// order's outlet and sums the total. Food IDs that could not be found, or that the outlet hides or
+// has marked unavailable, are returned so the caller can reject the request.
+func priceOrderItems(ctx context.Context, outletId string, items map[string]int) (map[string]int, []models.OrderLine, float64, []string) {
+	var totalPrice float64
+	transformedItems := make(map[string]int)
+	var lines []models.OrderLine
+	var missingFoodIDs []string
+
+	for foodID, quantity := range items {
+		food, err := effectiveFood(ctx, outletId, foodID)
+		if err != nil || (food.Available != nil && !*food.Available) {
+			missingFoodIDs = append(missingFoodIDs, foodID)
+			continue
+		}
+
+		transformedItems[*food.Name] = quantity
+		lines = append(lines, models.OrderLine{
+			Food_id:    foodID,
+			Name:       *food.Name,
+			Quantity:   quantity,
+			Unit_price: *food.Price,
+			Amount:     helper.RoundMoney(*food.Price * float64(quantity)),
+		})
+		totalPrice += (*food.Price) * float64(quantity)
+	}
+
+	return transformedItems, lines, helper.RoundMoney(totalPrice), missingFoodIDs
+}
+
+// orderLineChange is a food whose quantity an update changed
+type orderLineChange struct {
+	Food_id string `json:"food_id"`
+	Name    string `json:"name"`
+	From    int    `json:"from"`
+	To      int    `json:"to"`
+}
+
+// repriceOrderItem sets new quantities, given by food ID, on an order item and works its total out
+// again. Only foods already on the item can be changed. Lines keep the price they were ordered at;
+// items from before priced lines are repriced at the food's current price at the outlet for the
+// change in quantity. It returns the changes, or a message when the request cannot be applied.
+func repriceOrderItem(ctx context.Context, outletId string, orderItem *models.OrderItem, quantities map[string]int) ([]orderLineChange, string) {
+	var changes []orderLineChange
+	var unknownFoodIDs []string
+	if orderItem.Items == nil {
+		orderItem.Items = make(map[string]int)
+	}
+
+	total := orderItem.TotalPrice
+	if len(orderItem.Lines) > 0 {
+		total = 0
+	}
+
+	for foodID, quantity := range quantities {
+		if quantity < 0 {
+			return nil, "Quantity of " + foodID + " cannot be negative"
+		}
+
+		if len(orderItem.Lines) > 0 {
+			index := slices.IndexFunc(orderItem.Lines, func(line models.OrderLine) bool { return line.Food_id == foodID })
+			if index < 0 {
+				unknownFoodIDs = append(unknownFoodIDs, foodID)
+				continue
+			}
+			line := orderItem.Lines[index]
+			if line.Quantity != quantity {
+				changes = append(changes, orderLineChange{Food_id: foodID, Name: line.Name, From: line.Quantity, To: quantity})
+			}
+			line.Quantity = quantity
+			line.Amount = helper.RoundMoney(line.Unit_price * float64(quantity))
+			orderItem.Lines[index] = line
+			orderItem.Items[line.Name] = quantity
+			continue
+		}
+
+		food, err := effectiveFood(ctx, outletId, foodID)
+		if err != nil || food.Name == nil || food.Price == nil {
+			unknownFoodIDs = append(unknownFoodIDs, foodID)
+			continue
+		}
+		previous, onItem := orderItem.Items[*food.Name]
+		if !onItem {
+			unknownFoodIDs = append(unknownFoodIDs, foodID)
+			continue
+		}
+		if previous != quantity {
+			changes = append(changes, orderLineChange{Food_id: foodID, Name: *food.Name, From: previous, To: quantity})
+		}
+		total += *food.Price * float64(quantity-previous)
+		orderItem.Items[*food.Name] = quantity
+	}
+
+	if len(unknownFoodIDs) > 0 {
+		sort.Strings(unknownFoodIDs)
+		return nil, "Food items not on this order item: " + strings.Join(unknownFoodIDs, ", ")
+	}
+
+	for _, line := range orderItem.Lines {
+		total += line.Amount
+	}
+	orderItem.TotalPrice = helper.RoundMoney(max(total, 0))
+	return changes, ""
+}
+
+// UpdateOrderItem sets new quantities on an existing order item and reprices it
 func UpdateOrderItem(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 	defer cancel()
@@ -195,39 +325,39 @@ func UpdateOrderItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert food IDs to food names and check for missing IDs
-	foodIDToName := make(map[string]string)
-	var missingFoodIDs []string
-
-	for foodID := range updateRequest.Items {
-		var food models.Food
-		err := foodCollection.FindOne(ctx, bson.M{"food_id": foodID}).Decode(&food)
-		if err != nil {
-			missingFoodIDs = append(missingFoodIDs, foodID)
-			continue
-		}
-		foodIDToName[foodID] = *food.Name
+	var order models.Order
+	if err := orderCollection.FindOne(ctx, bson.M{"order_id": existingOrderItem.Order_id}).Decode(&order); err != nil {
+		http.Error(w, `{"success": false, "message": "Order not found for this order item"}`, http.StatusNotFound)
+		return
 	}
-
-	// If any food IDs are missing, return an error
-	if len(missingFoodIDs) > 0 {
-		http.Error(w, `{"success": false, "message": "Food items not found for IDs: `+strings.Join(missingFoodIDs, ", ")+`"}`, http.StatusBadRequest)
+	if slices.Contains(closedOrderStatuses, order.Status) {
+		http.Error(w, `{"success": false, "message": "Order is already closed: `+order.Status+`"}`, http.StatusConflict)
+		return
+	}
+	billed, err := orderHasPendingInvoice(ctx, order.Order_id)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking order invoices"}`, http.StatusInternalServerError)
+		return
+	}
+	if billed {
+		http.Error(w, `{"success": false, "message": "Order has a pending invoice; void it before changing the order"}`, http.StatusConflict)
 		return
 	}
 
-	// Update only the quantities of the items present in the request
-	for foodID, newQuantity := range updateRequest.Items {
-		foodName := foodIDToName[foodID] // Since all IDs are verified, this will always exist
-		if _, itemExists := existingOrderItem.Items[foodName]; itemExists {
-			existingOrderItem.Items[foodName] = newQuantity
-		}
+	changes, message := repriceOrderItem(ctx, order.Outlet_id, &existingOrderItem, updateRequest.Items)
+	if message != "" {
+		http.Error(w, `{"success": false, "message": "`+message+`"}`, http.StatusBadRequest)
+		return
 	}
 
-	// Update the order item in the database
 	updateObj := bson.D{
 		{Key: "items", Value: existingOrderItem.Items},
+		{Key: "total_price", Value: existingOrderItem.TotalPrice},
 		{Key: "updated_at", Value: time.Now()},
 	}
+	if len(existingOrderItem.Lines) > 0 {
+		updateObj = append(updateObj, bson.E{Key: "lines", Value: existingOrderItem.Lines})
+	}
 
 	filter := bson.M{"order_item_id": orderItemId}
 	opt := options.Update().SetUpsert(false)
@@ -238,10 +368,20 @@ func UpdateOrderItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The kitchen hears about the change with an amendment ticket per station
+	kots, err := generateAmendmentKOTs(ctx, r, existingOrderItem, changes)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Order item updated but kitchen tickets could not be generated"}`, http.StatusInternalServerError)
+		return
+	}
+
 	response := map[string]interface{}{
 		"success":       true,
 		"message":       "Order item updated successfully",
 		"updated_items": updateRequest.Items,
+		"changes":       changes,
+		"data":          existingOrderItem,
+		"kots":          kots,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -256,7 +396,8 @@ func DeleteOrderItem(w http.ResponseWriter, r *http.Request) {
 	orderItemId := mux.Vars(r)["order_item_id"]
 
 	// Check if the order item exists before deleting
-	err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": orderItemId}).Err()
+	var orderItem models.OrderItem
+	err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": orderItemId}).Decode(&orderItem)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		http.Error(w, `{"success": false, "message": "Order item not found"}`, http.StatusNotFound)
 		return
@@ -265,6 +406,12 @@ func DeleteOrderItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The kitchen gets a cancellation slip for anything already sent
+	if err := cancelOrderItemKOTs(ctx, r, orderItemId, "Order item deleted"); err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to cancel kitchen tickets"}`, http.StatusInternalServerError)
+		return
+	}
+
 	// Proceed with deletion
 	result, err := orderItemCollection.DeleteOne(ctx, bson.M{"order_item_id": orderItemId})
 	if err != nil || result.DeletedCount == 0 {
@@ -0,0 +1,340 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var outletCollection *mongo.Collection = database.OpenCollection(database.Client, "outlet")
+
+// Tables, menus, foods, orders and invoices carry the outlet they belong to. Data from before
+// outlets has none and stays with users who have no outlet either.
+
+// outletScopeValue returns what outlet_id must match for the caller, or false for a head-office
+// user looking across every outlet
+func outletScopeValue(r *http.Request) (interface{}, bool) {
+	outletId, headOffice := middleware.GetOutletFromContext(r)
+	if headOffice && outletId == "" {
+		return nil, false
+	}
+	if outletId == "" {
+		return nil, true // matches documents without an outlet
+	}
+	return outletId, true
+}
+
+// outletScope restricts a filter to the caller's outlet
+func outletScope(r *http.Request, filter bson.M) bson.M {
+	return outletScopeField(r, filter, "outlet_id")
+}
+
+// outletScopeField restricts a filter on a field holding an outlet id, e.g. "order.outlet_id" after a $lookup
+func outletScopeField(r *http.Request, filter bson.M, field string) bson.M {
+	if value, scoped := outletScopeValue(r); scoped {
+		filter[field] = value
+	}
+	return filter
+}
+
+// requestOutletId is the outlet new documents are created in: the user's own, or the one a
+// head-office user picked with the X-Outlet-Id header
+func requestOutletId(r *http.Request) string {
+	outletId, _ := middleware.GetOutletFromContext(r)
+	return outletId
+}
+
+// outletValue is what outlet_id is stored or matched as: null, never "", for no outlet
+func outletValue(outletId string) interface{} {
+	if outletId == "" {
+		return nil
+	}
+	return outletId
+}
+
+// sameOutlet matches documents of exactly this outlet, "" meaning those without one
+func sameOutlet(filter bson.M, outletId string) bson.M {
+	filter["outlet_id"] = outletValue(outletId)
+	return filter
+}
+
+// outletCodes returns an outlet's code and invoice prefix. Documents without an outlet use
+// OUTLET_CODE and INVOICE_PREFIX, as before outlets existed.
+func outletCodes(ctx context.Context, outletId string) (string, string, error) {
+	if outletId == "" {
+		return helper.OutletCode(), helper.InvoicePrefix(), nil
+	}
+	var outlet models.Outlet
+	if err := outletCollection.FindOne(ctx, bson.M{"outlet_id": outletId}).Decode(&outlet); err != nil {
+		return "", "", err
+	}
+	prefix := outlet.Invoice_prefix
+	if prefix == "" {
+		prefix = outlet.Code
+	}
+	return outlet.Code, prefix, nil
+}
+
+// invoiceSeriesTaken reports whether an invoice prefix already numbers another outlet's invoices,
+// or those of data without an outlet. Deleted outlets keep their series.
+func invoiceSeriesTaken(ctx context.Context, prefix, outletId string) (bool, error) {
+	if prefix == helper.InvoicePrefix() {
+		return true, nil
+	}
+	count, err := outletCollection.CountDocuments(ctx, bson.M{
+		"outlet_id": bson.M{"$ne": outletId},
+		"$or": bson.A{
+			bson.M{"invoice_prefix": prefix},
+			bson.M{"code": prefix, "invoice_prefix": bson.M{"$in": bson.A{nil, ""}}},
+		},
+	})
+	return count > 0, err
+}
+
+// checkRequestOutlet makes sure the outlet a head-office user picked exists before anything is created in it
+func checkRequestOutlet(ctx context.Context, r *http.Request) (string, bool, error) {
+	outletId := requestOutletId(r)
+	if outletId == "" {
+		return "", true, nil
+	}
+	count, err := outletCollection.CountDocuments(ctx, activeFilter(bson.M{"outlet_id": outletId}))
+	return outletId, count > 0, err
+}
+
+// GetOutlets lists the outlets; like every list, it only shows the caller's own outlet unless they are head office
+func GetOutlets(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := listFilter(r, bson.M{})
+
+	cursor, err := outletCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "code", Value: 1}}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving outlets"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	outlets := []models.Outlet{}
+	if err := cursor.All(ctx, &outlets); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding outlets"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Outlets retrieved successfully",
+		"data":    outlets,
+	})
+}
+
+// GetOutlet returns one outlet
+func GetOutlet(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	outletId := mux.Vars(r)["outlet_id"]
+	if userOutlet, headOffice := middleware.GetOutletFromContext(r); !headOffice && userOutlet != outletId {
+		http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusNotFound)
+		return
+	}
+
+	var outlet models.Outlet
+	err := outletCollection.FindOne(ctx, activeFilter(bson.M{"outlet_id": outletId})).Decode(&outlet)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving outlet"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Outlet retrieved successfully",
+		"data":    outlet,
+	})
+}
+
+// CreateOutlet adds an outlet, head office only. Codes are unique.
+func CreateOutlet(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var outlet models.Outlet
+	if err := json.NewDecoder(r.Body).Decode(&outlet); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	outlet.Code = strings.ToUpper(strings.TrimSpace(outlet.Code))
+	if validationErr := validate.Struct(outlet); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "`+validationErr.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	count, err := outletCollection.CountDocuments(ctx, bson.M{"code": outlet.Code})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking outlet code"}`, http.StatusInternalServerError)
+		return
+	}
+	if count > 0 || outlet.Code == helper.OutletCode() {
+		http.Error(w, `{"success": false, "message": "Outlet code already exists"}`, http.StatusConflict)
+		return
+	}
+
+	// Every outlet numbers its invoices in a series of its own
+	if outlet.Invoice_prefix == "" {
+		outlet.Invoice_prefix = outlet.Code
+	}
+	taken, err := invoiceSeriesTaken(ctx, outlet.Invoice_prefix, "")
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking invoice prefix"}`, http.StatusInternalServerError)
+		return
+	}
+	if taken {
+		http.Error(w, `{"success": false, "message": "Invoice prefix is already used by another series"}`, http.StatusConflict)
+		return
+	}
+
+	outlet.ID = primitive.NewObjectID()
+	outlet.Outlet_id = outlet.ID.Hex()
+	outlet.Created_at = time.Now()
+	outlet.Updated_at = outlet.Created_at
+	outlet.Deleted_at, outlet.Deleted_by = nil, nil
+
+	if _, err := outletCollection.InsertOne(ctx, outlet); mongo.IsDuplicateKeyError(err) {
+		http.Error(w, `{"success": false, "message": "Outlet code or invoice prefix already exists"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Outlet creation failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Outlet created successfully",
+		"data":    outlet,
+	})
+}
+
+// UpdateOutlet changes an outlet's name, address, phone or invoice prefix, head office only.
+// The code keys the outlet's receipt template and invoice series, so it does not change.
+func UpdateOutlet(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	outletId := mux.Vars(r)["outlet_id"]
+	var requestBody struct {
+		Name           *string `json:"name" validate:"omitempty,min=2,max=100"`
+		Address        *string `json:"address" validate:"omitempty,max=300"`
+		Phone          *string `json:"phone" validate:"omitempty,max=20"`
+		Invoice_prefix *string `json:"invoice_prefix" validate:"omitempty,max=20"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if validationErr := validate.Struct(requestBody); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "`+validationErr.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if requestBody.Name != nil {
+		set["name"] = *requestBody.Name
+	}
+	if requestBody.Address != nil {
+		set["address"] = *requestBody.Address
+	}
+	if requestBody.Phone != nil {
+		set["phone"] = *requestBody.Phone
+	}
+	if requestBody.Invoice_prefix != nil {
+		if *requestBody.Invoice_prefix == "" {
+			http.Error(w, `{"success": false, "message": "invoice_prefix cannot be empty"}`, http.StatusBadRequest)
+			return
+		}
+		taken, err := invoiceSeriesTaken(ctx, *requestBody.Invoice_prefix, outletId)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Error checking invoice prefix"}`, http.StatusInternalServerError)
+			return
+		}
+		if taken {
+			http.Error(w, `{"success": false, "message": "Invoice prefix is already used by another series"}`, http.StatusConflict)
+			return
+		}
+		set["invoice_prefix"] = *requestBody.Invoice_prefix
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var outlet models.Outlet
+	err := outletCollection.FindOneAndUpdate(ctx, activeFilter(bson.M{"outlet_id": outletId}), bson.M{"$set": set}, opts).Decode(&outlet)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusNotFound)
+		return
+	} else if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, `{"success": false, "message": "Invoice prefix is already used by another series"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Outlet update failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Outlet updated successfully",
+		"data":    outlet,
+	})
+}
+
+// DeleteOutlet soft-deletes an outlet that no user works at any more, head office only
+func DeleteOutlet(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	outletId := mux.Vars(r)["outlet_id"]
+
+	staff, err := userCollection.CountDocuments(ctx, bson.M{"outlet_id": outletId})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking outlet users"}`, http.StatusInternalServerError)
+		return
+	}
+	if staff > 0 {
+		http.Error(w, `{"success": false, "message": "Move the outlet's users to another outlet first"}`, http.StatusConflict)
+		return
+	}
+
+	result, err := softDelete(ctx, r, outletCollection, bson.M{"outlet_id": outletId}, time.Now())
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error deleting outlet"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Outlet deleted successfully",
+	})
+}
@@ -0,0 +1,434 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/02priyeshraj/Hotel_Management_Backend/payments"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var paymentEventCollection *mongo.Collection = database.OpenCollection(database.Client, "payment_event")
+
+var errInvoiceNotPayable = errors.New("invoice is not pending payment")
+
+// Payment methods an invoice can be settled with
+var paymentMethods = []string{"CARD", "CASH", "UPI", "WALLET", "GIFT_CARD"}
+
+// Payment methods that can be collected through a payment provider
+var providerPaymentMethods = []string{"CARD", "UPI", "WALLET"}
+
+// validatePaymentDetails checks the method-specific data for a payment and returns a message
+// for the client if it is unusable. A paid invoice must carry the reference its method needs.
+func validatePaymentDetails(method string, details *models.PaymentDetails, paid bool) string {
+	if method != "" && !slices.Contains(paymentMethods, method) {
+		return "Payment method must be one of " + strings.Join(paymentMethods, ", ")
+	}
+	if details == nil {
+		details = &models.PaymentDetails{}
+	} else if err := validate.Struct(details); err != nil {
+		return err.Error()
+	}
+
+	// Details belong to one method only
+	if (details.Upi_reference != "" && method != "UPI") ||
+		(details.Card_last4 != "" && method != "CARD") ||
+		((details.Wallet_provider != "" || details.Wallet_reference != "") && method != "WALLET") ||
+		(details.Gift_card_code != "" && method != "GIFT_CARD") {
+		return "Payment details do not match the payment method"
+	}
+
+	if !paid {
+		return ""
+	}
+	switch method {
+	case "":
+		return "Payment method is required for a paid invoice"
+	case "UPI":
+		if details.Upi_reference == "" {
+			return "upi_reference is required for UPI payments"
+		}
+	case "WALLET":
+		if details.Wallet_provider == "" {
+			return "wallet_provider is required for wallet payments"
+		}
+	case "GIFT_CARD":
+		if details.Gift_card_code == "" {
+			return "gift_card_code is required for gift card payments"
+		}
+	}
+	return ""
+}
+
+// markOrderPaid moves an invoice's order to "Order Paid" and frees its table for cleaning
+func markOrderPaid(ctx context.Context, orderId string) error {
+	_, err := orderCollection.UpdateOne(ctx,
+		bson.M{"order_id": orderId},
+		bson.M{"$set": bson.M{"status": "Order Paid", "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	return ignoreNoDocuments(advanceOrderTableStatus(ctx, orderId, "pay"))
+}
+
+// markInvoicePaid settles a pending invoice with a provider transaction. Applying the same
+// transaction again is a no-op, so webhooks may be delivered any number of times. Invoices of a
+// business date that has had its Z-report are left alone.
+func markInvoicePaid(ctx context.Context, invoiceId, provider, reference string) (models.Invoice, error) {
+	var pending models.Invoice
+	if err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&pending); err != nil {
+		return pending, err
+	}
+	closed, err := businessDateClosed(ctx, pending.Outlet_id, pending.Created_at)
+	if err != nil {
+		return pending, err
+	}
+	if closed && (pending.Payment_reference != reference || pending.Payment_provider != provider) {
+		return pending, errBusinessDateClosed
+	}
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"payment_status":    "PAID",
+		"payment_date":      now,
+		"payment_provider":  provider,
+		"payment_reference": reference,
+		"updated_at":        now,
+	}}
+	result, err := invoiceCollection.UpdateOne(ctx, activeFilter(bson.M{"invoice_id": invoiceId, "payment_status": "PENDING"}), update)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+
+	var invoice models.Invoice
+	if err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice); err != nil {
+		return invoice, err
+	}
+
+	if result.ModifiedCount == 0 {
+		// Already settled: fine if it was this transaction, anything else needs a person to look at it
+		if invoice.Payment_reference == reference && invoice.Payment_provider == provider {
+			return invoice, nil
+		}
+		return invoice, errInvoiceNotPayable
+	}
+
+	if invoice.Order_id != nil {
+		if err := markOrderPaid(ctx, *invoice.Order_id); err != nil {
+			return invoice, err
+		}
+	}
+	points, err := earnLoyaltyPoints(ctx, invoice, "")
+	if err != nil {
+		log.Printf("invoice %s: failed to credit loyalty points: %v", invoiceId, err)
+	}
+	if points > 0 {
+		invoice.Loyalty_points_earned = points
+	}
+	return invoice, nil
+}
+
+// CreatePaymentIntent starts collecting a pending invoice through a payment provider.
+// The body may name the provider (default PAYMENT_PROVIDER) and the method (default CARD).
+func CreatePaymentIntent(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	invoiceId := mux.Vars(r)["invoice_id"]
+
+	var requestBody struct {
+		Provider    string  `json:"provider"`
+		Method      string  `json:"method"`
+		Tip_amount  float64 `json:"tip_amount"`
+		Tip_percent float64 `json:"tip_percent"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	if requestBody.Method == "" {
+		requestBody.Method = "CARD"
+	}
+	if !slices.Contains(providerPaymentMethods, requestBody.Method) {
+		http.Error(w, `{"success": false, "message": "Unsupported payment method for a provider payment"}`, http.StatusBadRequest)
+		return
+	}
+
+	provider, err := payments.Get(requestBody.Provider)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Unknown payment provider"}`, http.StatusBadRequest)
+		return
+	}
+
+	var invoice models.Invoice
+	err = invoiceCollection.FindOne(ctx, activeFilter(bson.M{"invoice_id": invoiceId})).Decode(&invoice)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Invoice not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving invoice"}`, http.StatusInternalServerError)
+		return
+	}
+	if invoice.Payment_status == nil || *invoice.Payment_status != "PENDING" {
+		http.Error(w, `{"success": false, "message": "Only pending invoices can be paid"}`, http.StatusConflict)
+		return
+	}
+
+	// The tip is collected together with the bill
+	subtotal := invoice.Subtotal - invoice.Discount_amount
+	if invoice.Subtotal == 0 {
+		subtotal = invoice.TotalPrice
+	}
+	tipAmount, tipPercent, message := resolveTip(subtotal, requestBody.Tip_amount, requestBody.Tip_percent)
+	if message != "" {
+		http.Error(w, `{"success": false, "message": "`+message+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	template, err := outletReceiptTemplate(ctx, invoice.Outlet_id)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving receipt template"}`, http.StatusInternalServerError)
+		return
+	}
+
+	intent, err := provider.CreateIntent(ctx, payments.IntentRequest{
+		Invoice_id: invoice.Invoice_id,
+		Amount:     helper.RoundMoney(invoice.TotalPrice + tipAmount),
+		Currency:   template.Currency,
+		Method:     requestBody.Method,
+	})
+	if err != nil {
+		log.Printf("payment provider %s: create intent for invoice %s failed: %v", provider.Name(), invoice.Invoice_id, err)
+		http.Error(w, `{"success": false, "message": "Payment provider could not create the payment"}`, http.StatusBadGateway)
+		return
+	}
+
+	update := bson.M{"$set": bson.M{
+		"payment_method":    requestBody.Method,
+		"payment_provider":  provider.Name(),
+		"payment_intent_id": intent.Intent_id,
+		"tip_amount":        tipAmount,
+		"tip_percent":       tipPercent,
+		"tip_server_id":     orderServerId(ctx, invoice.Order_id),
+		"updated_at":        time.Now(),
+	}}
+	result, err := invoiceCollection.UpdateOne(ctx, activeFilter(bson.M{"invoice_id": invoiceId, "payment_status": "PENDING"}), update)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to save payment intent"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"success": false, "message": "Invoice is no longer pending"}`, http.StatusConflict)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Payment intent created successfully",
+		"data": map[string]interface{}{
+			"invoice_id": invoice.Invoice_id,
+			"provider":   provider.Name(),
+			"intent":     intent,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CapturePayment collects the invoice's open payment intent and marks the invoice PAID
+func CapturePayment(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	invoiceId := mux.Vars(r)["invoice_id"]
+
+	var invoice models.Invoice
+	err := invoiceCollection.FindOne(ctx, activeFilter(bson.M{"invoice_id": invoiceId})).Decode(&invoice)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Invoice not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving invoice"}`, http.StatusInternalServerError)
+		return
+	}
+	if invoice.Payment_intent_id == "" {
+		http.Error(w, `{"success": false, "message": "Invoice has no payment intent; create one first"}`, http.StatusConflict)
+		return
+	}
+
+	// Do not take money for an invoice that can no longer be settled
+	closed, err := businessDateClosed(ctx, invoice.Outlet_id, invoice.Created_at)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking business date"}`, http.StatusInternalServerError)
+		return
+	}
+	if closed {
+		http.Error(w, `{"success": false, "message": "Business date `+businessDate(invoice.Created_at)+` is closed; invoices from it can no longer be changed"}`, http.StatusConflict)
+		return
+	}
+
+	provider, err := payments.Get(invoice.Payment_provider)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Unknown payment provider"}`, http.StatusInternalServerError)
+		return
+	}
+
+	transaction, err := provider.Capture(ctx, invoice.Payment_intent_id)
+	if errors.Is(err, payments.ErrIntentNotFound) {
+		http.Error(w, `{"success": false, "message": "Payment intent not found at the provider"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("payment provider %s: capture of %s failed: %v", provider.Name(), invoice.Payment_intent_id, err)
+		http.Error(w, `{"success": false, "message": "Payment provider could not capture the payment"}`, http.StatusBadGateway)
+		return
+	}
+
+	paidInvoice, err := markInvoicePaid(ctx, invoice.Invoice_id, provider.Name(), transaction.Transaction_ref)
+	if errors.Is(err, errInvoiceNotPayable) {
+		http.Error(w, `{"success": false, "message": "Payment captured but the invoice was already settled: `+transaction.Transaction_ref+`"}`, http.StatusConflict)
+		return
+	} else if errors.Is(err, errBusinessDateClosed) {
+		http.Error(w, `{"success": false, "message": "Payment captured but the invoice's business date was closed: `+transaction.Transaction_ref+`"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Payment captured but the invoice could not be updated"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Payment captured successfully",
+		"data":    paidInvoice,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PaymentWebhook receives provider callbacks. A successful payment marks its invoice PAID;
+// every event is recorded once, so redelivered callbacks are acknowledged without reprocessing.
+func PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	provider, err := payments.Get(mux.Vars(r)["provider"])
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Unknown payment provider"}`, http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	event, err := provider.VerifyWebhook(r.Header, body)
+	if errors.Is(err, payments.ErrWebhookNotConfigured) {
+		log.Printf("payment webhook %s: refused, no webhook secret is configured", provider.Name())
+		http.Error(w, `{"success": false, "message": "Webhooks are not enabled for this provider"}`, http.StatusServiceUnavailable)
+		return
+	} else if errors.Is(err, payments.ErrInvalidSignature) {
+		http.Error(w, `{"success": false, "message": "Invalid signature"}`, http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid webhook payload"}`, http.StatusBadRequest)
+		return
+	}
+
+	eventKey := provider.Name() + ":" + event.Event_id
+	err = paymentEventCollection.FindOne(ctx, bson.M{"_id": eventKey}).Err()
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Event already processed",
+		})
+		return
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Error checking event"}`, http.StatusInternalServerError)
+		return
+	}
+
+	outcome := "ignored"
+	if event.Type == payments.EventPaymentSucceeded {
+		// Only an intent this API created for the invoice can settle it, for the full amount
+		if event.Intent_id == "" || event.Amount <= 0 {
+			http.Error(w, `{"success": false, "message": "intent_id and amount are required"}`, http.StatusBadRequest)
+			return
+		}
+		var invoice models.Invoice
+		filter := bson.M{"payment_provider": provider.Name(), "payment_intent_id": event.Intent_id}
+		err := invoiceCollection.FindOne(ctx, activeFilter(filter)).Decode(&invoice)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, `{"success": false, "message": "Invoice not found for payment"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, `{"success": false, "message": "Error retrieving invoice"}`, http.StatusInternalServerError)
+			return
+		}
+
+		expected := helper.RoundMoney(invoice.TotalPrice + invoice.Tip_amount)
+		if math.Abs(event.Amount-expected) > 0.005 {
+			log.Printf("payment webhook %s: amount %.2f does not match invoice %s total %.2f", eventKey, event.Amount, invoice.Invoice_id, expected)
+			http.Error(w, `{"success": false, "message": "Payment amount does not match the invoice total"}`, http.StatusUnprocessableEntity)
+			return
+		}
+
+		_, err = markInvoicePaid(ctx, invoice.Invoice_id, provider.Name(), event.Transaction_ref)
+		if errors.Is(err, errInvoiceNotPayable) || errors.Is(err, errBusinessDateClosed) {
+			// Acknowledge so the provider stops retrying; the event is kept for reconciliation
+			log.Printf("payment webhook %s: invoice %s cannot be settled (%v), transaction %s needs review", eventKey, invoice.Invoice_id, err, event.Transaction_ref)
+			outcome = "needs_review"
+		} else if err != nil {
+			http.Error(w, `{"success": false, "message": "Failed to mark invoice paid"}`, http.StatusInternalServerError)
+			return
+		} else {
+			outcome = "invoice_paid"
+		}
+		event.Invoice_id = invoice.Invoice_id
+	}
+
+	record := bson.M{
+		"_id":         eventKey,
+		"provider":    provider.Name(),
+		"event":       event,
+		"outcome":     outcome,
+		"received_at": time.Now(),
+	}
+	if _, err := paymentEventCollection.InsertOne(ctx, record); err != nil && !mongo.IsDuplicateKeyError(err) {
+		http.Error(w, `{"success": false, "message": "Failed to record event"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Event processed",
+		"data": map[string]interface{}{
+			"event_id":   event.Event_id,
+			"invoice_id": event.Invoice_id,
+			"outcome":    outcome,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
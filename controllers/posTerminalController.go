@@ -0,0 +1,312 @@
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var posTerminalCollection *mongo.Collection = database.OpenCollection(database.Client, "pos_terminal")
+
+// Roles of users who work the floor; they can switch onto a terminal, where everyone acts as STAFF,
+// and serve tables
+var staffRoles = map[string]bool{"STAFF": true, "MANAGER": true, "ADMIN": true}
+
+// Ends whoever is signed in on a terminal
+var endPosSession = bson.M{"session_id": "", "session_staff_id": ""}
+
+// recordTerminalFailure counts a failed PIN login on a terminal and locks the terminal once
+// there have been twice PIN_MAX_ATTEMPTS in a row, so codes cannot be tried one after another
+func recordTerminalFailure(ctx context.Context, terminalId string) error {
+	filter := bson.M{"terminal_id": terminalId}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"failed_logins": 1})
+	var terminal models.PosTerminal
+	if err := posTerminalCollection.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"failed_logins": 1}}, opts).Decode(&terminal); err != nil {
+		return err
+	}
+	if terminal.Failed_logins < 2*helper.PinMaxAttempts() {
+		return nil
+	}
+	update := bson.M{
+		"$set":   bson.M{"locked_until": time.Now().Add(helper.PinLockout())},
+		"$unset": bson.M{"failed_logins": ""},
+	}
+	_, err := posTerminalCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// posTerminalWithKey is a terminal along with the device key it was just given
+type posTerminalWithKey struct {
+	models.PosTerminal
+	Device_key string `json:"device_key"`
+}
+
+// GetPosTerminals lists the outlet's registered terminals
+func GetPosTerminals(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	cursor, err := posTerminalCollection.Find(ctx, listFilter(r, bson.M{}), options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving POS terminals"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	terminals := []models.PosTerminal{}
+	if err := cursor.All(ctx, &terminals); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding POS terminals"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "POS terminals retrieved successfully",
+		"data":    terminals,
+	})
+}
+
+// RegisterPosTerminal registers a device at the caller's outlet. The device key in the response is
+// shown only this once; the terminal sends it with every request.
+func RegisterPosTerminal(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var terminal models.PosTerminal
+	if err := json.NewDecoder(r.Body).Decode(&terminal); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	terminal.Name = strings.TrimSpace(terminal.Name)
+	if validationErr := validate.Struct(terminal); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "`+validationErr.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	outletId, outletExists, err := checkRequestOutlet(ctx, r)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking outlet"}`, http.StatusInternalServerError)
+		return
+	}
+	if !outletExists {
+		http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusBadRequest)
+		return
+	}
+
+	deviceKey, keyHash, err := helper.NewDeviceKey()
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to generate device key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	terminal = models.PosTerminal{
+		ID:         primitive.NewObjectID(),
+		Outlet_id:  outletId,
+		Name:       terminal.Name,
+		Key_hash:   keyHash,
+		Created_by: uid,
+		Created_at: time.Now(),
+	}
+	terminal.Terminal_id = terminal.ID.Hex()
+	terminal.Updated_at = terminal.Created_at
+
+	if _, err := posTerminalCollection.InsertOne(ctx, terminal); err != nil {
+		http.Error(w, `{"success": false, "message": "POS terminal registration failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "POS terminal registered successfully",
+		"data":    posTerminalWithKey{terminal, deviceKey},
+	})
+}
+
+// RotatePosTerminalKey issues the terminal a new device key, e.g. when the device is replaced.
+// The old key stops working and whoever was signed in is signed out.
+func RotatePosTerminalKey(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	deviceKey, keyHash, err := helper.NewDeviceKey()
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to generate device key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	set := bson.M{"key_hash": keyHash, "updated_at": time.Now()}
+	for field, value := range endPosSession {
+		set[field] = value
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var terminal models.PosTerminal
+	err = posTerminalCollection.FindOneAndUpdate(ctx, activeFilter(bson.M{"terminal_id": mux.Vars(r)["terminal_id"]}), bson.M{"$set": set}, opts).Decode(&terminal)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "POS terminal not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to rotate device key"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Device key rotated successfully",
+		"data":    posTerminalWithKey{terminal, deviceKey},
+	})
+}
+
+// DeletePosTerminal revokes a terminal; its device key and any token issued on it stop working
+func DeletePosTerminal(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	result, err := softDelete(ctx, r, posTerminalCollection, bson.M{"terminal_id": mux.Vars(r)["terminal_id"]}, time.Now())
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error revoking POS terminal"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"success": false, "message": "POS terminal not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "POS terminal revoked successfully",
+	})
+}
+
+// PosLogin switches a registered terminal to the employee whose code and PIN are punched in. The
+// terminal identifies itself with the X-Terminal-Id and X-Terminal-Key headers. The token it gets
+// lasts POS_SESSION_MINUTES, works only with the device key, acts as STAFF at the terminal's outlet,
+// and ends as soon as someone else switches on. Wrong PINs lock the employee, and then the terminal, out.
+func PosLogin(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	terminalId := r.Header.Get(middleware.TerminalIdHeader)
+	deviceKey := r.Header.Get(middleware.TerminalKeyHeader)
+	var terminal models.PosTerminal
+	err := posTerminalCollection.FindOne(ctx, activeFilter(bson.M{"terminal_id": terminalId})).Decode(&terminal)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Error retrieving POS terminal"}`, http.StatusInternalServerError)
+		return
+	}
+	if err != nil || subtle.ConstantTimeCompare([]byte(helper.HashDeviceKey(deviceKey)), []byte(terminal.Key_hash)) != 1 {
+		http.Error(w, `{"success": false, "message": "Unknown or revoked POS terminal"}`, http.StatusUnauthorized)
+		return
+	}
+	now := time.Now()
+	if terminal.Locked_until != nil && now.Before(*terminal.Locked_until) {
+		http.Error(w, `{"success": false, "message": "Too many failed logins on this terminal; try again later"}`, http.StatusTooManyRequests)
+		return
+	}
+
+	var requestBody struct {
+		Employee_code string `json:"employee_code"`
+		Pin           string `json:"pin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Employee_code == "" || requestBody.Pin == "" {
+		http.Error(w, `{"success": false, "message": "employee_code and pin are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	var staff models.Staff
+	code := strings.ToUpper(strings.TrimSpace(requestBody.Employee_code))
+	err = staffCollection.FindOne(ctx, activeFilter(sameOutlet(bson.M{"employee_code": code}, terminal.Outlet_id))).Decode(&staff)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Error retrieving staff member"}`, http.StatusInternalServerError)
+		return
+	}
+	msg, status := "Invalid employee code or PIN", http.StatusUnauthorized
+	if err == nil {
+		msg, status, err = verifyStaffPin(ctx, staff, requestBody.Pin)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Error checking PIN"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+	if msg != "" {
+		if err := recordTerminalFailure(ctx, terminal.Terminal_id); err != nil {
+			http.Error(w, `{"success": false, "message": "Error recording failed login"}`, http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, `{"success": false, "message": "`+msg+`"}`, status)
+		return
+	}
+
+	if staff.User_id == nil || *staff.User_id == "" {
+		http.Error(w, `{"success": false, "message": "Staff member has no user account to sign in with"}`, http.StatusForbidden)
+		return
+	}
+	var user models.User
+	if err := userCollection.FindOne(ctx, bson.M{"user_id": *staff.User_id}).Decode(&user); err != nil {
+		http.Error(w, `{"success": false, "message": "Staff member's user account not found"}`, http.StatusForbidden)
+		return
+	}
+	if user.Role == nil || !staffRoles[*user.Role] {
+		http.Error(w, `{"success": false, "message": "Only staff accounts can sign in on a POS terminal"}`, http.StatusForbidden)
+		return
+	}
+
+	sessionId := primitive.NewObjectID().Hex()
+	token, expiresAt, err := helper.GeneratePOSToken(*user.Email, *user.First_name, *user.Last_name, user.User_id, terminal.Terminal_id, sessionId)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to sign in"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// The new session replaces the last one, signing the previous user out of the terminal
+	update := bson.M{
+		"$set": bson.M{
+			"session_id":       sessionId,
+			"session_staff_id": staff.Staff_id,
+			"last_login_at":    now,
+			"updated_at":       now,
+		},
+		"$unset": bson.M{"failed_logins": "", "locked_until": ""},
+	}
+	if _, err := posTerminalCollection.UpdateOne(ctx, bson.M{"terminal_id": terminal.Terminal_id}, update); err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to sign in"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Signed in on POS terminal successfully",
+		"data": map[string]interface{}{
+			"token":         token,
+			"expires_at":    expiresAt,
+			"terminal_id":   terminal.Terminal_id,
+			"outlet_id":     terminal.Outlet_id,
+			"user_id":       user.User_id,
+			"staff_id":      staff.Staff_id,
+			"employee_code": staff.Employee_code,
+			"first_name":    staff.First_name,
+			"last_name":     staff.Last_name,
+			"role":          "STAFF",
+		},
+	})
+}
@@ -0,0 +1,283 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var receiptTemplateCollection *mongo.Collection = database.OpenCollection(database.Client, "receipt_template")
+
+// loadReceiptTemplate returns the outlet's receipt template, or a plain default if none is saved yet
+func loadReceiptTemplate(ctx context.Context, outletCode string) (models.ReceiptTemplate, error) {
+	var template models.ReceiptTemplate
+	err := receiptTemplateCollection.FindOne(ctx, bson.M{"outlet_code": outletCode}).Decode(&template)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.ReceiptTemplate{
+			Outlet_code:     outletCode,
+			Restaurant_name: "Tomato",
+			Footer_lines:    []string{"Thank you for dining with us!"},
+		}, nil
+	}
+	return template, err
+}
+
+// outletReceiptTemplate loads the receipt template of an outlet, "" being the one of OUTLET_CODE
+func outletReceiptTemplate(ctx context.Context, outletId string) (models.ReceiptTemplate, error) {
+	outletCode, _, err := outletCodes(ctx, outletId)
+	if err != nil {
+		return models.ReceiptTemplate{}, err
+	}
+	return loadReceiptTemplate(ctx, outletCode)
+}
+
+// receiptLines collects the priced lines of an order, merging repeats of the same food at the same price.
+// Order items saved before lines were captured are priced from the food's current price.
+func receiptLines(ctx context.Context, orderId string) ([]models.OrderLine, error) {
+	cursor, err := orderItemCollection.Find(ctx, bson.M{"order_id": orderId}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var orderItems []models.OrderItem
+	if err := cursor.All(ctx, &orderItems); err != nil {
+		return nil, err
+	}
+
+	var lines []models.OrderLine
+	index := make(map[string]int)
+	addLine := func(line models.OrderLine) {
+		key := line.Food_id + "|" + line.Name + "|" + strconv.FormatFloat(line.Unit_price, 'f', 2, 64)
+		if i, ok := index[key]; ok {
+			lines[i].Quantity += line.Quantity
+			lines[i].Amount = helper.RoundMoney(lines[i].Amount + line.Amount)
+			return
+		}
+		index[key] = len(lines)
+		lines = append(lines, line)
+	}
+
+	for _, orderItem := range orderItems {
+		if len(orderItem.Lines) > 0 {
+			for _, line := range orderItem.Lines {
+				addLine(line)
+			}
+			continue
+		}
+
+		// Older order items only hold food name -> quantity
+		names := make([]string, 0, len(orderItem.Items))
+		for name := range orderItem.Items {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			line := models.OrderLine{Name: name, Quantity: orderItem.Items[name]}
+			var food models.Food
+			if err := foodCollection.FindOne(ctx, bson.M{"name": name}).Decode(&food); err == nil && food.Price != nil {
+				line.Food_id = food.Food_id
+				line.Unit_price = *food.Price
+				line.Amount = helper.RoundMoney(*food.Price * float64(line.Quantity))
+			}
+			addLine(line)
+		}
+	}
+	return lines, nil
+}
+
+// buildReceipt gathers everything printed on an invoice
+func buildReceipt(ctx context.Context, invoice models.Invoice) (helper.Receipt, error) {
+	receipt := helper.Receipt{
+		Invoice_number: invoice.Invoice_number,
+		Date:           invoice.Created_at,
+		Subtotal:       invoice.Subtotal,
+		Discount:       invoice.Discount_amount,
+		Taxes:          invoice.Taxes,
+		Tax_amount:     invoice.Tax_amount,
+		Total:          invoice.TotalPrice,
+		Refunded_total: invoice.Refunded_total,
+		Tip:            invoice.Tip_amount,
+	}
+	if receipt.Invoice_number == "" {
+		receipt.Invoice_number = invoice.Invoice_id // invoices issued before numbering
+	}
+	if receipt.Subtotal == 0 && receipt.Tax_amount == 0 {
+		receipt.Subtotal = invoice.TotalPrice
+	}
+	if invoice.Payment_status != nil {
+		receipt.Payment_status = *invoice.Payment_status
+	}
+	if invoice.Payment_method != nil {
+		receipt.Payment_method = *invoice.Payment_method
+	}
+
+	template, err := outletReceiptTemplate(ctx, invoice.Outlet_id)
+	if err != nil {
+		return receipt, err
+	}
+	receipt.Template = template
+
+	if invoice.Order_id == nil {
+		return receipt, nil
+	}
+	receipt.Order_id = *invoice.Order_id
+
+	var order models.Order
+	if err := orderCollection.FindOne(ctx, bson.M{"order_id": *invoice.Order_id}).Decode(&order); err == nil && order.Table_id != nil {
+		var table models.Table
+		if err := tableCollection.FindOne(ctx, bson.M{"table_id": *order.Table_id}).Decode(&table); err == nil && table.Table_number != nil {
+			receipt.Table_number = *table.Table_number
+		}
+	}
+
+	receipt.Lines, err = receiptLines(ctx, *invoice.Order_id)
+	return receipt, err
+}
+
+// PrintInvoice renders an invoice as a PDF (?format=pdf, the default) or as ESC/POS
+// bytes for a thermal printer (?format=escpos&width=58|80).
+func PrintInvoice(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	invoiceId := mux.Vars(r)["invoice_id"]
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "escpos" {
+		http.Error(w, `{"success": false, "message": "Invalid format, expected pdf or escpos"}`, http.StatusBadRequest)
+		return
+	}
+
+	var invoice models.Invoice
+	err := invoiceCollection.FindOne(ctx, activeFilter(bson.M{"invoice_id": invoiceId})).Decode(&invoice)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Invoice not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving invoice"}`, http.StatusInternalServerError)
+		return
+	}
+
+	receipt, err := buildReceipt(ctx, invoice)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error preparing receipt"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if format == "escpos" {
+		width, err := strconv.Atoi(r.URL.Query().Get("width"))
+		if err != nil {
+			width = 80
+		}
+		if width != 58 && width != 80 {
+			http.Error(w, `{"success": false, "message": "Invalid width, expected 58 or 80"}`, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="invoice-`+invoice.Invoice_id+`.bin"`)
+		w.Write(helper.RenderReceiptESCPOS(receipt, width))
+		return
+	}
+
+	pdf, err := helper.RenderReceiptPDF(receipt)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error rendering invoice PDF"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="invoice-`+invoice.Invoice_id+`.pdf"`)
+	w.Write(pdf)
+}
+
+// GetReceiptTemplate returns the caller's outlet's receipt template
+func GetReceiptTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	template, err := outletReceiptTemplate(ctx, requestOutletId(r))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving receipt template"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Receipt template retrieved successfully",
+		"data":    template,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateReceiptTemplate replaces the caller's outlet's receipt template
+func UpdateReceiptTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var template models.ReceiptTemplate
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if validationErr := validate.Struct(template); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "`+validationErr.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	outletCode, _, err := outletCodes(ctx, requestOutletId(r))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving outlet"}`, http.StatusInternalServerError)
+		return
+	}
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	template.Outlet_code = outletCode
+	template.Updated_by = uid
+	template.Updated_at = time.Now()
+
+	update := bson.M{"$set": bson.M{
+		"outlet_code":     template.Outlet_code,
+		"restaurant_name": template.Restaurant_name,
+		"address_lines":   template.Address_lines,
+		"phone":           template.Phone,
+		"tax_id":          template.Tax_id,
+		"currency":        template.Currency,
+		"footer_lines":    template.Footer_lines,
+		"updated_by":      template.Updated_by,
+		"updated_at":      template.Updated_at,
+	}}
+	opts := options.Update().SetUpsert(true)
+	if _, err := receiptTemplateCollection.UpdateOne(ctx, bson.M{"outlet_code": template.Outlet_code}, update, opts); err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to save receipt template"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Receipt template saved successfully",
+		"data":    template,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
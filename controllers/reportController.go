@@ -0,0 +1,376 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Invoices that count as sales; voided and pending invoices never took money
+var salesInvoiceStatuses = bson.A{"PAID", invoicePartiallyRefunded, invoiceRefunded}
+
+// invoiceGrossSales is an invoice's menu total before discounts and taxes.
+// Invoices from before taxes were split out only have total_price.
+var invoiceGrossSales = bson.D{{Key: "$cond", Value: bson.A{
+	bson.D{{Key: "$gt", Value: bson.A{"$subtotal", 0}}},
+	"$subtotal",
+	bson.D{{Key: "$subtract", Value: bson.A{"$total_price", bson.D{{Key: "$ifNull", Value: bson.A{"$tax_amount", 0}}}}}},
+}}}
+
+// refundInvoiceTotals joins each credit note to the total and taxes of its invoice
+var refundInvoiceTotals = mongo.Pipeline{
+	{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: "invoice"},
+		{Key: "localField", Value: "invoice_id"},
+		{Key: "foreignField", Value: "invoice_id"},
+		{Key: "as", Value: "invoice"},
+	}}},
+	{{Key: "$set", Value: bson.D{
+		{Key: "invoice_total", Value: bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$arrayElemAt", Value: bson.A{"$invoice.total_price", 0}}}, 0}}}},
+		{Key: "invoice_tax", Value: bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$arrayElemAt", Value: bson.A{"$invoice.tax_amount", 0}}}, 0}}}},
+	}}},
+}
+
+// refundPreTax is a credit note's pre-tax share after refundInvoiceTotals: the refund scaled by
+// the invoice's total less taxes over its total. Net sales take off this share, not the refund.
+var refundPreTax = bson.D{{Key: "$cond", Value: bson.A{
+	bson.D{{Key: "$gt", Value: bson.A{"$invoice_total", 0}}},
+	bson.D{{Key: "$multiply", Value: bson.A{"$amount", bson.D{{Key: "$divide", Value: bson.A{
+		bson.D{{Key: "$subtract", Value: bson.A{"$invoice_total", "$invoice_tax"}}},
+		"$invoice_total",
+	}}}}}},
+	"$amount",
+}}}
+
+// $dateToString formats for each report grouping
+var reportPeriodFormats = map[string]string{
+	"day":   "%Y-%m-%d",
+	"week":  "%G-W%V", // ISO week
+	"month": "%Y-%m",
+}
+
+// reportTimezone names the server's time zone (time.Local) for MongoDB, so periods follow the
+// outlet's local calendar across daylight saving changes. The zone comes from TZ, else the
+// /etc/localtime link; only when neither names one does it fall back to the current UTC offset.
+func reportTimezone() string {
+	if name := os.Getenv("TZ"); name != "" {
+		if _, err := time.LoadLocation(name); err == nil {
+			return name
+		}
+	}
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		if _, name, found := strings.Cut(target, "zoneinfo/"); found {
+			if _, err := time.LoadLocation(name); err == nil {
+				return name
+			}
+		}
+	}
+	return time.Now().Format("-07:00")
+}
+
+// reportRange reads ?from=YYYY-MM-DD&to=YYYY-MM-DD (both inclusive, default the last 30 days)
+// and returns the start and the exclusive end of the range
+func reportRange(r *http.Request) (time.Time, time.Time, string) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	to := today
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, "Invalid to date, expected YYYY-MM-DD"
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -29)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, "Invalid from date, expected YYYY-MM-DD"
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, "from must not be after to"
+	}
+	return from, to.AddDate(0, 0, 1), ""
+}
+
+// reportGrouping reads ?group_by=day|week|month (default day)
+func reportGrouping(r *http.Request) (string, string, bool) {
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = "day"
+	}
+	format, ok := reportPeriodFormats[groupBy]
+	return groupBy, format, ok
+}
+
+type paymentMethodMix struct {
+	Method   string  `json:"method" bson:"method"`
+	Invoices int64   `json:"invoices" bson:"invoices"`
+	Amount   float64 `json:"amount" bson:"amount"`
+}
+
+type salesReportRow struct {
+	Period            string             `json:"period"`
+	Invoices          int64              `json:"invoices"`
+	Covers            int64              `json:"covers"`
+	Gross_sales       float64            `json:"gross_sales"` // menu prices before discounts and taxes
+	Discounts         float64            `json:"discounts"`
+	Refunds           float64            `json:"refunds"`         // money returned, taxes included
+	Refunds_pre_tax   float64            `json:"refunds_pre_tax"` // the refunds' share of the invoices' pre-tax totals
+	Net_sales         float64            `json:"net_sales"`       // gross sales less discounts and pre-tax refunds
+	Taxes             float64            `json:"taxes"`
+	Total_sales       float64            `json:"total_sales"` // billed to guests, taxes included
+	Average_ticket    float64            `json:"average_ticket"`
+	Average_per_cover float64            `json:"average_per_cover"`
+	Payment_methods   []paymentMethodMix `json:"payment_methods"`
+}
+
+// finish rounds the sums and works out the derived figures
+func (row *salesReportRow) finish() {
+	row.Gross_sales = helper.RoundMoney(row.Gross_sales)
+	row.Discounts = helper.RoundMoney(row.Discounts)
+	row.Refunds = helper.RoundMoney(row.Refunds)
+	row.Refunds_pre_tax = helper.RoundMoney(row.Refunds_pre_tax)
+	row.Taxes = helper.RoundMoney(row.Taxes)
+	row.Total_sales = helper.RoundMoney(row.Total_sales)
+	row.Net_sales = helper.RoundMoney(row.Gross_sales - row.Discounts - row.Refunds_pre_tax)
+	if row.Invoices > 0 {
+		row.Average_ticket = helper.RoundMoney(row.Total_sales / float64(row.Invoices))
+	}
+	if row.Covers > 0 {
+		row.Average_per_cover = helper.RoundMoney(row.Total_sales / float64(row.Covers))
+	}
+	for i := range row.Payment_methods {
+		row.Payment_methods[i].Amount = helper.RoundMoney(row.Payment_methods[i].Amount)
+	}
+	sort.Slice(row.Payment_methods, func(i, j int) bool {
+		return row.Payment_methods[i].Amount > row.Payment_methods[j].Amount
+	})
+}
+
+func addPaymentMix(mix []paymentMethodMix, method string, invoices int64, amount float64) []paymentMethodMix {
+	for i := range mix {
+		if mix[i].Method == method {
+			mix[i].Invoices += invoices
+			mix[i].Amount += amount
+			return mix
+		}
+	}
+	return append(mix, paymentMethodMix{Method: method, Invoices: invoices, Amount: amount})
+}
+
+// GetSalesReport reports sales per day, week or month (?group_by=) over ?from=&to=,
+// with the totals for the whole range
+func GetSalesReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	from, to, message := reportRange(r)
+	if message != "" {
+		http.Error(w, `{"success": false, "message": "`+message+`"}`, http.StatusBadRequest)
+		return
+	}
+	groupBy, periodFormat, ok := reportGrouping(r)
+	if !ok {
+		http.Error(w, `{"success": false, "message": "group_by must be day, week or month"}`, http.StatusBadRequest)
+		return
+	}
+	timezone := reportTimezone()
+
+	// One pass over the invoices gives the period figures and the payment mix
+	invoicePipeline := mongo.Pipeline{
+		{{Key: "$match", Value: activeFilter(outletScope(r, bson.M{
+			"payment_status": bson.M{"$in": salesInvoiceStatuses},
+			"created_at":     bson.M{"$gte": from, "$lt": to},
+		}))}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "order"},
+			{Key: "localField", Value: "order_id"},
+			{Key: "foreignField", Value: "order_id"},
+			{Key: "as", Value: "order"},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "period", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+				{Key: "format", Value: periodFormat},
+				{Key: "date", Value: "$created_at"},
+				{Key: "timezone", Value: timezone},
+			}}}},
+			{Key: "method", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$payment_method", "UNKNOWN"}}}},
+			{Key: "gross", Value: invoiceGrossSales},
+			{Key: "discount", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$discount_amount", 0}}}},
+			{Key: "tax", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$tax_amount", 0}}}},
+			{Key: "total", Value: "$total_price"},
+			{Key: "covers", Value: bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$arrayElemAt", Value: bson.A{"$order.covers", 0}}}, 0}}}},
+		}}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "periods", Value: bson.A{
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: "$period"},
+					{Key: "invoices", Value: bson.D{{Key: "$sum", Value: 1}}},
+					{Key: "covers", Value: bson.D{{Key: "$sum", Value: "$covers"}}},
+					{Key: "gross", Value: bson.D{{Key: "$sum", Value: "$gross"}}},
+					{Key: "discounts", Value: bson.D{{Key: "$sum", Value: "$discount"}}},
+					{Key: "taxes", Value: bson.D{{Key: "$sum", Value: "$tax"}}},
+					{Key: "total", Value: bson.D{{Key: "$sum", Value: "$total"}}},
+				}}},
+			}},
+			{Key: "methods", Value: bson.A{
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: bson.D{{Key: "period", Value: "$period"}, {Key: "method", Value: "$method"}}},
+					{Key: "invoices", Value: bson.D{{Key: "$sum", Value: 1}}},
+					{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$total"}}},
+				}}},
+			}},
+		}}},
+	}
+	cursor, err := invoiceCollection.Aggregate(ctx, invoicePipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error building sales report"}`, http.StatusInternalServerError)
+		return
+	}
+	var facets []struct {
+		Periods []struct {
+			Period    string  `bson:"_id"`
+			Invoices  int64   `bson:"invoices"`
+			Covers    int64   `bson:"covers"`
+			Gross     float64 `bson:"gross"`
+			Discounts float64 `bson:"discounts"`
+			Taxes     float64 `bson:"taxes"`
+			Total     float64 `bson:"total"`
+		} `bson:"periods"`
+		Methods []struct {
+			ID struct {
+				Period string `bson:"period"`
+				Method string `bson:"method"`
+			} `bson:"_id"`
+			Invoices int64   `bson:"invoices"`
+			Amount   float64 `bson:"amount"`
+		} `bson:"methods"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		http.Error(w, `{"success": false, "message": "Error building sales report"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Refunds count in the period the credit note was issued
+	refundPipeline := append(mongo.Pipeline{
+		{{Key: "$match", Value: outletScope(r, bson.M{"created_at": bson.M{"$gte": from, "$lt": to}})}},
+	}, refundInvoiceTotals...)
+	refundPipeline = append(refundPipeline, bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+			{Key: "format", Value: periodFormat},
+			{Key: "date", Value: "$created_at"},
+			{Key: "timezone", Value: timezone},
+		}}}},
+		{Key: "refunds", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+		{Key: "refunds_pre_tax", Value: bson.D{{Key: "$sum", Value: refundPreTax}}},
+	}}})
+	cursor, err = creditNoteCollection.Aggregate(ctx, refundPipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error building sales report"}`, http.StatusInternalServerError)
+		return
+	}
+	var refunds []struct {
+		Period          string  `bson:"_id"`
+		Refunds         float64 `bson:"refunds"`
+		Refunds_pre_tax float64 `bson:"refunds_pre_tax"`
+	}
+	if err := cursor.All(ctx, &refunds); err != nil {
+		http.Error(w, `{"success": false, "message": "Error building sales report"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows := make(map[string]*salesReportRow)
+	row := func(period string) *salesReportRow {
+		if rows[period] == nil {
+			rows[period] = &salesReportRow{Period: period, Payment_methods: []paymentMethodMix{}}
+		}
+		return rows[period]
+	}
+	totals := salesReportRow{Period: "total", Payment_methods: []paymentMethodMix{}}
+
+	if len(facets) > 0 {
+		for _, p := range facets[0].Periods {
+			periodRow := row(p.Period)
+			periodRow.Invoices, periodRow.Covers = p.Invoices, p.Covers
+			periodRow.Gross_sales, periodRow.Discounts = p.Gross, p.Discounts
+			periodRow.Taxes, periodRow.Total_sales = p.Taxes, p.Total
+
+			totals.Invoices += p.Invoices
+			totals.Covers += p.Covers
+			totals.Gross_sales += p.Gross
+			totals.Discounts += p.Discounts
+			totals.Taxes += p.Taxes
+			totals.Total_sales += p.Total
+		}
+		for _, m := range facets[0].Methods {
+			periodRow := row(m.ID.Period)
+			periodRow.Payment_methods = addPaymentMix(periodRow.Payment_methods, m.ID.Method, m.Invoices, m.Amount)
+			totals.Payment_methods = addPaymentMix(totals.Payment_methods, m.ID.Method, m.Invoices, m.Amount)
+		}
+	}
+	for _, refund := range refunds {
+		periodRow := row(refund.Period)
+		periodRow.Refunds, periodRow.Refunds_pre_tax = refund.Refunds, refund.Refunds_pre_tax
+		totals.Refunds += refund.Refunds
+		totals.Refunds_pre_tax += refund.Refunds_pre_tax
+	}
+
+	report := make([]salesReportRow, 0, len(rows))
+	for _, periodRow := range rows {
+		periodRow.finish()
+		report = append(report, *periodRow)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Period < report[j].Period })
+	totals.finish()
+
+	if format != "" {
+		header := []interface{}{
+			"period", "invoices", "covers", "gross_sales", "discounts", "refunds", "refunds_pre_tax", "net_sales", "taxes",
+			"total_sales", "average_ticket", "average_per_cover", "payment_methods",
+		}
+		rows := make([][]interface{}, 0, len(report)+1)
+		for _, row := range append(report, totals) {
+			methods := make([]string, len(row.Payment_methods))
+			for i, mix := range row.Payment_methods {
+				methods[i] = fmt.Sprintf("%s %.2f", mix.Method, mix.Amount)
+			}
+			rows = append(rows, []interface{}{
+				row.Period, row.Invoices, row.Covers, row.Gross_sales, row.Discounts, row.Refunds, row.Refunds_pre_tax, row.Net_sales, row.Taxes,
+				row.Total_sales, row.Average_ticket, row.Average_per_cover, strings.Join(methods, "; "),
+			})
+		}
+		writeExport(w, format, "sales-report", header, rows)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Sales report generated successfully",
+		"data": map[string]interface{}{
+			"from":     from.Format("2006-01-02"),
+			"to":       to.AddDate(0, 0, -1).Format("2006-01-02"),
+			"group_by": groupBy,
+			"periods":  report,
+			"totals":   totals,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,600 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var sectionAssignmentCollection *mongo.Collection = database.OpenCollection(database.Client, "section_assignment")
+
+// Servers are assigned to floor sections, and to single tables over their section. New orders go
+// to the table's server; the order's user_id is whoever rang it in.
+
+// checkServer makes sure a user can serve tables at an outlet: a floor role, and working at the
+// outlet or at head office. It returns why not, or "".
+func checkServer(ctx context.Context, userId, outletId string) (string, error) {
+	var user models.User
+	err := userCollection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "Server not found", nil
+	} else if err != nil {
+		return "", err
+	}
+	if user.Role == nil || !staffRoles[*user.Role] {
+		return "Only staff can serve tables", nil
+	}
+	userOutlet := ""
+	if user.Outlet_id != nil {
+		userOutlet = *user.Outlet_id
+	}
+	if userOutlet != outletId && !user.Head_office {
+		return "Server works at another outlet", nil
+	}
+	return "", nil
+}
+
+// tableServer is the server looking after a table: its own, else its section's, else ""
+func tableServer(ctx context.Context, table models.Table) (string, error) {
+	if table.Server_id != "" {
+		return table.Server_id, nil
+	}
+	if table.Section == "" {
+		return "", nil
+	}
+	var assignment models.SectionAssignment
+	err := sectionAssignmentCollection.FindOne(ctx, sameOutlet(bson.M{"section": table.Section}, table.Outlet_id)).Decode(&assignment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	return assignment.Server_id, err
+}
+
+// sectionKey identifies a section across outlets
+func sectionKey(outletId, section string) string {
+	return outletId + "/" + section
+}
+
+// sectionServers maps the caller's outlets' sections to their servers
+func sectionServers(ctx context.Context, r *http.Request) (map[string]string, error) {
+	cursor, err := sectionAssignmentCollection.Find(ctx, outletScope(r, bson.M{}))
+	if err != nil {
+		return nil, err
+	}
+	var assignments []models.SectionAssignment
+	if err := cursor.All(ctx, &assignments); err != nil {
+		return nil, err
+	}
+	servers := make(map[string]string, len(assignments))
+	for _, assignment := range assignments {
+		servers[sectionKey(assignment.Outlet_id, assignment.Section)] = assignment.Server_id
+	}
+	return servers, nil
+}
+
+// userNames returns the full names of users by user_id
+func userNames(ctx context.Context, userIds []string) (map[string]string, error) {
+	names := make(map[string]string, len(userIds))
+	if len(userIds) == 0 {
+		return names, nil
+	}
+	cursor, err := userCollection.Find(ctx, bson.M{"user_id": bson.M{"$in": userIds}})
+	if err != nil {
+		return nil, err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if user.First_name != nil && user.Last_name != nil {
+			names[user.User_id] = strings.TrimSpace(*user.First_name + " " + *user.Last_name)
+		}
+	}
+	return names, nil
+}
+
+type floorSection struct {
+	Outlet_id   string `json:"outlet_id,omitempty" bson:"outlet_id"`
+	Section     string `json:"section" bson:"section"`
+	Tables      int64  `json:"tables" bson:"tables"`
+	Seats       int64  `json:"seats" bson:"seats"`
+	Server_id   string `json:"server_id" bson:"-"`
+	Server_name string `json:"server_name" bson:"-"`
+}
+
+// GetSections lists the floor sections of the outlet's tables with the server of each
+func GetSections(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: outletScope(r, bson.M{"table_number": bson.M{"$ne": nil}, "merged_into": bson.M{"$in": bson.A{nil, ""}}})}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "outlet_id", Value: "$outlet_id"}, {Key: "section", Value: "$section"}}},
+			{Key: "tables", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "seats", Value: bson.D{{Key: "$sum", Value: "$number_of_guests"}}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "outlet_id", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$_id.outlet_id", ""}}}},
+			{Key: "section", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$_id.section", ""}}}},
+			{Key: "tables", Value: 1},
+			{Key: "seats", Value: 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "outlet_id", Value: 1}, {Key: "section", Value: 1}}}},
+	}
+	cursor, err := tableCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving sections"}`, http.StatusInternalServerError)
+		return
+	}
+	sections := []floorSection{}
+	if err := cursor.All(ctx, &sections); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding sections"}`, http.StatusInternalServerError)
+		return
+	}
+
+	servers, err := sectionServers(ctx, r)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving section servers"}`, http.StatusInternalServerError)
+		return
+	}
+	serverIds := make([]string, 0, len(servers))
+	for _, serverId := range servers {
+		serverIds = append(serverIds, serverId)
+	}
+	names, err := userNames(ctx, serverIds)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving servers"}`, http.StatusInternalServerError)
+		return
+	}
+	for i := range sections {
+		sections[i].Server_id = servers[sectionKey(sections[i].Outlet_id, sections[i].Section)]
+		sections[i].Server_name = names[sections[i].Server_id]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Sections retrieved successfully",
+		"data":    sections,
+	})
+}
+
+// AssignSectionServer puts a server in charge of a section of the caller's outlet. Orders already
+// open keep their server; hand them over with POST /servers/{user_id}/handover.
+func AssignSectionServer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	section := mux.Vars(r)["section"]
+	var requestBody struct {
+		Server_id string `json:"server_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Server_id == "" {
+		http.Error(w, `{"success": false, "message": "server_id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	outletId, outletExists, err := checkRequestOutlet(ctx, r)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking outlet"}`, http.StatusInternalServerError)
+		return
+	}
+	if !outletExists {
+		http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusBadRequest)
+		return
+	}
+
+	count, err := tableCollection.CountDocuments(ctx, sameOutlet(bson.M{"section": section}, outletId))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking section"}`, http.StatusInternalServerError)
+		return
+	}
+	if count == 0 {
+		http.Error(w, `{"success": false, "message": "No tables in this section"}`, http.StatusNotFound)
+		return
+	}
+
+	msg, err := checkServer(ctx, requestBody.Server_id, outletId)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking server"}`, http.StatusInternalServerError)
+		return
+	}
+	if msg != "" {
+		http.Error(w, `{"success": false, "message": "`+msg+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	assignment := models.SectionAssignment{
+		Outlet_id:   outletId,
+		Section:     section,
+		Server_id:   requestBody.Server_id,
+		Assigned_by: uid,
+		Assigned_at: time.Now(),
+	}
+	opts := options.FindOneAndReplace().SetUpsert(true).SetReturnDocument(options.After)
+	err = sectionAssignmentCollection.FindOneAndReplace(ctx, sameOutlet(bson.M{"section": section}, outletId), assignment, opts).Decode(&assignment)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to assign section"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Section assigned successfully",
+		"data":    assignment,
+	})
+}
+
+// UnassignSectionServer leaves a section of the caller's outlet without a server
+func UnassignSectionServer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := sameOutlet(bson.M{"section": mux.Vars(r)["section"]}, requestOutletId(r))
+	result, err := sectionAssignmentCollection.DeleteOne(ctx, filter)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to unassign section"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, `{"success": false, "message": "Section has no server"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Section unassigned successfully",
+	})
+}
+
+// AssignTableServer gives a table its own server, or with an empty server_id hands it back to its
+// section's. The table's open order goes to the new server.
+func AssignTableServer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	tableId := mux.Vars(r)["table_id"]
+	var requestBody struct {
+		Server_id *string `json:"server_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Server_id == nil {
+		http.Error(w, `{"success": false, "message": "server_id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	var table models.Table
+	err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Table not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving table"}`, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"server_id": *requestBody.Server_id, "updated_at": now}}
+	if *requestBody.Server_id == "" {
+		update = bson.M{"$unset": bson.M{"server_id": ""}, "$set": bson.M{"updated_at": now}}
+	} else {
+		msg, err := checkServer(ctx, *requestBody.Server_id, table.Outlet_id)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Error checking server"}`, http.StatusInternalServerError)
+			return
+		}
+		if msg != "" {
+			http.Error(w, `{"success": false, "message": "`+msg+`"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	if _, err := tableCollection.UpdateOne(ctx, bson.M{"table_id": tableId}, update); err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to assign table"}`, http.StatusInternalServerError)
+		return
+	}
+
+	table.Server_id = *requestBody.Server_id
+	serverId, err := tableServer(ctx, table)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving section server"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// The party at the table goes along with it
+	openOrderId := ""
+	if order, err := findOpenOrder(ctx, tableId); err == nil {
+		openOrderId = order.Order_id
+		if serverId != "" {
+			_, err = orderCollection.UpdateOne(ctx, bson.M{"order_id": order.Order_id}, bson.M{"$set": bson.M{"waiter_id": serverId, "updated_at": now}})
+			if err != nil {
+				http.Error(w, `{"success": false, "message": "Failed to hand over the open order"}`, http.StatusInternalServerError)
+				return
+			}
+		}
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Error retrieving open order"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Table server assigned successfully",
+		"data": map[string]interface{}{
+			"table_id":      tableId,
+			"server_id":     serverId,
+			"open_order_id": openOrderId,
+		},
+	})
+}
+
+// HandoverServer moves every section, table and open order of a server at the caller's outlet to
+// another server, e.g. at the end of their shift
+func HandoverServer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	fromId := mux.Vars(r)["user_id"]
+	var requestBody struct {
+		To_server_id string `json:"to_server_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.To_server_id == "" {
+		http.Error(w, `{"success": false, "message": "to_server_id is required"}`, http.StatusBadRequest)
+		return
+	}
+	toId := requestBody.To_server_id
+	if toId == fromId {
+		http.Error(w, `{"success": false, "message": "Cannot hand over to the same server"}`, http.StatusBadRequest)
+		return
+	}
+
+	msg, err := checkServer(ctx, toId, requestOutletId(r))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking server"}`, http.StatusInternalServerError)
+		return
+	}
+	if msg != "" {
+		http.Error(w, `{"success": false, "message": "`+msg+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	sections, err := sectionAssignmentCollection.UpdateMany(ctx,
+		outletScope(r, bson.M{"server_id": fromId}),
+		bson.M{"$set": bson.M{"server_id": toId, "assigned_by": uid, "assigned_at": now}},
+	)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to hand over sections"}`, http.StatusInternalServerError)
+		return
+	}
+	tables, err := tableCollection.UpdateMany(ctx,
+		outletScope(r, bson.M{"server_id": fromId}),
+		bson.M{"$set": bson.M{"server_id": toId, "updated_at": now}},
+	)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to hand over tables"}`, http.StatusInternalServerError)
+		return
+	}
+	// Orders without a waiter are served by whoever rang them in
+	orders, err := orderCollection.UpdateMany(ctx,
+		activeFilter(outletScope(r, bson.M{
+			"status": bson.M{"$nin": closedOrderStatuses},
+			"$or": bson.A{
+				bson.M{"waiter_id": fromId},
+				bson.M{"waiter_id": nil, "user_id": fromId},
+			},
+		})),
+		bson.M{"$set": bson.M{"waiter_id": toId, "updated_at": now}},
+	)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to hand over open orders"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Server handed over successfully",
+		"data": map[string]interface{}{
+			"from_server_id": fromId,
+			"to_server_id":   toId,
+			"sections":       sections.ModifiedCount,
+			"tables":         tables.ModifiedCount,
+			"open_orders":    orders.ModifiedCount,
+		},
+	})
+}
+
+type serverReportRow struct {
+	Server_id         string  `json:"server_id" bson:"_id"`
+	Name              string  `json:"name" bson:"-"`
+	Invoices          int64   `json:"invoices" bson:"invoices"`
+	Covers            int64   `json:"covers" bson:"covers"`
+	Gross_sales       float64 `json:"gross_sales" bson:"gross"`
+	Discounts         float64 `json:"discounts" bson:"discounts"`
+	Refunds           float64 `json:"refunds" bson:"-"`         // money returned, taxes included
+	Refunds_pre_tax   float64 `json:"refunds_pre_tax" bson:"-"` // the refunds' share of the invoices' pre-tax totals
+	Net_sales         float64 `json:"net_sales" bson:"-"`       // gross sales less discounts and pre-tax refunds
+	Total_sales       float64 `json:"total_sales" bson:"total"`
+	Tips              float64 `json:"tips" bson:"tips"`
+	Average_ticket    float64 `json:"average_ticket" bson:"-"`
+	Average_per_cover float64 `json:"average_per_cover" bson:"-"`
+}
+
+// invoiceServer is the server an invoice's order was looked after by, after a $lookup of the order
+var invoiceServer = bson.D{{Key: "$ifNull", Value: bson.A{
+	bson.D{{Key: "$arrayElemAt", Value: bson.A{"$order.waiter_id", 0}}},
+	bson.D{{Key: "$arrayElemAt", Value: bson.A{"$order.user_id", 0}}},
+	"",
+}}}
+
+// GetServerReport reports each server's sales, covers and tips over ?from=&to=, credited to the
+// server looking after the order. Refunds count against the server in the range they were issued.
+func GetServerReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	from, to, message := reportRange(r)
+	if message != "" {
+		http.Error(w, `{"success": false, "message": "`+message+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	orderLookup := bson.D{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: "order"},
+		{Key: "localField", Value: "order_id"},
+		{Key: "foreignField", Value: "order_id"},
+		{Key: "as", Value: "order"},
+	}}}
+
+	invoicePipeline := mongo.Pipeline{
+		{{Key: "$match", Value: activeFilter(outletScope(r, bson.M{
+			"payment_status": bson.M{"$in": salesInvoiceStatuses},
+			"created_at":     bson.M{"$gte": from, "$lt": to},
+		}))}},
+		orderLookup,
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: invoiceServer},
+			{Key: "invoices", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "covers", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$arrayElemAt", Value: bson.A{"$order.covers", 0}}}, 0}}}}}},
+			{Key: "gross", Value: bson.D{{Key: "$sum", Value: invoiceGrossSales}}},
+			{Key: "discounts", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$discount_amount", 0}}}}}},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: "$total_price"}}},
+			{Key: "tips", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$tip_amount", 0}}}}}},
+		}}},
+	}
+	cursor, err := invoiceCollection.Aggregate(ctx, invoicePipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error building server report"}`, http.StatusInternalServerError)
+		return
+	}
+	var sales []serverReportRow
+	if err := cursor.All(ctx, &sales); err != nil {
+		http.Error(w, `{"success": false, "message": "Error building server report"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Refunds come off net sales without their taxes, as in the sales report
+	refundPipeline := append(mongo.Pipeline{
+		{{Key: "$match", Value: outletScope(r, bson.M{"created_at": bson.M{"$gte": from, "$lt": to}})}},
+		orderLookup,
+	}, refundInvoiceTotals...)
+	refundPipeline = append(refundPipeline, bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: invoiceServer},
+		{Key: "refunds", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+		{Key: "refunds_pre_tax", Value: bson.D{{Key: "$sum", Value: refundPreTax}}},
+	}}})
+	cursor, err = creditNoteCollection.Aggregate(ctx, refundPipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error building server report"}`, http.StatusInternalServerError)
+		return
+	}
+	var refunds []struct {
+		Server_id       string  `bson:"_id"`
+		Refunds         float64 `bson:"refunds"`
+		Refunds_pre_tax float64 `bson:"refunds_pre_tax"`
+	}
+	if err := cursor.All(ctx, &refunds); err != nil {
+		http.Error(w, `{"success": false, "message": "Error building server report"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows := make(map[string]*serverReportRow, len(sales))
+	for i := range sales {
+		rows[sales[i].Server_id] = &sales[i]
+	}
+	for _, refund := range refunds {
+		if rows[refund.Server_id] == nil {
+			rows[refund.Server_id] = &serverReportRow{Server_id: refund.Server_id}
+		}
+		rows[refund.Server_id].Refunds = refund.Refunds
+		rows[refund.Server_id].Refunds_pre_tax = refund.Refunds_pre_tax
+	}
+
+	serverIds := make([]string, 0, len(rows))
+	for serverId := range rows {
+		serverIds = append(serverIds, serverId)
+	}
+	names, err := userNames(ctx, serverIds)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving servers"}`, http.StatusInternalServerError)
+		return
+	}
+
+	report := make([]serverReportRow, 0, len(rows))
+	for _, row := range rows {
+		row.Name = names[row.Server_id]
+		row.Gross_sales = helper.RoundMoney(row.Gross_sales)
+		row.Discounts = helper.RoundMoney(row.Discounts)
+		row.Refunds = helper.RoundMoney(row.Refunds)
+		row.Refunds_pre_tax = helper.RoundMoney(row.Refunds_pre_tax)
+		row.Total_sales = helper.RoundMoney(row.Total_sales)
+		row.Tips = helper.RoundMoney(row.Tips)
+		row.Net_sales = helper.RoundMoney(row.Gross_sales - row.Discounts - row.Refunds_pre_tax)
+		if row.Invoices > 0 {
+			row.Average_ticket = helper.RoundMoney(row.Total_sales / float64(row.Invoices))
+		}
+		if row.Covers > 0 {
+			row.Average_per_cover = helper.RoundMoney(row.Total_sales / float64(row.Covers))
+		}
+		report = append(report, *row)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Net_sales != report[j].Net_sales {
+			return report[i].Net_sales > report[j].Net_sales
+		}
+		return report[i].Server_id < report[j].Server_id
+	})
+
+	if format != "" {
+		header := []interface{}{
+			"server_id", "name", "invoices", "covers", "gross_sales", "discounts", "refunds", "refunds_pre_tax", "net_sales",
+			"total_sales", "tips", "average_ticket", "average_per_cover",
+		}
+		exportRows := make([][]interface{}, 0, len(report))
+		for _, row := range report {
+			exportRows = append(exportRows, []interface{}{
+				row.Server_id, row.Name, row.Invoices, row.Covers, row.Gross_sales, row.Discounts, row.Refunds, row.Refunds_pre_tax, row.Net_sales,
+				row.Total_sales, row.Tips, row.Average_ticket, row.Average_per_cover,
+			})
+		}
+		writeExport(w, format, "server-report", header, exportRows)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Server report generated successfully",
+		"data": map[string]interface{}{
+			"from":    from.Format("2006-01-02"),
+			"to":      to.AddDate(0, 0, -1).Format("2006-01-02"),
+			"servers": report,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
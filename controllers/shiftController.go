@@ -0,0 +1,263 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var shiftCollection *mongo.Collection = database.OpenCollection(database.Client, "shift")
+
+// Longest shift that can be scheduled
+const maxShiftLength = 16 * time.Hour
+
+// checkShiftTimes validates a shift's start and end, returning a message when they are unusable
+func checkShiftTimes(start, end time.Time) string {
+	if !end.After(start) {
+		return "end must be after start"
+	}
+	if end.Sub(start) > maxShiftLength {
+		return "A shift cannot be longer than 16 hours"
+	}
+	return ""
+}
+
+// shiftOverlaps reports whether the employee already has a shift overlapping start to end,
+// other than the one being changed
+func shiftOverlaps(ctx context.Context, staffId, exceptShiftId string, start, end time.Time) (bool, error) {
+	filter := bson.M{
+		"staff_id": staffId,
+		"start":    bson.M{"$lt": end},
+		"end":      bson.M{"$gt": start},
+	}
+	if exceptShiftId != "" {
+		filter["shift_id"] = bson.M{"$ne": exceptShiftId}
+	}
+	count, err := shiftCollection.CountDocuments(ctx, filter)
+	return count > 0, err
+}
+
+// GetShifts lists the outlet's schedule between ?from=YYYY-MM-DD and ?to=YYYY-MM-DD (inclusive, default
+// the next 7 days), optionally for one ?staff_id
+func GetShifts(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid from date, expected YYYY-MM-DD"}`, http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	to := from.AddDate(0, 0, 7)
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid to date, expected YYYY-MM-DD"}`, http.StatusBadRequest)
+			return
+		}
+		to = parsed.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		http.Error(w, `{"success": false, "message": "from must not be after to"}`, http.StatusBadRequest)
+		return
+	}
+
+	filter := outletScope(r, bson.M{"start": bson.M{"$lt": to}, "end": bson.M{"$gt": from}})
+	if staffId := r.URL.Query().Get("staff_id"); staffId != "" {
+		filter["staff_id"] = staffId
+	}
+
+	cursor, err := shiftCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "start", Value: 1}}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving shifts"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	shifts := []models.Shift{}
+	if err := cursor.All(ctx, &shifts); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding shifts"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Shifts retrieved successfully",
+		"data":    shifts,
+	})
+}
+
+// CreateShift schedules an employee of the caller's outlet. An employee's shifts never overlap.
+func CreateShift(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var shift models.Shift
+	if err := json.NewDecoder(r.Body).Decode(&shift); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if validationErr := validate.Struct(shift); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "`+validationErr.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	if msg := checkShiftTimes(shift.Start, shift.End); msg != "" {
+		http.Error(w, `{"success": false, "message": "`+msg+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	var staff models.Staff
+	err := staffCollection.FindOne(ctx, activeFilter(outletScope(r, bson.M{"staff_id": shift.Staff_id}))).Decode(&staff)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Staff member not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving staff member"}`, http.StatusInternalServerError)
+		return
+	}
+
+	overlaps, err := shiftOverlaps(ctx, shift.Staff_id, "", shift.Start, shift.End)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking schedule"}`, http.StatusInternalServerError)
+		return
+	}
+	if overlaps {
+		http.Error(w, `{"success": false, "message": "Staff member already has a shift at that time"}`, http.StatusConflict)
+		return
+	}
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	shift.ID = primitive.NewObjectID()
+	shift.Shift_id = shift.ID.Hex()
+	shift.Outlet_id = staff.Outlet_id
+	shift.Created_by = uid
+	shift.Created_at = time.Now()
+	shift.Updated_at = shift.Created_at
+
+	if _, err := shiftCollection.InsertOne(ctx, shift); err != nil {
+		http.Error(w, `{"success": false, "message": "Shift creation failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Shift created successfully",
+		"data":    shift,
+	})
+}
+
+// UpdateShift moves a shift or changes its notes
+func UpdateShift(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	shiftId := mux.Vars(r)["shift_id"]
+	var requestBody struct {
+		Start *time.Time `json:"start"`
+		End   *time.Time `json:"end"`
+		Notes *string    `json:"notes" validate:"omitempty,max=300"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if validationErr := validate.Struct(requestBody); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "`+validationErr.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	var shift models.Shift
+	err := shiftCollection.FindOne(ctx, bson.M{"shift_id": shiftId}).Decode(&shift)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Shift not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving shift"}`, http.StatusInternalServerError)
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if requestBody.Start != nil || requestBody.End != nil {
+		if requestBody.Start != nil {
+			shift.Start = *requestBody.Start
+		}
+		if requestBody.End != nil {
+			shift.End = *requestBody.End
+		}
+		if msg := checkShiftTimes(shift.Start, shift.End); msg != "" {
+			http.Error(w, `{"success": false, "message": "`+msg+`"}`, http.StatusBadRequest)
+			return
+		}
+		overlaps, err := shiftOverlaps(ctx, shift.Staff_id, shiftId, shift.Start, shift.End)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Error checking schedule"}`, http.StatusInternalServerError)
+			return
+		}
+		if overlaps {
+			http.Error(w, `{"success": false, "message": "Staff member already has a shift at that time"}`, http.StatusConflict)
+			return
+		}
+		set["start"], set["end"] = shift.Start, shift.End
+	}
+	if requestBody.Notes != nil {
+		set["notes"] = *requestBody.Notes
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = shiftCollection.FindOneAndUpdate(ctx, bson.M{"shift_id": shiftId}, bson.M{"$set": set}, opts).Decode(&shift)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Shift not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Shift update failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Shift updated successfully",
+		"data":    shift,
+	})
+}
+
+// DeleteShift takes a shift off the schedule. Time already clocked against it is kept.
+func DeleteShift(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	result, err := shiftCollection.DeleteOne(ctx, bson.M{"shift_id": mux.Vars(r)["shift_id"]})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error deleting shift"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.DeletedCount == 0 {
+		http.Error(w, `{"success": false, "message": "Shift not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Shift deleted successfully",
+	})
+}
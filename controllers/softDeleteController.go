@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Menus, foods, orders and invoices are never removed; deleting one stamps
+// deleted_at/deleted_by and hides it from the regular queries.
+
+// activeFilter restricts a filter to documents that have not been deleted
+func activeFilter(filter bson.M) bson.M {
+	filter["deleted_at"] = nil
+	return filter
+}
+
+// listFilter hides deleted documents unless the request asks for ?include_deleted=true,
+// and documents of other outlets
+func listFilter(r *http.Request, filter bson.M) bson.M {
+	return deletedFilter(r, outletScope(r, filter))
+}
+
+// deletedFilter hides deleted documents unless the request asks for ?include_deleted=true
+func deletedFilter(r *http.Request, filter bson.M) bson.M {
+	if r.URL.Query().Get("include_deleted") == "true" {
+		return filter
+	}
+	return activeFilter(filter)
+}
+
+// cascadeRequested reports whether the caller asked to delete dependants along with the document
+func cascadeRequested(r *http.Request) bool {
+	return r.URL.Query().Get("cascade") == "true"
+}
+
+// softDelete marks every matching active document as deleted by the calling user
+func softDelete(ctx context.Context, r *http.Request, collection *mongo.Collection, filter bson.M, deletedAt time.Time) (*mongo.UpdateResult, error) {
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	update := bson.M{"$set": bson.M{
+		"deleted_at": deletedAt,
+		"deleted_by": uid,
+		"updated_at": deletedAt,
+	}}
+	return collection.UpdateMany(ctx, activeFilter(filter), update)
+}
+
+// restoreDeleted clears the deletion stamp on every matching deleted document
+func restoreDeleted(ctx context.Context, collection *mongo.Collection, filter bson.M) (*mongo.UpdateResult, error) {
+	if _, ok := filter["deleted_at"]; !ok {
+		filter["deleted_at"] = bson.M{"$ne": nil}
+	}
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	}
+	return collection.UpdateMany(ctx, filter, update)
+}
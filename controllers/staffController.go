@@ -0,0 +1,355 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var staffCollection *mongo.Collection = database.OpenCollection(database.Client, "staff")
+
+// PINs are stored hashed and never leave the server
+var staffProjection = bson.M{"pin": 0}
+
+// checkStaffUser makes sure the login account a staff profile is linked to exists
+func checkStaffUser(ctx context.Context, userId *string) (bool, error) {
+	if userId == nil || *userId == "" {
+		return true, nil
+	}
+	count, err := userCollection.CountDocuments(ctx, bson.M{"user_id": *userId})
+	return count > 0, err
+}
+
+// verifyStaffPin checks a PIN punched in for an employee. After PIN_MAX_ATTEMPTS wrong PINs in a row
+// they are locked out for PIN_LOCKOUT_MINUTES, right PIN or not. On failure it returns the
+// message and status to answer with.
+func verifyStaffPin(ctx context.Context, staff models.Staff, pin string) (string, int, error) {
+	now := time.Now()
+	if staff.Pin_locked_until != nil && now.Before(*staff.Pin_locked_until) {
+		return "Too many wrong PINs; try again later or ask a manager to unlock", http.StatusTooManyRequests, nil
+	}
+
+	filter := bson.M{"staff_id": staff.Staff_id}
+	if valid, _ := VerifyPassword(pin, staff.Pin); valid {
+		if staff.Pin_failures > 0 || staff.Pin_locked_until != nil {
+			if _, err := staffCollection.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"pin_failures": "", "pin_locked_until": ""}}); err != nil {
+				return "", 0, err
+			}
+		}
+		return "", 0, nil
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"pin_failures": 1})
+	var counted models.Staff
+	if err := staffCollection.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"pin_failures": 1}}, opts).Decode(&counted); err != nil {
+		return "", 0, err
+	}
+	if counted.Pin_failures >= helper.PinMaxAttempts() {
+		update := bson.M{"$set": bson.M{"pin_locked_until": now.Add(helper.PinLockout())}, "$unset": bson.M{"pin_failures": ""}}
+		if _, err := staffCollection.UpdateOne(ctx, filter, update); err != nil {
+			return "", 0, err
+		}
+		return "Too many wrong PINs; try again later or ask a manager to unlock", http.StatusTooManyRequests, nil
+	}
+	return "Invalid employee code or PIN", http.StatusUnauthorized, nil
+}
+
+// GetStaff lists the outlet's staff by employee code
+func GetStaff(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := listFilter(r, bson.M{})
+	if role := r.URL.Query().Get("role"); role != "" {
+		filter["role"] = strings.ToUpper(role)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "employee_code", Value: 1}}).SetProjection(staffProjection)
+	cursor, err := staffCollection.Find(ctx, filter, opts)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving staff"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	staff := []models.Staff{}
+	if err := cursor.All(ctx, &staff); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding staff"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Staff retrieved successfully",
+		"data":    staff,
+	})
+}
+
+// GetStaffMember returns one staff profile
+func GetStaffMember(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var staff models.Staff
+	opts := options.FindOne().SetProjection(staffProjection)
+	err := staffCollection.FindOne(ctx, deletedFilter(r, bson.M{"staff_id": mux.Vars(r)["staff_id"]}), opts).Decode(&staff)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Staff member not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving staff member"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Staff member retrieved successfully",
+		"data":    staff,
+	})
+}
+
+// CreateStaff adds an employee to the caller's outlet. Employee codes are unique across outlets,
+// so staff keep theirs when they move.
+func CreateStaff(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var staff models.Staff
+	if err := json.NewDecoder(r.Body).Decode(&staff); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	staff.Employee_code = strings.ToUpper(strings.TrimSpace(staff.Employee_code))
+	staff.Role = strings.ToUpper(strings.TrimSpace(staff.Role))
+	if validationErr := validate.Struct(staff); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "`+validationErr.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	outletId, outletExists, err := checkRequestOutlet(ctx, r)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking outlet"}`, http.StatusInternalServerError)
+		return
+	}
+	if !outletExists {
+		http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusBadRequest)
+		return
+	}
+
+	count, err := staffCollection.CountDocuments(ctx, bson.M{"employee_code": staff.Employee_code})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking employee code"}`, http.StatusInternalServerError)
+		return
+	}
+	if count > 0 {
+		http.Error(w, `{"success": false, "message": "Employee code already exists"}`, http.StatusConflict)
+		return
+	}
+
+	userExists, err := checkStaffUser(ctx, staff.User_id)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking user"}`, http.StatusInternalServerError)
+		return
+	}
+	if !userExists {
+		http.Error(w, `{"success": false, "message": "User not found"}`, http.StatusBadRequest)
+		return
+	}
+
+	staff.ID = primitive.NewObjectID()
+	staff.Staff_id = staff.ID.Hex()
+	staff.Outlet_id = outletId
+	staff.Pin = HashPassword(staff.Pin)
+	staff.Created_at = time.Now()
+	staff.Updated_at = staff.Created_at
+	staff.Deleted_at, staff.Deleted_by = nil, nil
+	staff.Pin_failures, staff.Pin_locked_until = 0, nil
+
+	if _, err := staffCollection.InsertOne(ctx, staff); mongo.IsDuplicateKeyError(err) {
+		http.Error(w, `{"success": false, "message": "Employee code already exists"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Staff creation failed"}`, http.StatusInternalServerError)
+		return
+	}
+	staff.Pin = ""
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Staff member created successfully",
+		"data":    staff,
+	})
+}
+
+// UpdateStaff changes a staff profile or resets the PIN. Resetting the PIN or sending unlock ends a
+// PIN lockout. Only head office moves staff to another outlet; a raise applies to time clocked in
+// from then on.
+func UpdateStaff(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	staffId := mux.Vars(r)["staff_id"]
+	var requestBody struct {
+		First_name  *string  `json:"first_name" validate:"omitempty,min=2,max=100"`
+		Last_name   *string  `json:"last_name" validate:"omitempty,min=1,max=100"`
+		Role        *string  `json:"role" validate:"omitempty,min=2,max=30"`
+		Hourly_rate *float64 `json:"hourly_rate" validate:"omitempty,gte=0"`
+		Pin         *string  `json:"pin" validate:"omitempty,numeric,min=4,max=6"`
+		User_id     *string  `json:"user_id"`
+		Outlet_id   *string  `json:"outlet_id"`
+		Unlock      bool     `json:"unlock"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if validationErr := validate.Struct(requestBody); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "`+validationErr.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	update := bson.M{}
+	set := bson.M{"updated_at": time.Now()}
+	if requestBody.First_name != nil {
+		set["first_name"] = *requestBody.First_name
+	}
+	if requestBody.Last_name != nil {
+		set["last_name"] = *requestBody.Last_name
+	}
+	if requestBody.Role != nil {
+		set["role"] = strings.ToUpper(strings.TrimSpace(*requestBody.Role))
+	}
+	if requestBody.Hourly_rate != nil {
+		set["hourly_rate"] = *requestBody.Hourly_rate
+	}
+	unset := bson.M{}
+	if requestBody.Pin != nil {
+		set["pin"] = HashPassword(*requestBody.Pin)
+	}
+	if requestBody.Pin != nil || requestBody.Unlock {
+		unset["pin_failures"], unset["pin_locked_until"] = "", ""
+	}
+	if requestBody.User_id != nil {
+		if *requestBody.User_id == "" {
+			unset["user_id"] = ""
+		} else {
+			userExists, err := checkStaffUser(ctx, requestBody.User_id)
+			if err != nil {
+				http.Error(w, `{"success": false, "message": "Error checking user"}`, http.StatusInternalServerError)
+				return
+			}
+			if !userExists {
+				http.Error(w, `{"success": false, "message": "User not found"}`, http.StatusBadRequest)
+				return
+			}
+			set["user_id"] = *requestBody.User_id
+		}
+	}
+	if requestBody.Outlet_id != nil {
+		if _, headOffice := middleware.GetOutletFromContext(r); !headOffice {
+			http.Error(w, `{"success": false, "message": "Only head office moves staff between outlets"}`, http.StatusForbidden)
+			return
+		}
+		if *requestBody.Outlet_id != "" {
+			count, err := outletCollection.CountDocuments(ctx, activeFilter(bson.M{"outlet_id": *requestBody.Outlet_id}))
+			if err != nil {
+				http.Error(w, `{"success": false, "message": "Error checking outlet"}`, http.StatusInternalServerError)
+				return
+			}
+			if count == 0 {
+				http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusNotFound)
+				return
+			}
+		}
+		clockedIn, err := timeEntryCollection.CountDocuments(ctx, bson.M{"staff_id": staffId, "status": clockOpen})
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Error checking time clock"}`, http.StatusInternalServerError)
+			return
+		}
+		if clockedIn > 0 {
+			http.Error(w, `{"success": false, "message": "Staff member is clocked in; clock out before moving outlets"}`, http.StatusConflict)
+			return
+		}
+		set["outlet_id"] = outletValue(*requestBody.Outlet_id)
+	}
+	update["$set"] = set
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(staffProjection)
+	var staff models.Staff
+	err := staffCollection.FindOneAndUpdate(ctx, activeFilter(bson.M{"staff_id": staffId}), update, opts).Decode(&staff)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Staff member not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Staff update failed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Staff member updated successfully",
+		"data":    staff,
+	})
+}
+
+// DeleteStaff soft-deletes a staff member who is not clocked in; their timesheets stay.
+// Shifts scheduled for them from now on are removed.
+func DeleteStaff(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	staffId := mux.Vars(r)["staff_id"]
+
+	clockedIn, err := timeEntryCollection.CountDocuments(ctx, bson.M{"staff_id": staffId, "status": clockOpen})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking time clock"}`, http.StatusInternalServerError)
+		return
+	}
+	if clockedIn > 0 {
+		http.Error(w, `{"success": false, "message": "Staff member is clocked in; clock out first"}`, http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	result, err := softDelete(ctx, r, staffCollection, bson.M{"staff_id": staffId}, now)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error deleting staff member"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"success": false, "message": "Staff member not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if _, err := shiftCollection.DeleteMany(ctx, bson.M{"staff_id": staffId, "start": bson.M{"$gte": now}}); err != nil {
+		http.Error(w, `{"success": false, "message": "Staff member deleted but their upcoming shifts could not be removed"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Staff member deleted successfully",
+	})
+}
@@ -3,21 +3,26 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
 	"github.com/02priyeshraj/Hotel_Management_Backend/models"
 
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var tableCollection *mongo.Collection = database.OpenCollection(database.Client, "table")
 
+var errTableAlreadyMerged = errors.New("table is already part of a merge")
+
 func GetTables(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 	defer cancel()
@@ -36,7 +41,7 @@ func GetTables(w http.ResponseWriter, r *http.Request) {
 	startIndex := (page - 1) * recordPerPage
 
 	// MongoDB Aggregation Pipeline
-	matchStage := bson.D{{Key: "$match", Value: bson.D{}}}
+	matchStage := bson.D{{Key: "$match", Value: outletScope(r, bson.M{})}}
 	skipStage := bson.D{{Key: "$skip", Value: startIndex}}
 	limitStage := bson.D{{Key: "$limit", Value: int64(recordPerPage)}}
 	projectStage := bson.D{
@@ -80,7 +85,7 @@ func GetTables(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get total table count
-	totalTables, err := tableCollection.CountDocuments(ctx, bson.M{})
+	totalTables, err := tableCollection.CountDocuments(ctx, outletScope(r, bson.M{}))
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error retrieving total table count"}`, http.StatusInternalServerError)
 		return
@@ -165,8 +170,26 @@ func CreateTable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if the table number already exists
-	count, err := tableCollection.CountDocuments(ctx, bson.M{"table_number": table.Table_number})
+	outletId, outletExists, err := checkRequestOutlet(ctx, r)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Error checking outlet",
+		})
+		return
+	}
+	if !outletExists {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Outlet not found",
+		})
+		return
+	}
+
+	// Check if the table number already exists in the outlet
+	count, err := tableCollection.CountDocuments(ctx, sameOutlet(bson.M{"table_number": table.Table_number}, outletId))
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -186,7 +209,7 @@ func CreateTable(w http.ResponseWriter, r *http.Request) {
 
 	// Set default status if missing
 	if table.Status == "" {
-		table.Status = "Not Reserved"
+		table.Status = tableAvailable
 	}
 
 	// Set metadata fields
@@ -194,6 +217,8 @@ func CreateTable(w http.ResponseWriter, r *http.Request) {
 	table.Updated_at = time.Now()
 	table.ID = primitive.NewObjectID()
 	table.Table_id = table.ID.Hex()
+	table.Outlet_id = outletId
+	table.Server_id = "" // assigned with PATCH /tables/{table_id}/server
 
 	// Insert into MongoDB
 	_, insertErr := tableCollection.InsertOne(ctx, table)
@@ -386,7 +411,7 @@ func ReserveTable(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if the table is already reserved
-	if existingTable.Status == "Reserved" {
+	if existingTable.Status == tableReserved {
 		w.WriteHeader(http.StatusConflict)
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
@@ -396,11 +421,7 @@ func ReserveTable(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update the table status to Reserved
-	existingTable.Status = "Reserved"
-	existingTable.Updated_at = time.Now()
-	update := bson.D{{Key: "$set", Value: bson.M{"status": existingTable.Status, "updated_at": existingTable.Updated_at}}}
-
-	_, err = tableCollection.UpdateOne(ctx, bson.M{"table_id": tableId}, update)
+	changed, err := advanceTableStatus(ctx, tableId, "reserve", "")
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -409,6 +430,16 @@ func ReserveTable(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if !changed {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Table is not available, current status: " + existingTable.Status,
+		})
+		return
+	}
+	existingTable.Status = tableReserved
+	existingTable.Updated_at = time.Now()
 
 	// Return updated table details
 	w.WriteHeader(http.StatusOK)
@@ -446,7 +477,7 @@ func UnreserveTable(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if the table is already not reserved
-	if existingTable.Status == "Not Reserved" {
+	if normalizeTableStatus(existingTable.Status) == tableAvailable {
 		w.WriteHeader(http.StatusConflict)
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
@@ -455,12 +486,8 @@ func UnreserveTable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update the table status to Not Reserved
-	existingTable.Status = "Not Reserved"
-	existingTable.Updated_at = time.Now()
-	update := bson.D{{Key: "$set", Value: bson.M{"status": existingTable.Status, "updated_at": existingTable.Updated_at}}}
-
-	_, err = tableCollection.UpdateOne(ctx, bson.M{"table_id": tableId}, update)
+	// Update the table status back to Available
+	changed, err := advanceTableStatus(ctx, tableId, "unreserve", "")
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -469,6 +496,16 @@ func UnreserveTable(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if !changed {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "Only reserved tables can be unreserved, current status: " + existingTable.Status,
+		})
+		return
+	}
+	existingTable.Status = tableAvailable
+	existingTable.Updated_at = time.Now()
 
 	// Return updated table details
 	w.WriteHeader(http.StatusOK)
@@ -505,7 +542,7 @@ func GetReservedTables(w http.ResponseWriter, r *http.Request) {
 
 	// Create aggregation pipeline with filtering and pagination
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"status": "Reserved"}}},
+		{{Key: "$match", Value: outletScope(r, bson.M{"status": tableReserved})}},
 		{{Key: "$skip", Value: startIndex}},
 		{{Key: "$limit", Value: int64(recordPerPage)}},
 	}
@@ -524,7 +561,7 @@ func GetReservedTables(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get total count of reserved tables
-	totalCount, err := tableCollection.CountDocuments(ctx, bson.M{"status": "Reserved"})
+	totalCount, err := tableCollection.CountDocuments(ctx, outletScope(r, bson.M{"status": tableReserved}))
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error retrieving total reserved table count"}`, http.StatusInternalServerError)
 		return
@@ -559,9 +596,14 @@ func GetUnreservedTables(w http.ResponseWriter, r *http.Request) {
 
 	startIndex := (page - 1) * recordPerPage
 
+	filter := outletScope(r, bson.M{})
+	for key, value := range availableTableFilter {
+		filter[key] = value
+	}
+
 	// Create aggregation pipeline with filtering and pagination
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"status": "Not Reserved"}}},
+		{{Key: "$match", Value: filter}},
 		{{Key: "$skip", Value: startIndex}},
 		{{Key: "$limit", Value: int64(recordPerPage)}},
 	}
@@ -580,7 +622,7 @@ func GetUnreservedTables(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get total count of unreserved tables
-	totalCount, err := tableCollection.CountDocuments(ctx, bson.M{"status": "Not Reserved"})
+	totalCount, err := tableCollection.CountDocuments(ctx, filter)
 	if err != nil {
 		http.Error(w, `{"success": false, "message": "Error retrieving total unreserved table count"}`, http.StatusInternalServerError)
 		return
@@ -597,3 +639,422 @@ func GetUnreservedTables(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+func GetTableQR(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	tableId := mux.Vars(r)["table_id"]
+
+	var table models.Table
+	if err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table); err != nil {
+		http.Error(w, `{"success": false, "message": "Table not found"}`, http.StatusNotFound)
+		return
+	}
+
+	qrToken, err := helper.GenerateTableQRToken(table.Table_id, table.Qr_version)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error generating QR token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Table QR token retrieved successfully",
+		"data": map[string]interface{}{
+			"table_id":     table.Table_id,
+			"table_number": table.Table_number,
+			"qr_version":   table.Qr_version,
+			"qr_token":     qrToken,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RotateTableQR invalidates the table's current QR code and every guest session opened with it
+func RotateTableQR(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	tableId := mux.Vars(r)["table_id"]
+
+	var table models.Table
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	update := bson.M{"$inc": bson.M{"qr_version": 1}, "$set": bson.M{"updated_at": time.Now()}}
+	err := tableCollection.FindOneAndUpdate(ctx, bson.M{"table_id": tableId}, update, opts).Decode(&table)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Table not found"}`, http.StatusNotFound)
+		return
+	}
+
+	qrToken, err := helper.GenerateTableQRToken(table.Table_id, table.Qr_version)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error generating QR token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Table QR token rotated successfully",
+		"data": map[string]interface{}{
+			"table_id":     table.Table_id,
+			"table_number": table.Table_number,
+			"qr_version":   table.Qr_version,
+			"qr_token":     qrToken,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateTableLayout sets the floor plan placement of a table
+func UpdateTableLayout(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	tableId := mux.Vars(r)["table_id"]
+
+	var layout models.Table
+	if err := json.NewDecoder(r.Body).Decode(&layout); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request payload"}`, http.StatusBadRequest)
+		return
+	}
+
+	if validationErr := validate.StructPartial(layout, "Shape"); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "Shape must be one of Square, Round or Rectangle"}`, http.StatusBadRequest)
+		return
+	}
+
+	updateObj := bson.D{}
+	if layout.Section != "" {
+		updateObj = append(updateObj, bson.E{Key: "section", Value: layout.Section})
+	}
+	if layout.Position_x != nil {
+		updateObj = append(updateObj, bson.E{Key: "position_x", Value: layout.Position_x})
+	}
+	if layout.Position_y != nil {
+		updateObj = append(updateObj, bson.E{Key: "position_y", Value: layout.Position_y})
+	}
+	if layout.Shape != "" {
+		updateObj = append(updateObj, bson.E{Key: "shape", Value: layout.Shape})
+	}
+	if layout.Number_of_guests != nil {
+		updateObj = append(updateObj, bson.E{Key: "number_of_guests", Value: layout.Number_of_guests})
+	}
+	updateObj = append(updateObj, bson.E{Key: "updated_at", Value: time.Now()})
+
+	var updatedTable models.Table
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := tableCollection.FindOneAndUpdate(ctx, bson.M{"table_id": tableId}, bson.D{{Key: "$set", Value: updateObj}}, opts).Decode(&updatedTable)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Table not found"}`, http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Table layout updated successfully",
+		"data":    tableLayoutData(updatedTable, "", updatedTable.Server_id),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetFloorPlan returns every table with its layout, status, merge state and open order
+func GetFloorPlan(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := outletScope(r, bson.M{"table_number": bson.M{"$ne": nil}})
+	if section := r.URL.Query().Get("section"); section != "" {
+		filter["section"] = section
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "section", Value: 1}, {Key: "table_number", Value: 1}})
+	cursor, err := tableCollection.Find(ctx, filter, opts)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving tables"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var tables []models.Table
+	if err = cursor.All(ctx, &tables); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding table data"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Map each table to its open order, if any
+	tableIds := make([]string, 0, len(tables))
+	for _, table := range tables {
+		tableIds = append(tableIds, table.Table_id)
+	}
+	orderCursor, err := orderCollection.Find(ctx, bson.M{"table_id": bson.M{"$in": tableIds}, "status": bson.M{"$nin": closedOrderStatuses}})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving open orders"}`, http.StatusInternalServerError)
+		return
+	}
+	defer orderCursor.Close(ctx)
+
+	var openOrders []models.Order
+	if err = orderCursor.All(ctx, &openOrders); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding order data"}`, http.StatusInternalServerError)
+		return
+	}
+	openOrderByTable := make(map[string]string)
+	for _, order := range openOrders {
+		if order.Table_id != nil {
+			openOrderByTable[*order.Table_id] = order.Order_id
+		}
+	}
+
+	// A table's own server, else its section's
+	servers, err := sectionServers(ctx, r)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving section servers"}`, http.StatusInternalServerError)
+		return
+	}
+
+	sections := make(map[string][]map[string]interface{})
+	for _, table := range tables {
+		serverId := table.Server_id
+		if serverId == "" {
+			serverId = servers[sectionKey(table.Outlet_id, table.Section)]
+		}
+		sections[table.Section] = append(sections[table.Section], tableLayoutData(table, openOrderByTable[table.Table_id], serverId))
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Floor plan retrieved successfully",
+		"data":    sections,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// MergeTables joins secondary tables onto a primary table for a large party
+func MergeTables(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var requestBody struct {
+		Primary_table_id string   `json:"primary_table_id"`
+		Table_ids        []string `json:"table_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Primary_table_id == "" || len(requestBody.Table_ids) == 0 {
+		http.Error(w, `{"success": false, "message": "primary_table_id and table_ids are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	var primary models.Table
+	if err := tableCollection.FindOne(ctx, outletScope(r, bson.M{"table_id": requestBody.Primary_table_id})).Decode(&primary); err != nil {
+		http.Error(w, `{"success": false, "message": "Primary table not found"}`, http.StatusNotFound)
+		return
+	}
+	if primary.Merged_into != "" {
+		http.Error(w, `{"success": false, "message": "Primary table is itself merged into another table"}`, http.StatusConflict)
+		return
+	}
+
+	seats := 0
+	if primary.Number_of_guests != nil {
+		seats = *primary.Number_of_guests
+	}
+
+	seen := map[string]bool{}
+	for _, tableId := range requestBody.Table_ids {
+		if tableId == primary.Table_id {
+			http.Error(w, `{"success": false, "message": "A table cannot be merged into itself"}`, http.StatusBadRequest)
+			return
+		}
+		if seen[tableId] {
+			http.Error(w, `{"success": false, "message": "Table `+tableId+` is listed twice"}`, http.StatusBadRequest)
+			return
+		}
+		seen[tableId] = true
+
+		// Only tables of the primary table's outlet can be merged into it
+		var table models.Table
+		if err := tableCollection.FindOne(ctx, sameOutlet(bson.M{"table_id": tableId}, primary.Outlet_id)).Decode(&table); err != nil {
+			http.Error(w, `{"success": false, "message": "Table `+tableId+` not found"}`, http.StatusNotFound)
+			return
+		}
+		if table.Merged_into != "" || len(table.Merged_tables) > 0 {
+			http.Error(w, `{"success": false, "message": "Table `+tableId+` is already part of a merge"}`, http.StatusConflict)
+			return
+		}
+		if _, err := findOpenOrder(ctx, tableId); err == nil {
+			http.Error(w, `{"success": false, "message": "Table `+tableId+` has an open order, transfer it first"}`, http.StatusConflict)
+			return
+		} else if err != mongo.ErrNoDocuments {
+			http.Error(w, `{"success": false, "message": "Error checking open orders"}`, http.StatusInternalServerError)
+			return
+		}
+		if table.Number_of_guests != nil {
+			seats += *table.Number_of_guests
+		}
+	}
+
+	session, err := database.Client.StartSession()
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to merge tables"}`, http.StatusInternalServerError)
+		return
+	}
+	defer session.EndSession(ctx)
+
+	// Secondaries and primary are updated together; a table merged elsewhere
+	// since the checks above aborts the whole merge
+	now := time.Now()
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		result, err := tableCollection.UpdateMany(sessCtx,
+			bson.M{
+				"table_id":      bson.M{"$in": requestBody.Table_ids},
+				"merged_into":   bson.M{"$in": []interface{}{nil, ""}},
+				"merged_tables": bson.M{"$in": []interface{}{nil, bson.A{}}},
+			},
+			bson.M{"$set": bson.M{"merged_into": primary.Table_id, "status": primary.Status, "updated_at": now}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount != int64(len(requestBody.Table_ids)) {
+			return nil, errTableAlreadyMerged
+		}
+
+		result, err = tableCollection.UpdateOne(sessCtx,
+			bson.M{"table_id": primary.Table_id, "merged_into": bson.M{"$in": []interface{}{nil, ""}}},
+			bson.M{"$addToSet": bson.M{"merged_tables": bson.M{"$each": requestBody.Table_ids}}, "$set": bson.M{"updated_at": now}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 0 {
+			return nil, errTableAlreadyMerged
+		}
+		return nil, nil
+	})
+	if err == errTableAlreadyMerged {
+		http.Error(w, `{"success": false, "message": "Tables changed while merging, try again"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to merge tables"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Tables merged successfully",
+		"data": map[string]interface{}{
+			"primary_table_id": primary.Table_id,
+			"merged_tables":    requestBody.Table_ids,
+			"total_seats":      seats,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SplitTable detaches secondary tables from a primary table, all of them when table_ids is empty
+func SplitTable(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	tableId := mux.Vars(r)["table_id"]
+
+	var requestBody struct {
+		Table_ids []string `json:"table_ids"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid request payload"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var primary models.Table
+	if err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&primary); err != nil {
+		http.Error(w, `{"success": false, "message": "Table not found"}`, http.StatusNotFound)
+		return
+	}
+	if len(primary.Merged_tables) == 0 {
+		http.Error(w, `{"success": false, "message": "Table has no merged tables"}`, http.StatusBadRequest)
+		return
+	}
+
+	splitIds := requestBody.Table_ids
+	if len(splitIds) == 0 {
+		splitIds = primary.Merged_tables
+	}
+
+	// Split tables that were part of a seated party need cleaning before reuse
+	splitStatus := tableAvailable
+	for _, status := range tableOccupiedStatuses {
+		if primary.Status == status {
+			splitStatus = tableNeedsCleaning
+		}
+	}
+
+	session, err := database.Client.StartSession()
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to split tables"}`, http.StatusInternalServerError)
+		return
+	}
+	defer session.EndSession(ctx)
+
+	// Secondaries and primary are updated together, like a merge
+	now := time.Now()
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		_, err := tableCollection.UpdateMany(sessCtx,
+			bson.M{"table_id": bson.M{"$in": splitIds}, "merged_into": primary.Table_id},
+			bson.M{"$unset": bson.M{"merged_into": ""}, "$set": bson.M{"status": splitStatus, "status_changed_at": now, "updated_at": now}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return tableCollection.UpdateOne(sessCtx,
+			bson.M{"table_id": primary.Table_id},
+			bson.M{"$pull": bson.M{"merged_tables": bson.M{"$in": splitIds}}, "$set": bson.M{"updated_at": now}},
+		)
+	})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to split tables"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Tables split successfully",
+		"data": map[string]interface{}{
+			"primary_table_id": primary.Table_id,
+			"split_tables":     splitIds,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func tableLayoutData(table models.Table, openOrderId, serverId string) map[string]interface{} {
+	return map[string]interface{}{
+		"table_id":      table.Table_id,
+		"table_number":  table.Table_number,
+		"seats":         table.Number_of_guests,
+		"status":        table.Status,
+		"section":       table.Section,
+		"position_x":    table.Position_x,
+		"position_y":    table.Position_y,
+		"shape":         table.Shape,
+		"merged_into":   table.Merged_into,
+		"merged_tables": table.Merged_tables,
+		"open_order_id": openOrderId,
+		"server_id":     serverId,
+	}
+}
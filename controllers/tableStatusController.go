@@ -0,0 +1,412 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var tableTurnCollection *mongo.Collection = database.OpenCollection(database.Client, "table_turn")
+
+// Table lifecycle statuses
+const (
+	tableAvailable      = "Available"
+	tableReserved       = "Reserved"
+	tableSeated         = "Seated"
+	tableOrdering       = "Ordering"
+	tableWaitingForBill = "Waiting For Bill"
+	tableNeedsCleaning  = "Needs Cleaning"
+	tableOutOfService   = "Out Of Service"
+)
+
+// Statuses in which guests are sitting at the table
+var tableOccupiedStatuses = []string{tableSeated, tableOrdering, tableWaitingForBill}
+
+// Filter for tables free to seat a party right now, including the legacy "Not Reserved" status
+var availableTableFilter = bson.M{
+	"status":      bson.M{"$in": []string{tableAvailable, "Not Reserved"}},
+	"merged_into": bson.M{"$exists": false},
+}
+
+type tableTransition struct {
+	from []string
+	to   string
+}
+
+// tableTransitions drives the table lifecycle; order and invoice events fire these automatically
+var tableTransitions = map[string]tableTransition{
+	"reserve":           {from: []string{tableAvailable}, to: tableReserved},
+	"unreserve":         {from: []string{tableReserved}, to: tableAvailable},
+	"seat":              {from: []string{tableAvailable, tableReserved}, to: tableSeated},
+	"order":             {from: []string{tableSeated}, to: tableOrdering},
+	"request_bill":      {from: []string{tableSeated, tableOrdering}, to: tableWaitingForBill},
+	"pay":               {from: tableOccupiedStatuses, to: tableNeedsCleaning},
+	"cancel":            {from: tableOccupiedStatuses, to: tableNeedsCleaning},
+	"clean":             {from: []string{tableNeedsCleaning}, to: tableAvailable},
+	"out_of_service":    {from: []string{tableAvailable, tableNeedsCleaning}, to: tableOutOfService},
+	"return_to_service": {from: []string{tableOutOfService}, to: tableAvailable},
+}
+
+// Events staff may trigger by hand through UpdateTableStatus, keyed by target status
+var manualTableEvents = map[string]string{
+	tableAvailable:    "clean",
+	tableOutOfService: "out_of_service",
+}
+
+// normalizeTableStatus maps the legacy "Not Reserved" status onto Available
+func normalizeTableStatus(status string) string {
+	if status == "" || status == "Not Reserved" {
+		return tableAvailable
+	}
+	return status
+}
+
+// nextTableStatus returns the status an event moves a table to, or false if it does not apply
+func nextTableStatus(status, event string) (string, bool) {
+	transition, ok := tableTransitions[event]
+	if !ok {
+		return "", false
+	}
+	status = normalizeTableStatus(status)
+	for _, from := range transition.from {
+		if from == status {
+			return transition.to, true
+		}
+	}
+	return "", false
+}
+
+// advanceTableStatus applies a lifecycle event to a table and the tables merged into it.
+// It returns false without an error when the event does not apply to the table's current status.
+func advanceTableStatus(ctx context.Context, tableId, event, orderId string) (bool, error) {
+	var table models.Table
+	if err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table); err != nil {
+		return false, err
+	}
+
+	next, ok := nextTableStatus(table.Status, event)
+	if !ok {
+		return false, nil
+	}
+
+	now := time.Now()
+	set := bson.M{"status": next, "status_changed_at": now, "updated_at": now}
+	update := bson.M{"$set": set}
+
+	turnEnded := false
+	switch next {
+	case tableSeated:
+		set["seated_at"] = now
+	case tableNeedsCleaning, tableAvailable:
+		if table.Seated_at != nil {
+			turnEnded = true
+			update["$unset"] = bson.M{"seated_at": ""}
+		}
+	}
+
+	// Only apply if nobody changed the status in the meantime
+	result, err := tableCollection.UpdateOne(ctx, bson.M{"table_id": tableId, "status": table.Status}, update)
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	// The turn is only recorded by the caller whose update won
+	if turnEnded {
+		if err := recordTableTurn(ctx, table, orderId, now); err != nil {
+			return true, err
+		}
+	}
+
+	_, err = tableCollection.UpdateMany(ctx,
+		bson.M{"merged_into": tableId},
+		bson.M{"$set": bson.M{"status": next, "status_changed_at": now, "updated_at": now}},
+	)
+	return true, err
+}
+
+// advanceOrderTableStatus applies a lifecycle event to the table an order is on
+func advanceOrderTableStatus(ctx context.Context, orderId, event string) error {
+	var order models.Order
+	if err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order); err != nil {
+		return err
+	}
+	if order.Table_id == nil {
+		return nil
+	}
+	_, err := advanceTableStatus(ctx, *order.Table_id, event, orderId)
+	return err
+}
+
+// moveTableOccupancy hands the seated party of one table over to another.
+// The new table takes over the status and seating time, the old one needs cleaning.
+func moveTableOccupancy(ctx context.Context, fromTableId, toTableId string) error {
+	var from models.Table
+	if err := tableCollection.FindOne(ctx, bson.M{"table_id": fromTableId}).Decode(&from); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	status := normalizeTableStatus(from.Status)
+	occupied := false
+	for _, s := range tableOccupiedStatuses {
+		if s == status {
+			occupied = true
+		}
+	}
+	if !occupied {
+		status = tableSeated
+	}
+	seatedAt := now
+	if from.Seated_at != nil {
+		seatedAt = *from.Seated_at
+	}
+
+	_, err := tableCollection.UpdateOne(ctx,
+		bson.M{"table_id": toTableId},
+		bson.M{"$set": bson.M{"status": status, "seated_at": seatedAt, "status_changed_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tableCollection.UpdateOne(ctx,
+		bson.M{"table_id": fromTableId},
+		bson.M{"$set": bson.M{"status": tableNeedsCleaning, "status_changed_at": now, "updated_at": now}, "$unset": bson.M{"seated_at": ""}},
+	)
+	return err
+}
+
+func recordTableTurn(ctx context.Context, table models.Table, orderId string, clearedAt time.Time) error {
+	turn := models.TableTurn{
+		ID:           primitive.NewObjectID(),
+		Table_id:     table.Table_id,
+		Order_id:     orderId,
+		Seated_at:    *table.Seated_at,
+		Cleared_at:   clearedAt,
+		Turn_minutes: clearedAt.Sub(*table.Seated_at).Minutes(),
+		Created_at:   time.Now(),
+	}
+	turn.Turn_id = turn.ID.Hex()
+
+	_, err := tableTurnCollection.InsertOne(ctx, turn)
+	return err
+}
+
+// UpdateTableStatus lets staff mark a table cleaned or out of service
+func UpdateTableStatus(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	tableId := mux.Vars(r)["table_id"]
+
+	var requestBody struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	event, ok := manualTableEvents[requestBody.Status]
+	if !ok {
+		http.Error(w, `{"success": false, "message": "Status can only be set to Available or Out Of Service, other statuses follow orders and invoices"}`, http.StatusBadRequest)
+		return
+	}
+
+	var table models.Table
+	if err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table); err != nil {
+		http.Error(w, `{"success": false, "message": "Table not found"}`, http.StatusNotFound)
+		return
+	}
+
+	// Bringing a table back from Out Of Service uses its own transition
+	if normalizeTableStatus(table.Status) == tableOutOfService && requestBody.Status == tableAvailable {
+		event = "return_to_service"
+	}
+
+	changed, err := advanceTableStatus(ctx, tableId, event, "")
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to update table status"}`, http.StatusInternalServerError)
+		return
+	}
+	if !changed {
+		http.Error(w, `{"success": false, "message": "Table cannot move from `+normalizeTableStatus(table.Status)+` to `+requestBody.Status+`"}`, http.StatusConflict)
+		return
+	}
+
+	if err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table); err != nil {
+		http.Error(w, `{"success": false, "message": "Error fetching updated table"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Table status updated successfully",
+		"data": map[string]interface{}{
+			"table_id":          table.Table_id,
+			"table_number":      table.Table_number,
+			"status":            table.Status,
+			"status_changed_at": table.Status_changed_at,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetTableTurns returns the recent turns of a table with the average turn time
+func GetTableTurns(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	tableId := mux.Vars(r)["table_id"]
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "cleared_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := tableTurnCollection.Find(ctx, bson.M{"table_id": tableId}, opts)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving table turns"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var turns []models.TableTurn
+	if err = cursor.All(ctx, &turns); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding table turns"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var totalMinutes float64
+	for _, turn := range turns {
+		totalMinutes += turn.Turn_minutes
+	}
+	averageMinutes := 0.0
+	if len(turns) > 0 {
+		averageMinutes = totalMinutes / float64(len(turns))
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Table turns retrieved successfully",
+		"data": map[string]interface{}{
+			"table_id":             tableId,
+			"turns":                turns,
+			"average_turn_minutes": averageMinutes,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetTableTurnSummary returns turn counts and average turn time per table of the caller's outlet
+func GetTableTurnSummary(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	match := bson.M{}
+	if from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from")); err == nil {
+		match["cleared_at"] = bson.M{"$gte": from}
+	}
+
+	// Turns recorded before outlets take theirs from the table
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "table"},
+			{Key: "localField", Value: "table_id"},
+			{Key: "foreignField", Value: "table_id"},
+			{Key: "as", Value: "table"},
+		}}},
+		{{Key: "$match", Value: outletScopeField(r, bson.M{}, "table.outlet_id")}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$table_id"},
+			{Key: "turns", Value: bson.M{"$sum": 1}},
+			{Key: "average_turn_minutes", Value: bson.M{"$avg": "$turn_minutes"}},
+			{Key: "last_cleared_at", Value: bson.M{"$max": "$cleared_at"}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "table_id", Value: "$_id"},
+			{Key: "turns", Value: 1},
+			{Key: "average_turn_minutes", Value: 1},
+			{Key: "last_cleared_at", Value: 1},
+		}}},
+		{{Key: "$sort", Value: bson.M{"table_id": 1}}},
+	}
+
+	cursor, err := tableTurnCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving table turns"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var summary []bson.M
+	if err = cursor.All(ctx, &summary); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding table turns"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Table turn summary retrieved successfully",
+		"data":    summary,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// averageTurnMinutes averages the outlet's most recent turns, falling back when there is no history yet
+func averageTurnMinutes(ctx context.Context, outletId string, sample int64, fallback float64) float64 {
+	// Turns belong to the outlet of their table, so only the latest turns of its tables are read
+	tableIds, err := tableCollection.Distinct(ctx, "table_id", sameOutlet(bson.M{}, outletId))
+	if err != nil || len(tableIds) == 0 {
+		return fallback
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "cleared_at", Value: -1}}).SetLimit(sample)
+	cursor, err := tableTurnCollection.Find(ctx, bson.M{"table_id": bson.M{"$in": tableIds}}, opts)
+	if err != nil {
+		return fallback
+	}
+	defer cursor.Close(ctx)
+
+	var turns []models.TableTurn
+	if err = cursor.All(ctx, &turns); err != nil || len(turns) == 0 {
+		return fallback
+	}
+
+	var total float64
+	for _, turn := range turns {
+		total += turn.Turn_minutes
+	}
+	return total / float64(len(turns))
+}
+
+// ignoreNoDocuments treats a missing order or table as nothing to update
+func ignoreNoDocuments(err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	return err
+}
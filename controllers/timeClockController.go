@@ -0,0 +1,602 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var timeEntryCollection *mongo.Collection = database.OpenCollection(database.Client, "time_entry")
+
+// Time entry statuses
+const (
+	clockOpen   = "OPEN"
+	clockClosed = "CLOSED"
+)
+
+// How early staff can clock in for a scheduled shift and still have the time counted against it
+const shiftClockInGrace = time.Hour
+
+// An open break has no end yet
+var openBreak = bson.M{"end": bson.M{"$exists": false}}
+
+func roundMinutes(minutes float64) float64 {
+	return math.Round(minutes*100) / 100
+}
+
+// entryMinutes works out the minutes worked and spent on breaks between clock-in and until.
+// Breaks are clipped to the entry, and one still running ends at until.
+func entryMinutes(entry models.TimeEntry, until time.Time) (float64, float64) {
+	var breaks time.Duration
+	for _, b := range entry.Breaks {
+		start, end := b.Start, until
+		if b.End != nil && b.End.Before(until) {
+			end = *b.End
+		}
+		if start.Before(entry.Clock_in) {
+			start = entry.Clock_in
+		}
+		if end.After(start) {
+			breaks += end.Sub(start)
+		}
+	}
+	worked := until.Sub(entry.Clock_in) - breaks
+	if worked < 0 {
+		worked = 0
+	}
+	return roundMinutes(worked.Minutes()), roundMinutes(breaks.Minutes())
+}
+
+// clockStaff reads the employee code and PIN punched in at the outlet and returns the employee.
+// It answers the request itself and returns false when they do not match or the employee is locked out.
+func clockStaff(ctx context.Context, w http.ResponseWriter, r *http.Request) (models.Staff, bool) {
+	var requestBody struct {
+		Employee_code string `json:"employee_code"`
+		Pin           string `json:"pin"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Employee_code == "" || requestBody.Pin == "" {
+		http.Error(w, `{"success": false, "message": "employee_code and pin are required"}`, http.StatusBadRequest)
+		return models.Staff{}, false
+	}
+
+	var staff models.Staff
+	code := strings.ToUpper(strings.TrimSpace(requestBody.Employee_code))
+	err := staffCollection.FindOne(ctx, activeFilter(outletScope(r, bson.M{"employee_code": code}))).Decode(&staff)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Error retrieving staff member"}`, http.StatusInternalServerError)
+		return models.Staff{}, false
+	}
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid employee code or PIN"}`, http.StatusUnauthorized)
+		return models.Staff{}, false
+	}
+	msg, status, err := verifyStaffPin(ctx, staff, requestBody.Pin)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking PIN"}`, http.StatusInternalServerError)
+		return models.Staff{}, false
+	}
+	if msg != "" {
+		http.Error(w, `{"success": false, "message": "`+msg+`"}`, status)
+		return models.Staff{}, false
+	}
+	staff.Pin = ""
+	return staff, true
+}
+
+// ClockIn starts a time entry for the employee whose code and PIN are given, against the shift
+// they are scheduled for if there is one
+func ClockIn(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	staff, ok := clockStaff(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	count, err := timeEntryCollection.CountDocuments(ctx, bson.M{"staff_id": staff.Staff_id, "status": clockOpen})
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking time clock"}`, http.StatusInternalServerError)
+		return
+	}
+	if count > 0 {
+		http.Error(w, `{"success": false, "message": "Already clocked in"}`, http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	entry := models.TimeEntry{
+		ID:            primitive.NewObjectID(),
+		Staff_id:      staff.Staff_id,
+		Employee_code: staff.Employee_code,
+		Outlet_id:     staff.Outlet_id,
+		Status:        clockOpen,
+		Clock_in:      now,
+		Breaks:        []models.Break{},
+		Hourly_rate:   staff.Hourly_rate,
+		Updated_at:    now,
+	}
+	entry.Time_entry_id = entry.ID.Hex()
+
+	var shift models.Shift
+	shiftFilter := bson.M{
+		"staff_id": staff.Staff_id,
+		"start":    bson.M{"$lte": now.Add(shiftClockInGrace)},
+		"end":      bson.M{"$gt": now},
+	}
+	err = shiftCollection.FindOne(ctx, shiftFilter, options.FindOne().SetSort(bson.D{{Key: "start", Value: 1}})).Decode(&shift)
+	if err == nil {
+		entry.Shift_id = shift.Shift_id
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Error checking schedule"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// The index on open entries turns away a second clock-in racing past the check above
+	if _, err := timeEntryCollection.InsertOne(ctx, entry); mongo.IsDuplicateKeyError(err) {
+		http.Error(w, `{"success": false, "message": "Already clocked in"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to clock in"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Clocked in successfully",
+		"data":    entry,
+	})
+}
+
+// ClockOut closes the employee's open time entry, ending a break still running
+func ClockOut(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	staff, ok := clockStaff(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	var entry models.TimeEntry
+	err := timeEntryCollection.FindOne(ctx, bson.M{"staff_id": staff.Staff_id, "status": clockOpen}).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Not clocked in"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving time entry"}`, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	for i := range entry.Breaks {
+		if entry.Breaks[i].End == nil {
+			entry.Breaks[i].End = &now
+		}
+	}
+	entry.Status = clockClosed
+	entry.Clock_out = &now
+	entry.Worked_minutes, entry.Break_minutes = entryMinutes(entry, now)
+	entry.Updated_at = now
+
+	// Guarded on the breaks so a break started or ended meanwhile is not overwritten
+	filter := bson.M{"time_entry_id": entry.Time_entry_id, "status": clockOpen, "breaks": bson.M{"$size": len(entry.Breaks)}}
+	update := bson.M{"$set": bson.M{
+		"status":         entry.Status,
+		"clock_out":      entry.Clock_out,
+		"breaks":         entry.Breaks,
+		"worked_minutes": entry.Worked_minutes,
+		"break_minutes":  entry.Break_minutes,
+		"updated_at":     entry.Updated_at,
+	}}
+	result, err := timeEntryCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to clock out"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"success": false, "message": "The time entry changed while clocking out; try again"}`, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Clocked out successfully",
+		"data":    entry,
+	})
+}
+
+// StartBreak starts an unpaid break in the employee's open time entry
+func StartBreak(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	staff, ok := clockStaff(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		"staff_id": staff.Staff_id,
+		"status":   clockOpen,
+		"breaks":   bson.M{"$not": bson.M{"$elemMatch": openBreak}},
+	}
+	update := bson.M{
+		"$push": bson.M{"breaks": models.Break{Start: now}},
+		"$set":  bson.M{"updated_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var entry models.TimeEntry
+	err := timeEntryCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Not clocked in, or already on a break"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to start break"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Break started successfully",
+		"data":    entry,
+	})
+}
+
+// EndBreak ends the break the employee is on
+func EndBreak(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	staff, ok := clockStaff(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		"staff_id": staff.Staff_id,
+		"status":   clockOpen,
+		"breaks":   bson.M{"$elemMatch": openBreak},
+	}
+	update := bson.M{"$set": bson.M{"breaks.$[open].end": now, "updated_at": now}}
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"open.end": bson.M{"$exists": false}}}})
+	var entry models.TimeEntry
+	err := timeEntryCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Not on a break"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to end break"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Break ended successfully",
+		"data":    entry,
+	})
+}
+
+// GetTimeEntries lists the outlet's time entries clocked in between ?from and ?to (default the last
+// 30 days), optionally for one ?staff_id or ?status=OPEN|CLOSED
+func GetTimeEntries(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	from, to, msg := reportRange(r)
+	if msg != "" {
+		http.Error(w, `{"success": false, "message": "`+msg+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	filter := outletScope(r, bson.M{"clock_in": bson.M{"$gte": from, "$lt": to}})
+	if staffId := r.URL.Query().Get("staff_id"); staffId != "" {
+		filter["staff_id"] = staffId
+	}
+	if status := strings.ToUpper(r.URL.Query().Get("status")); status != "" {
+		if status != clockOpen && status != clockClosed {
+			http.Error(w, `{"success": false, "message": "status must be OPEN or CLOSED"}`, http.StatusBadRequest)
+			return
+		}
+		filter["status"] = status
+	}
+
+	cursor, err := timeEntryCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "clock_in", Value: -1}}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving time entries"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.TimeEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding time entries"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Time entries retrieved successfully",
+		"data":    entries,
+	})
+}
+
+// AdjustTimeEntry lets a manager correct the clock-in or clock-out of an entry, with a reason.
+// Setting the clock-out of an open entry closes it, for staff who forgot to clock out.
+func AdjustTimeEntry(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	entryId := mux.Vars(r)["time_entry_id"]
+	var requestBody struct {
+		Clock_in  *time.Time `json:"clock_in"`
+		Clock_out *time.Time `json:"clock_out"`
+		Reason    string     `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if requestBody.Clock_in == nil && requestBody.Clock_out == nil {
+		http.Error(w, `{"success": false, "message": "clock_in or clock_out is required"}`, http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(requestBody.Reason)
+	if reason == "" {
+		http.Error(w, `{"success": false, "message": "A reason is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	var entry models.TimeEntry
+	err := timeEntryCollection.FindOne(ctx, bson.M{"time_entry_id": entryId}).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Time entry not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving time entry"}`, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	if requestBody.Clock_in != nil {
+		entry.Clock_in = *requestBody.Clock_in
+	}
+	if requestBody.Clock_out != nil {
+		entry.Clock_out = requestBody.Clock_out
+	}
+	if entry.Clock_out != nil {
+		if !entry.Clock_out.After(entry.Clock_in) || entry.Clock_out.After(now) {
+			http.Error(w, `{"success": false, "message": "clock_out must be after clock_in and not in the future"}`, http.StatusBadRequest)
+			return
+		}
+		for i := range entry.Breaks {
+			if entry.Breaks[i].End == nil {
+				entry.Breaks[i].End = entry.Clock_out
+			}
+		}
+		entry.Status = clockClosed
+		entry.Worked_minutes, entry.Break_minutes = entryMinutes(entry, *entry.Clock_out)
+	} else if entry.Clock_in.After(now) {
+		http.Error(w, `{"success": false, "message": "clock_in cannot be in the future"}`, http.StatusBadRequest)
+		return
+	}
+
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	entry.Adjusted_by = uid
+	entry.Adjust_reason = reason
+	entry.Updated_at = now
+
+	filter := bson.M{"time_entry_id": entryId, "breaks": bson.M{"$size": len(entry.Breaks)}}
+	update := bson.M{"$set": bson.M{
+		"status":         entry.Status,
+		"clock_in":       entry.Clock_in,
+		"clock_out":      entry.Clock_out,
+		"breaks":         entry.Breaks,
+		"worked_minutes": entry.Worked_minutes,
+		"break_minutes":  entry.Break_minutes,
+		"adjusted_by":    entry.Adjusted_by,
+		"adjust_reason":  entry.Adjust_reason,
+		"updated_at":     entry.Updated_at,
+	}}
+	result, err := timeEntryCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Time entry update failed"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"success": false, "message": "The time entry changed meanwhile; try again"}`, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Time entry updated successfully",
+		"data":    entry,
+	})
+}
+
+type timesheet struct {
+	Staff_id         string             `json:"staff_id"`
+	Employee_code    string             `json:"employee_code"`
+	Name             string             `json:"name"`
+	Role             string             `json:"role"`
+	Hourly_rate      float64            `json:"hourly_rate"`
+	Shifts_scheduled int                `json:"shifts_scheduled"`
+	Scheduled_hours  float64            `json:"scheduled_hours"`
+	Worked_hours     float64            `json:"worked_hours"`
+	Break_hours      float64            `json:"break_hours"`
+	Gross_pay        float64            `json:"gross_pay"`
+	Open_entries     int                `json:"open_entries"` // still clocked in, not paid until closed
+	Entries          []models.TimeEntry `json:"entries"`
+}
+
+// GetTimesheets totals the hours and pay of the outlet's staff over the pay period containing ?date
+// (YYYY-MM-DD, default today), optionally for one ?staff_id. Time is counted in the period it was
+// clocked in, at the rate of the time. With ?format=csv|xlsx it is the payroll export.
+func GetTimesheets(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	day := time.Now()
+	if date := r.URL.Query().Get("date"); date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid date, expected YYYY-MM-DD"}`, http.StatusBadRequest)
+			return
+		}
+		day = parsed
+	}
+	start, end := helper.PayPeriodBounds(day)
+
+	filter := outletScope(r, bson.M{})
+	if staffId := r.URL.Query().Get("staff_id"); staffId != "" {
+		filter["staff_id"] = staffId
+	}
+
+	// Staff who have left are still paid for the period, so deleted profiles are included
+	staffCursor, err := staffCollection.Find(ctx, filter, options.Find().SetProjection(staffProjection))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving staff"}`, http.StatusInternalServerError)
+		return
+	}
+	var staff []models.Staff
+	if err := staffCursor.All(ctx, &staff); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding staff"}`, http.StatusInternalServerError)
+		return
+	}
+	sheets := map[string]*timesheet{}
+	for _, member := range staff {
+		sheets[member.Staff_id] = &timesheet{
+			Staff_id:      member.Staff_id,
+			Employee_code: member.Employee_code,
+			Name:          strings.TrimSpace(member.First_name + " " + member.Last_name),
+			Role:          member.Role,
+			Hourly_rate:   member.Hourly_rate,
+			Entries:       []models.TimeEntry{},
+		}
+	}
+
+	entryFilter := bson.M{"clock_in": bson.M{"$gte": start, "$lt": end}}
+	shiftFilter := bson.M{"start": bson.M{"$gte": start, "$lt": end}}
+	for key, value := range filter {
+		entryFilter[key] = value
+		shiftFilter[key] = value
+	}
+
+	entryCursor, err := timeEntryCollection.Find(ctx, entryFilter, options.Find().SetSort(bson.D{{Key: "clock_in", Value: 1}}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving time entries"}`, http.StatusInternalServerError)
+		return
+	}
+	var entries []models.TimeEntry
+	if err := entryCursor.All(ctx, &entries); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding time entries"}`, http.StatusInternalServerError)
+		return
+	}
+	for _, entry := range entries {
+		sheet, found := sheets[entry.Staff_id]
+		if !found {
+			continue // moved to an outlet out of the caller's view
+		}
+		sheet.Entries = append(sheet.Entries, entry)
+		if entry.Status == clockOpen {
+			sheet.Open_entries++
+			continue
+		}
+		sheet.Worked_hours += entry.Worked_minutes / 60
+		sheet.Break_hours += entry.Break_minutes / 60
+		sheet.Gross_pay += entry.Worked_minutes / 60 * entry.Hourly_rate
+	}
+
+	shiftCursor, err := shiftCollection.Find(ctx, shiftFilter)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving shifts"}`, http.StatusInternalServerError)
+		return
+	}
+	var shifts []models.Shift
+	if err := shiftCursor.All(ctx, &shifts); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding shifts"}`, http.StatusInternalServerError)
+		return
+	}
+	for _, shift := range shifts {
+		if sheet, found := sheets[shift.Staff_id]; found {
+			sheet.Shifts_scheduled++
+			sheet.Scheduled_hours += shift.End.Sub(shift.Start).Hours()
+		}
+	}
+
+	// Only staff with time scheduled or worked in the period
+	result := []timesheet{}
+	for _, sheet := range sheets {
+		if len(sheet.Entries) == 0 && sheet.Shifts_scheduled == 0 {
+			continue
+		}
+		sheet.Scheduled_hours = helper.RoundMoney(sheet.Scheduled_hours)
+		sheet.Worked_hours = helper.RoundMoney(sheet.Worked_hours)
+		sheet.Break_hours = helper.RoundMoney(sheet.Break_hours)
+		sheet.Gross_pay = helper.RoundMoney(sheet.Gross_pay)
+		result = append(result, *sheet)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Employee_code < result[j].Employee_code })
+
+	periodEnd := end.AddDate(0, 0, -1)
+	if format != "" {
+		header := []interface{}{
+			"period_start", "period_end", "employee_code", "name", "role", "hourly_rate",
+			"shifts_scheduled", "scheduled_hours", "worked_hours", "break_hours", "gross_pay", "open_entries",
+		}
+		var rows [][]interface{}
+		for _, sheet := range result {
+			rows = append(rows, []interface{}{
+				businessDate(start), businessDate(periodEnd), sheet.Employee_code, sheet.Name, sheet.Role, sheet.Hourly_rate,
+				sheet.Shifts_scheduled, sheet.Scheduled_hours, sheet.Worked_hours, sheet.Break_hours, sheet.Gross_pay, sheet.Open_entries,
+			})
+		}
+		writeExport(w, format, "payroll", header, rows)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Timesheets generated successfully",
+		"data": map[string]interface{}{
+			"pay_period":   helper.PayPeriod(),
+			"period_start": businessDate(start),
+			"period_end":   businessDate(periodEnd),
+			"timesheets":   result,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
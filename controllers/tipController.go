@@ -0,0 +1,259 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// resolveTip works out a tip given as a fixed amount or as a percentage of the subtotal.
+// It returns the amount, the percentage (0 for a fixed tip) and a message if the tip is unusable.
+func resolveTip(subtotal, amount, percent float64) (float64, float64, string) {
+	if amount < 0 || percent < 0 {
+		return 0, 0, "Tip cannot be negative"
+	}
+	if amount > 0 && percent > 0 {
+		return 0, 0, "Give the tip as tip_amount or tip_percent, not both"
+	}
+	if percent > 100 {
+		return 0, 0, "tip_percent cannot be above 100"
+	}
+	if percent > 0 {
+		amount = subtotal * percent / 100
+	}
+	return helper.RoundMoney(amount), percent, ""
+}
+
+// orderServerId returns the server an order's tips go to: its waiter, or the user who opened it
+func orderServerId(ctx context.Context, orderId *string) string {
+	if orderId == nil {
+		return ""
+	}
+	var order models.Order
+	if err := orderCollection.FindOne(ctx, bson.M{"order_id": *orderId}).Decode(&order); err != nil {
+		return ""
+	}
+	if order.Waiter_id != nil && *order.Waiter_id != "" {
+		return *order.Waiter_id
+	}
+	if order.User_id != nil {
+		return *order.User_id
+	}
+	return ""
+}
+
+// splitEvenly divides an amount into n shares that add up to it exactly, the odd cents going to the first shares
+func splitEvenly(amount float64, n int) []float64 {
+	shares := make([]float64, n)
+	if n == 0 {
+		return shares
+	}
+	cents := int64(math.Round(amount * 100))
+	base, remainder := cents/int64(n), cents%int64(n)
+	for i := range shares {
+		share := base
+		if int64(i) < remainder {
+			share++
+		}
+		shares[i] = float64(share) / 100
+	}
+	return shares
+}
+
+type tipPoolStaff struct {
+	User_id     string  `json:"user_id"`
+	Name        string  `json:"name"`
+	Orders      int64   `json:"orders"`
+	Tips_earned float64 `json:"tips_earned"` // tips on the orders this server handled
+	Pool_share  float64 `json:"pool_share"`  // what this server takes home from the pool
+}
+
+type tipPoolShift struct {
+	Shift           string         `json:"shift"`
+	Start           time.Time      `json:"start"`
+	End             time.Time      `json:"end"`
+	Tip_total       float64        `json:"tip_total"`
+	Tipped_invoices int64          `json:"tipped_invoices"`
+	Staff           []tipPoolStaff `json:"staff"`
+}
+
+// tipPoolForShift totals the tips paid at an outlet during a shift and splits them evenly across
+// everyone who served an order in it
+func tipPoolForShift(ctx context.Context, outletId string, shift helper.ShiftWindow, day time.Time) (tipPoolShift, error) {
+	start, end := shift.Bounds(day)
+	pool := tipPoolShift{Shift: shift.Name, Start: start, End: end, Staff: []tipPoolStaff{}}
+	staff := make(map[string]*tipPoolStaff)
+	member := func(userId string) *tipPoolStaff {
+		if staff[userId] == nil {
+			staff[userId] = &tipPoolStaff{User_id: userId}
+		}
+		return staff[userId]
+	}
+
+	// Tips paid during the shift, by the server they were left for
+	tipPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: activeFilter(sameOutlet(bson.M{
+			"payment_status": bson.M{"$in": bson.A{"PAID", invoicePartiallyRefunded}},
+			"tip_amount":     bson.M{"$gt": 0},
+			"payment_date":   bson.M{"$gte": start, "$lt": end},
+		}, outletId))}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$tip_server_id"},
+			{Key: "tips", Value: bson.D{{Key: "$sum", Value: "$tip_amount"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+	cursor, err := invoiceCollection.Aggregate(ctx, tipPipeline)
+	if err != nil {
+		return pool, err
+	}
+	var tipTotals []struct {
+		Server_id string  `bson:"_id"`
+		Tips      float64 `bson:"tips"`
+		Count     int64   `bson:"count"`
+	}
+	if err := cursor.All(ctx, &tipTotals); err != nil {
+		return pool, err
+	}
+	for _, total := range tipTotals {
+		pool.Tip_total += total.Tips
+		pool.Tipped_invoices += total.Count
+		if total.Server_id != "" {
+			member(total.Server_id).Tips_earned = helper.RoundMoney(total.Tips)
+		}
+	}
+	pool.Tip_total = helper.RoundMoney(pool.Tip_total)
+
+	// Everyone who served an order opened during the shift worked it
+	orderPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: activeFilter(sameOutlet(bson.M{"created_at": bson.M{"$gte": start, "$lt": end}}, outletId))}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$waiter_id", "$user_id"}}}},
+			{Key: "orders", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+	cursor, err = orderCollection.Aggregate(ctx, orderPipeline)
+	if err != nil {
+		return pool, err
+	}
+	var orderCounts []struct {
+		Server_id string `bson:"_id"`
+		Orders    int64  `bson:"orders"`
+	}
+	if err := cursor.All(ctx, &orderCounts); err != nil {
+		return pool, err
+	}
+	for _, count := range orderCounts {
+		if count.Server_id != "" {
+			member(count.Server_id).Orders = count.Orders
+		}
+	}
+
+	if len(staff) == 0 {
+		return pool, nil
+	}
+
+	// Put names to the staff
+	userIds := make([]string, 0, len(staff))
+	for userId := range staff {
+		userIds = append(userIds, userId)
+	}
+	cursor, err = userCollection.Find(ctx, bson.M{"user_id": bson.M{"$in": userIds}})
+	if err != nil {
+		return pool, err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return pool, err
+	}
+	for _, user := range users {
+		if user.First_name != nil && user.Last_name != nil {
+			staff[user.User_id].Name = strings.TrimSpace(*user.First_name + " " + *user.Last_name)
+		}
+	}
+
+	for _, server := range staff {
+		pool.Staff = append(pool.Staff, *server)
+	}
+	sort.Slice(pool.Staff, func(i, j int) bool {
+		if pool.Staff[i].Name != pool.Staff[j].Name {
+			return pool.Staff[i].Name < pool.Staff[j].Name
+		}
+		return pool.Staff[i].User_id < pool.Staff[j].User_id
+	})
+	for i, share := range splitEvenly(pool.Tip_total, len(pool.Staff)) {
+		pool.Staff[i].Pool_share = share
+	}
+	return pool, nil
+}
+
+// GetTipPoolReport reports the outlet's tip pool of each shift on a day (?date=YYYY-MM-DD, default today),
+// or of a single shift with ?shift=NAME. Shifts come from SHIFTS.
+func GetTipPoolReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	day := time.Now()
+	if date := r.URL.Query().Get("date"); date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid date, expected YYYY-MM-DD"}`, http.StatusBadRequest)
+			return
+		}
+		day = parsed
+	}
+	shiftName := strings.ToUpper(r.URL.Query().Get("shift"))
+
+	pools := []tipPoolShift{}
+	for _, shift := range helper.ShiftWindows() {
+		if shiftName != "" && shift.Name != shiftName {
+			continue
+		}
+		pool, err := tipPoolForShift(ctx, requestOutletId(r), shift, day)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Error building tip pool report"}`, http.StatusInternalServerError)
+			return
+		}
+		pools = append(pools, pool)
+	}
+	if shiftName != "" && len(pools) == 0 {
+		http.Error(w, `{"success": false, "message": "Unknown shift"}`, http.StatusBadRequest)
+		return
+	}
+
+	if format != "" {
+		header := []interface{}{"shift", "start", "end", "shift_tip_total", "user_id", "name", "orders", "tips_earned", "pool_share"}
+		var rows [][]interface{}
+		for _, pool := range pools {
+			for _, staff := range pool.Staff {
+				rows = append(rows, []interface{}{
+					pool.Shift, pool.Start, pool.End, pool.Tip_total, staff.User_id, staff.Name, staff.Orders, staff.Tips_earned, staff.Pool_share,
+				})
+			}
+		}
+		writeExport(w, format, "tip-pool", header, rows)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Tip pool report generated successfully",
+		"data":    pools,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
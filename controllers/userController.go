@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -13,10 +14,12 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 
 	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
 	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
 	"github.com/02priyeshraj/Hotel_Management_Backend/models"
 )
 
@@ -40,7 +43,7 @@ func GetUsers(w http.ResponseWriter, r *http.Request) {
 	startIndex := (page - 1) * recordPerPage
 
 	// MongoDB aggregation pipeline
-	matchStage := bson.D{{Key: "$match", Value: bson.D{}}}
+	matchStage := bson.D{{Key: "$match", Value: outletScope(r, bson.M{})}}
 	skipStage := bson.D{{Key: "$skip", Value: startIndex}}
 	limitStage := bson.D{{Key: "$limit", Value: int64(recordPerPage)}}
 	projectStage := bson.D{
@@ -51,6 +54,9 @@ func GetUsers(w http.ResponseWriter, r *http.Request) {
 			{Key: "last_name", Value: 1},
 			{Key: "user_id", Value: 1},
 			{Key: "phone", Value: 1},
+			{Key: "role", Value: 1},
+			{Key: "outlet_id", Value: 1},
+			{Key: "head_office", Value: 1},
 			{Key: "created_at", Value: 1},
 			{Key: "updated_at", Value: 1},
 		}},
@@ -117,6 +123,7 @@ func GetUser(w http.ResponseWriter, r *http.Request) {
 			"last_name":  user.Last_name,
 			"email":      user.Email,
 			"phone":      user.Phone,
+			"role":       user.Role,
 			"created_at": user.Created_at,
 			"updated_at": user.Updated_at,
 		},
@@ -152,6 +159,10 @@ func SignUp(w http.ResponseWriter, r *http.Request) {
 	password := HashPassword(*user.Password)
 	user.Password = &password
 
+	// Roles are granted by an admin, never through signup
+	role := "USER"
+	user.Role = &role
+
 	// Set user metadata
 	user.Created_at = time.Now()
 	user.Updated_at = time.Now()
@@ -159,7 +170,7 @@ func SignUp(w http.ResponseWriter, r *http.Request) {
 	user.User_id = user.ID.Hex()
 
 	// Generate authentication tokens
-	token, refreshToken, _ := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id)
+	token, refreshToken, _ := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id, "")
 	user.Token = &token
 	user.Refresh_Token = &refreshToken
 
@@ -219,7 +230,11 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate new tokens
-	token, refreshToken, _ := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id)
+	outletId := ""
+	if foundUser.Outlet_id != nil {
+		outletId = *foundUser.Outlet_id
+	}
+	token, refreshToken, _ := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id, outletId)
 	helper.UpdateAllTokens(token, refreshToken, foundUser.User_id)
 
 	// Update the foundUser object with tokens
@@ -236,6 +251,8 @@ func Login(w http.ResponseWriter, r *http.Request) {
 			"last_name":     foundUser.Last_name,
 			"email":         foundUser.Email,
 			"phone":         foundUser.Phone,
+			"outlet_id":     foundUser.Outlet_id,
+			"head_office":   foundUser.Head_office,
 			"token":         foundUser.Token,
 			"refresh_token": foundUser.Refresh_Token,
 			"created_at":    foundUser.Created_at,
@@ -272,6 +289,22 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// On a POS terminal only the terminal's session ends; the user's own login is left alone
+	if claims.TerminalId != "" {
+		filter := bson.M{"terminal_id": claims.TerminalId, "session_id": claims.ID}
+		if _, err := posTerminalCollection.UpdateOne(ctx, filter, bson.M{"$set": endPosSession}); err != nil {
+			http.Error(w, "Logout failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Signed out of POS terminal successfully",
+		})
+		return
+	}
+
 	// Remove token from the database
 	updateObj := bson.D{
 		{Key: "$set", Value: bson.D{
@@ -298,6 +331,140 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// UpdateUserRole changes the access role of a user, admins only. Outlet admins manage the
+// users of their own outlet; only head office grants or takes away ADMIN, or changes head-office
+// users and users without an outlet.
+func UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	userId := mux.Vars(r)["user_id"]
+
+	var requestBody models.User
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Role == nil {
+		http.Error(w, `{"success": false, "message": "role is required"}`, http.StatusBadRequest)
+		return
+	}
+	if validationErr := validate.StructPartial(requestBody, "Role"); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "role must be one of ADMIN, MANAGER, STAFF or USER"}`, http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	err := userCollection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "User not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving user"}`, http.StatusInternalServerError)
+		return
+	}
+
+	outletId, headOffice := middleware.GetOutletFromContext(r)
+	if !headOffice {
+		if outletId == "" || user.Outlet_id == nil || *user.Outlet_id != outletId {
+			http.Error(w, `{"success": false, "message": "User not found"}`, http.StatusNotFound)
+			return
+		}
+		if *requestBody.Role == "ADMIN" || (user.Role != nil && *user.Role == "ADMIN") || user.Head_office {
+			http.Error(w, `{"success": false, "message": "Only head office can grant or change admin access"}`, http.StatusForbidden)
+			return
+		}
+	}
+
+	result, err := userCollection.UpdateOne(ctx,
+		bson.M{"user_id": userId},
+		bson.M{"$set": bson.M{"role": requestBody.Role, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to update user role"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"success": false, "message": "User not found"}`, http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "User role updated successfully",
+		"data": map[string]interface{}{
+			"user_id": userId,
+			"role":    requestBody.Role,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateUserOutlet moves a user to an outlet, or to none, and grants or takes away head-office
+// access. Admins at head office only. The change applies from the user's next request.
+func UpdateUserOutlet(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	userId := mux.Vars(r)["user_id"]
+
+	var requestBody struct {
+		Outlet_id   *string `json:"outlet_id"`
+		Head_office *bool   `json:"head_office"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || (requestBody.Outlet_id == nil && requestBody.Head_office == nil) {
+		http.Error(w, `{"success": false, "message": "outlet_id or head_office is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	update := bson.M{}
+	set := bson.M{"updated_at": time.Now()}
+	if requestBody.Outlet_id != nil {
+		if *requestBody.Outlet_id == "" {
+			update["$unset"] = bson.M{"outlet_id": ""}
+		} else {
+			count, err := outletCollection.CountDocuments(ctx, activeFilter(bson.M{"outlet_id": *requestBody.Outlet_id}))
+			if err != nil {
+				http.Error(w, `{"success": false, "message": "Error checking outlet"}`, http.StatusInternalServerError)
+				return
+			}
+			if count == 0 {
+				http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusNotFound)
+				return
+			}
+			set["outlet_id"] = *requestBody.Outlet_id
+		}
+	}
+	if requestBody.Head_office != nil {
+		set["head_office"] = *requestBody.Head_office
+	}
+	update["$set"] = set
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var user models.User
+	err := userCollection.FindOneAndUpdate(ctx, bson.M{"user_id": userId}, update, opts).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "User not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to update user outlet"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "User outlet updated successfully",
+		"data": map[string]interface{}{
+			"user_id":     userId,
+			"outlet_id":   user.Outlet_id,
+			"head_office": user.Head_office,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
 func HashPassword(password string) string {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	if err != nil {
@@ -0,0 +1,410 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var waitlistCollection *mongo.Collection = database.OpenCollection(database.Client, "waitlist")
+
+// Turn time assumed until enough table turns have been recorded
+const defaultTurnMinutes = 60.0
+
+// findBestFitTable returns the outlet's smallest free table that seats the party
+func findBestFitTable(ctx context.Context, outletId string, partySize int) (models.Table, error) {
+	filter := sameOutlet(bson.M{"number_of_guests": bson.M{"$gte": partySize}}, outletId)
+	for key, value := range availableTableFilter {
+		filter[key] = value
+	}
+
+	var table models.Table
+	opts := options.FindOne().SetSort(bson.D{{Key: "number_of_guests", Value: 1}, {Key: "table_number", Value: 1}})
+	err := tableCollection.FindOne(ctx, filter, opts).Decode(&table)
+	return table, err
+}
+
+// waitEstimator holds an outlet's seatable tables and recent average turn time, loaded once so a
+// whole waitlist can be quoted without a query per party
+type waitEstimator struct {
+	avgTurn float64
+	tables  []models.Table
+	loaded  bool
+	now     time.Time
+}
+
+func newWaitEstimator(ctx context.Context, outletId string) waitEstimator {
+	estimator := waitEstimator{avgTurn: averageTurnMinutes(ctx, outletId, 50, defaultTurnMinutes), now: time.Now()}
+
+	filter := sameOutlet(bson.M{
+		"merged_into": bson.M{"$exists": false},
+		"status":      bson.M{"$ne": tableOutOfService},
+	}, outletId)
+	cursor, err := tableCollection.Find(ctx, filter)
+	if err != nil {
+		return estimator
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &estimator.tables); err == nil {
+		estimator.loaded = true
+	}
+	return estimator
+}
+
+// estimateWaitMinutes quotes a wait from how long the occupied tables that fit the party have
+// been seated against the recent average turn time, with partiesAhead queued in front
+func estimateWaitMinutes(ctx context.Context, outletId string, partySize, partiesAhead int) int {
+	return newWaitEstimator(ctx, outletId).minutes(partySize, partiesAhead)
+}
+
+func (e waitEstimator) minutes(partySize, partiesAhead int) int {
+	if !e.loaded {
+		return int(math.Ceil(e.avgTurn))
+	}
+
+	// Minutes until each fitting table is expected to free up
+	var remaining []float64
+	for _, table := range e.tables {
+		if table.Number_of_guests == nil || *table.Number_of_guests < partySize {
+			continue
+		}
+		left := 0.0
+		if table.Seated_at != nil {
+			left = math.Max(e.avgTurn-e.now.Sub(*table.Seated_at).Minutes(), 5)
+		} else if normalizeTableStatus(table.Status) != tableAvailable {
+			left = e.avgTurn
+		}
+		if left == 0 && partiesAhead == 0 {
+			return 0
+		}
+		remaining = append(remaining, left)
+	}
+	if len(remaining) == 0 {
+		return int(math.Ceil(e.avgTurn * float64(partiesAhead+1)))
+	}
+	sort.Float64s(remaining)
+
+	rounds := partiesAhead / len(remaining)
+	next := remaining[partiesAhead%len(remaining)]
+	return int(math.Ceil(next + float64(rounds)*e.avgTurn))
+}
+
+// countPartiesAhead counts the parties still waiting at the outlet that joined before the given time
+func countPartiesAhead(ctx context.Context, outletId string, joinedAt time.Time) int {
+	count, err := waitlistCollection.CountDocuments(ctx, sameOutlet(bson.M{"status": "Waiting", "created_at": bson.M{"$lt": joinedAt}}, outletId))
+	if err != nil {
+		return 0
+	}
+	return int(count)
+}
+
+// AddToWaitlist queues a walk-in party at the caller's outlet and quotes them a wait time
+func AddToWaitlist(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var entry models.WaitlistEntry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if validationErr := validate.Struct(entry); validationErr != nil {
+		http.Error(w, `{"success": false, "message": "party_name, party_size and phone are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	outletId, outletExists, err := checkRequestOutlet(ctx, r)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking outlet"}`, http.StatusInternalServerError)
+		return
+	}
+	if !outletExists {
+		http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusBadRequest)
+		return
+	}
+
+	entry.Outlet_id = outletId
+	entry.Created_at = time.Now()
+	entry.Updated_at = time.Now()
+	entry.Quoted_at = entry.Created_at
+	entry.Quoted_minutes = estimateWaitMinutes(ctx, outletId, *entry.Party_size, countPartiesAhead(ctx, outletId, entry.Created_at))
+	entry.Status = "Waiting"
+	entry.Table_id = ""
+	entry.Seated_at = nil
+	entry.ID = primitive.NewObjectID()
+	entry.Waitlist_id = entry.ID.Hex()
+
+	if _, err := waitlistCollection.InsertOne(ctx, entry); err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to add party to the waitlist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Party added to the waitlist successfully",
+		"data":    entry,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetWaitlist returns the waiting parties in queue order with a fresh wait estimate. Each outlet
+// has its own queue; head office sees them all, positioned within their outlet.
+func GetWaitlist(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := waitlistCollection.Find(ctx, outletScope(r, bson.M{"status": "Waiting"}), opts)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving waitlist"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var entries []models.WaitlistEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding waitlist"}`, http.StatusInternalServerError)
+		return
+	}
+
+	estimators := make(map[string]waitEstimator)
+	positions := make(map[string]int)
+	now := time.Now()
+	var waitlist []map[string]interface{}
+	for _, entry := range entries {
+		estimator, ok := estimators[entry.Outlet_id]
+		if !ok {
+			estimator = newWaitEstimator(ctx, entry.Outlet_id)
+			estimators[entry.Outlet_id] = estimator
+		}
+		position := positions[entry.Outlet_id]
+		positions[entry.Outlet_id]++
+
+		waitlist = append(waitlist, map[string]interface{}{
+			"waitlist_id":       entry.Waitlist_id,
+			"party_name":        entry.Party_name,
+			"party_size":        entry.Party_size,
+			"phone":             entry.Phone,
+			"position":          position + 1,
+			"quoted_minutes":    entry.Quoted_minutes,
+			"quoted_at":         entry.Quoted_at,
+			"waited_minutes":    int(now.Sub(entry.Created_at).Minutes()),
+			"estimated_minutes": estimator.minutes(*entry.Party_size, position),
+		})
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Waitlist retrieved successfully",
+		"data":    waitlist,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	waitlistId := mux.Vars(r)["waitlist_id"]
+
+	var entry models.WaitlistEntry
+	err := waitlistCollection.FindOne(ctx, bson.M{"waitlist_id": waitlistId}).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Waitlist entry not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving waitlist entry"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Waitlist entry retrieved successfully",
+		"data":    entry,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RemoveFromWaitlist cancels a waiting party, e.g. when they leave
+func RemoveFromWaitlist(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	waitlistId := mux.Vars(r)["waitlist_id"]
+
+	result, err := waitlistCollection.UpdateOne(ctx,
+		bson.M{"waitlist_id": waitlistId, "status": "Waiting"},
+		bson.M{"$set": bson.M{"status": "Cancelled", "updated_at": time.Now()}},
+	)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to remove party from the waitlist"}`, http.StatusInternalServerError)
+		return
+	}
+	if result.MatchedCount == 0 {
+		http.Error(w, `{"success": false, "message": "No waiting party found with this ID"}`, http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Party removed from the waitlist successfully",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SuggestWaitlistTable returns the best-fit free table for a waiting party
+func SuggestWaitlistTable(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	waitlistId := mux.Vars(r)["waitlist_id"]
+
+	var entry models.WaitlistEntry
+	if err := waitlistCollection.FindOne(ctx, bson.M{"waitlist_id": waitlistId, "status": "Waiting"}).Decode(&entry); err != nil {
+		http.Error(w, `{"success": false, "message": "No waiting party found with this ID"}`, http.StatusNotFound)
+		return
+	}
+
+	table, err := findBestFitTable(ctx, entry.Outlet_id, *entry.Party_size)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "No free table fits this party yet"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error finding a table"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Table suggested successfully",
+		"data": map[string]interface{}{
+			"waitlist_id":      entry.Waitlist_id,
+			"party_size":       entry.Party_size,
+			"table_id":         table.Table_id,
+			"table_number":     table.Table_number,
+			"number_of_guests": table.Number_of_guests,
+			"section":          table.Section,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SeatWaitlistParty reserves a table for a waiting party and takes them off the list.
+// Without a table_id in the body the best-fit free table is used.
+func SeatWaitlistParty(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	waitlistId := mux.Vars(r)["waitlist_id"]
+
+	var requestBody struct {
+		Table_id string `json:"table_id"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var entry models.WaitlistEntry
+	if err := waitlistCollection.FindOne(ctx, bson.M{"waitlist_id": waitlistId, "status": "Waiting"}).Decode(&entry); err != nil {
+		http.Error(w, `{"success": false, "message": "No waiting party found with this ID"}`, http.StatusNotFound)
+		return
+	}
+
+	var table models.Table
+	if requestBody.Table_id == "" {
+		bestFit, err := findBestFitTable(ctx, entry.Outlet_id, *entry.Party_size)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.Error(w, `{"success": false, "message": "No free table fits this party yet"}`, http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, `{"success": false, "message": "Error finding a table"}`, http.StatusInternalServerError)
+			return
+		}
+		table = bestFit
+	} else {
+		if err := tableCollection.FindOne(ctx, sameOutlet(bson.M{"table_id": requestBody.Table_id}, entry.Outlet_id)).Decode(&table); err != nil {
+			http.Error(w, `{"success": false, "message": "Table not found"}`, http.StatusNotFound)
+			return
+		}
+		if table.Number_of_guests != nil && *table.Number_of_guests < *entry.Party_size {
+			http.Error(w, `{"success": false, "message": "Table is too small for this party"}`, http.StatusBadRequest)
+			return
+		}
+		if table.Merged_into != "" {
+			http.Error(w, `{"success": false, "message": "Table is merged into another table, use the primary table `+table.Merged_into+`"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Reserve the table first; the status check in advanceTableStatus guards against double seating
+	changed, err := advanceTableStatus(ctx, table.Table_id, "reserve", "")
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to reserve the table"}`, http.StatusInternalServerError)
+		return
+	}
+	if !changed {
+		http.Error(w, `{"success": false, "message": "Table is no longer available"}`, http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	result, err := waitlistCollection.UpdateOne(ctx,
+		bson.M{"waitlist_id": waitlistId, "status": "Waiting"},
+		bson.M{"$set": bson.M{"status": "Seated", "table_id": table.Table_id, "seated_at": now, "updated_at": now}},
+	)
+	if err != nil || result.MatchedCount == 0 {
+		// Give the table back if the party could not be taken off the list
+		if _, unreserveErr := advanceTableStatus(ctx, table.Table_id, "unreserve", ""); unreserveErr != nil {
+			log.Printf("waitlist %s: failed to release table %s: %v", waitlistId, table.Table_id, unreserveErr)
+		}
+		http.Error(w, `{"success": false, "message": "Failed to seat the party"}`, http.StatusConflict)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Party seated successfully",
+		"data": map[string]interface{}{
+			"waitlist_id":    entry.Waitlist_id,
+			"party_name":     entry.Party_name,
+			"party_size":     entry.Party_size,
+			"table_id":       table.Table_id,
+			"table_number":   table.Table_number,
+			"waited_minutes": int(now.Sub(entry.Created_at).Minutes()),
+			"quoted_minutes": entry.Quoted_minutes,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
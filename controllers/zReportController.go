@@ -0,0 +1,379 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/helper"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var zReportCollection *mongo.Collection = database.OpenCollection(database.Client, "z_report")
+
+var errBusinessDateClosed = errors.New("business date is closed")
+
+// businessDateClosed reports whether the outlet's business date t falls on has had its Z-report
+func businessDateClosed(ctx context.Context, outletId string, t time.Time) (bool, error) {
+	count, err := zReportCollection.CountDocuments(ctx, sameOutlet(bson.M{"business_date": businessDate(t)}, outletId))
+	return count > 0, err
+}
+
+// buildZReport totals the outlet's invoices, refunds and drawer sessions of the business date starting at day
+func buildZReport(ctx context.Context, outletId string, day time.Time) (models.ZReport, error) {
+	report := models.ZReport{
+		Outlet_id:       outletId,
+		Business_date:   businessDate(day),
+		Payment_methods: []models.PaymentMethodTotal{},
+		Drawer_sessions: []models.ZReportDrawer{},
+	}
+	from, to := day, day.AddDate(0, 0, 1)
+	sales := bson.D{{Key: "$match", Value: bson.M{"payment_status": bson.M{"$in": salesInvoiceStatuses}}}}
+
+	invoicePipeline := mongo.Pipeline{
+		{{Key: "$match", Value: activeFilter(sameOutlet(bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}, outletId))}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "sales", Value: bson.A{
+				sales,
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: nil},
+					{Key: "invoices", Value: bson.D{{Key: "$sum", Value: 1}}},
+					{Key: "gross", Value: bson.D{{Key: "$sum", Value: invoiceGrossSales}}},
+					{Key: "discounts", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$discount_amount", 0}}}}}},
+					{Key: "taxes", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$tax_amount", 0}}}}}},
+					{Key: "total", Value: bson.D{{Key: "$sum", Value: "$total_price"}}},
+					{Key: "tips", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$tip_amount", 0}}}}}},
+				}}},
+			}},
+			{Key: "methods", Value: bson.A{
+				sales,
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$payment_method", "UNKNOWN"}}}},
+					{Key: "invoices", Value: bson.D{{Key: "$sum", Value: 1}}},
+					{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$total_price"}}},
+				}}},
+			}},
+			{Key: "statuses", Value: bson.A{
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: "$payment_status"},
+					{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+				}}},
+			}},
+		}}},
+	}
+	cursor, err := invoiceCollection.Aggregate(ctx, invoicePipeline)
+	if err != nil {
+		return report, err
+	}
+	var facets []struct {
+		Sales []struct {
+			Invoices  int64   `bson:"invoices"`
+			Gross     float64 `bson:"gross"`
+			Discounts float64 `bson:"discounts"`
+			Taxes     float64 `bson:"taxes"`
+			Total     float64 `bson:"total"`
+			Tips      float64 `bson:"tips"`
+		} `bson:"sales"`
+		Methods []struct {
+			Method   string  `bson:"_id"`
+			Invoices int64   `bson:"invoices"`
+			Amount   float64 `bson:"amount"`
+		} `bson:"methods"`
+		Statuses []struct {
+			Status string `bson:"_id"`
+			Count  int64  `bson:"count"`
+		} `bson:"statuses"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return report, err
+	}
+	if len(facets) > 0 {
+		if len(facets[0].Sales) > 0 {
+			totals := facets[0].Sales[0]
+			report.Invoices = totals.Invoices
+			report.Gross_sales = helper.RoundMoney(totals.Gross)
+			report.Discounts = helper.RoundMoney(totals.Discounts)
+			report.Taxes = helper.RoundMoney(totals.Taxes)
+			report.Total_sales = helper.RoundMoney(totals.Total)
+			report.Tips = helper.RoundMoney(totals.Tips)
+		}
+		for _, method := range facets[0].Methods {
+			report.Payment_methods = append(report.Payment_methods, models.PaymentMethodTotal{
+				Method:   method.Method,
+				Invoices: method.Invoices,
+				Amount:   helper.RoundMoney(method.Amount),
+			})
+		}
+		for _, status := range facets[0].Statuses {
+			switch status.Status {
+			case "PENDING":
+				report.Pending_invoices = status.Count
+			case invoiceVoid:
+				report.Voided_invoices = status.Count
+			}
+		}
+	}
+	sort.Slice(report.Payment_methods, func(i, j int) bool {
+		return report.Payment_methods[i].Amount > report.Payment_methods[j].Amount
+	})
+
+	// Refunds count on the day the credit note was issued, and come off net sales without their
+	// taxes, as in the sales report
+	refundPipeline := append(mongo.Pipeline{
+		{{Key: "$match", Value: sameOutlet(bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}, outletId)}},
+	}, refundInvoiceTotals...)
+	refundPipeline = append(refundPipeline, bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: nil},
+		{Key: "refunds", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+		{Key: "refunds_pre_tax", Value: bson.D{{Key: "$sum", Value: refundPreTax}}},
+	}}})
+	cursor, err = creditNoteCollection.Aggregate(ctx, refundPipeline)
+	if err != nil {
+		return report, err
+	}
+	var refunds []struct {
+		Refunds         float64 `bson:"refunds"`
+		Refunds_pre_tax float64 `bson:"refunds_pre_tax"`
+	}
+	if err := cursor.All(ctx, &refunds); err != nil {
+		return report, err
+	}
+	if len(refunds) > 0 {
+		report.Refunds = helper.RoundMoney(refunds[0].Refunds)
+		report.Refunds_pre_tax = helper.RoundMoney(refunds[0].Refunds_pre_tax)
+	}
+	report.Net_sales = helper.RoundMoney(report.Gross_sales - report.Discounts - report.Refunds_pre_tax)
+
+	cursor, err = cashDrawerCollection.Find(ctx, sameOutlet(bson.M{"business_date": report.Business_date, "status": drawerClosed}, outletId),
+		options.Find().SetSort(bson.D{{Key: "opened_at", Value: 1}}))
+	if err != nil {
+		return report, err
+	}
+	var sessions []models.CashDrawerSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return report, err
+	}
+	var variance float64
+	for _, session := range sessions {
+		drawer := models.ZReportDrawer{
+			Session_id: session.Session_id,
+			Opened_by:  session.Opened_by,
+			Closed_by:  session.Closed_by,
+		}
+		if session.Expected_cash != nil {
+			drawer.Expected_cash = *session.Expected_cash
+		}
+		if session.Counted_cash != nil {
+			drawer.Counted_cash = *session.Counted_cash
+		}
+		if session.Variance != nil {
+			drawer.Variance = *session.Variance
+		}
+		variance += drawer.Variance
+		report.Drawer_sessions = append(report.Drawer_sessions, drawer)
+	}
+	report.Cash_variance = helper.RoundMoney(variance)
+
+	return report, nil
+}
+
+// CreateZReport closes a business date of the caller's outlet (body {"business_date": "YYYY-MM-DD"}, default today).
+// Every drawer opened on or before it must be closed, and its invoices paid or voided, first. After this the date's invoices are locked.
+func CreateZReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var requestBody struct {
+		Business_date string `json:"business_date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, `{"success": false, "message": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if requestBody.Business_date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", requestBody.Business_date, time.Local)
+		if err != nil {
+			http.Error(w, `{"success": false, "message": "Invalid business_date, expected YYYY-MM-DD"}`, http.StatusBadRequest)
+			return
+		}
+		if parsed.After(day) {
+			http.Error(w, `{"success": false, "message": "Cannot close a business date in the future"}`, http.StatusBadRequest)
+			return
+		}
+		day = parsed
+	}
+
+	outletId, outletExists, err := checkRequestOutlet(ctx, r)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking outlet"}`, http.StatusInternalServerError)
+		return
+	}
+	if !outletExists {
+		http.Error(w, `{"success": false, "message": "Outlet not found"}`, http.StatusBadRequest)
+		return
+	}
+
+	closed, err := businessDateClosed(ctx, outletId, day)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking business date"}`, http.StatusInternalServerError)
+		return
+	}
+	if closed {
+		http.Error(w, `{"success": false, "message": "Business date is already closed"}`, http.StatusConflict)
+		return
+	}
+
+	openCount, err := cashDrawerCollection.CountDocuments(ctx, sameOutlet(bson.M{"status": drawerOpen, "business_date": bson.M{"$lte": businessDate(day)}}, outletId))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error checking cash drawer"}`, http.StatusInternalServerError)
+		return
+	}
+	if openCount > 0 {
+		http.Error(w, `{"success": false, "message": "Close the cash drawer before closing the day"}`, http.StatusConflict)
+		return
+	}
+
+	report, err := buildZReport(ctx, outletId, day)
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error building Z-report"}`, http.StatusInternalServerError)
+		return
+	}
+	// Invoices of a closed date can no longer be paid or voided, so none may be left pending
+	if report.Pending_invoices > 0 {
+		http.Error(w, `{"success": false, "message": "`+strconv.FormatInt(report.Pending_invoices, 10)+` invoices of the date are still pending; settle or void them before closing the day"}`, http.StatusConflict)
+		return
+	}
+	// Z numbers run per outlet; data from before outlets keeps the original counter
+	sequence := "z_report"
+	if outletId != "" {
+		sequence += ":" + outletId
+	}
+	_, _, _, uid := middleware.GetUserFromContext(r)
+	report.ID = primitive.NewObjectID()
+	report.Closed_by = uid
+	report.Created_at = time.Now()
+
+	session, err := database.Client.StartSession()
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to save Z-report"}`, http.StatusInternalServerError)
+		return
+	}
+	defer session.EndSession(ctx)
+
+	// The number is only taken if the report is saved, so a date closed twice at once leaves no gap;
+	// the unique index on outlet and date turns the second close away
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		number, err := helper.NextSequence(sessCtx, sequence)
+		if err != nil {
+			return nil, err
+		}
+		report.Z_number = number
+		return zReportCollection.InsertOne(sessCtx, report)
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		http.Error(w, `{"success": false, "message": "Business date is already closed"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Failed to save Z-report"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Business date closed successfully",
+		"data":    report,
+	})
+}
+
+// GetZReports lists the Z-reports over ?from=&to= (default the last 30 days), newest first
+func GetZReports(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	format, ok := exportFormat(w, r)
+	if !ok {
+		return
+	}
+	from, to, message := reportRange(r)
+	if message != "" {
+		http.Error(w, `{"success": false, "message": "`+message+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	filter := outletScope(r, bson.M{"business_date": bson.M{"$gte": businessDate(from), "$lt": businessDate(to)}})
+	cursor, err := zReportCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "business_date", Value: -1}}))
+	if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving Z-reports"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	reports := []models.ZReport{}
+	if err := cursor.All(ctx, &reports); err != nil {
+		http.Error(w, `{"success": false, "message": "Error decoding Z-reports"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if format != "" {
+		header := []interface{}{
+			"z_number", "business_date", "invoices", "pending_invoices", "voided_invoices", "gross_sales", "discounts",
+			"refunds", "refunds_pre_tax", "net_sales", "taxes", "total_sales", "tips", "cash_variance", "closed_by", "created_at",
+		}
+		rows := make([][]interface{}, len(reports))
+		for i, report := range reports {
+			rows[i] = []interface{}{
+				report.Z_number, report.Business_date, report.Invoices, report.Pending_invoices, report.Voided_invoices, report.Gross_sales, report.Discounts,
+				report.Refunds, report.Refunds_pre_tax, report.Net_sales, report.Taxes, report.Total_sales, report.Tips, report.Cash_variance, report.Closed_by, report.Created_at,
+			}
+		}
+		writeExport(w, format, "z-reports", header, rows)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Z-reports retrieved successfully",
+		"data":    reports,
+	})
+}
+
+// GetZReport returns the Z-report of one business date
+func GetZReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var report models.ZReport
+	err := zReportCollection.FindOne(ctx, sameOutlet(bson.M{"business_date": mux.Vars(r)["business_date"]}, requestOutletId(r))).Decode(&report)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.Error(w, `{"success": false, "message": "Z-report not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"success": false, "message": "Error retrieving Z-report"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Z-report retrieved successfully",
+		"data":    report,
+	})
+}
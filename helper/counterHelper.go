@@ -0,0 +1,72 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var counterCollection *mongo.Collection = database.OpenCollection(database.Client, "counters")
+
+// NextSequence atomically increments the named counter and returns its new value, starting at 1.
+// Pass a mongo.SessionContext to allocate the value inside a transaction.
+func NextSequence(ctx context.Context, key string) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := counterCollection.FindOneAndUpdate(ctx, bson.M{"_id": key}, bson.M{"$inc": bson.M{"seq": 1}}, opts).Decode(&counter)
+	return counter.Seq, err
+}
+
+// FiscalYear returns the label of the fiscal year containing t, e.g. "2026-27".
+// The year starts in FISCAL_YEAR_START_MONTH (1-12, default 4 for April); a January start gives "2026".
+func FiscalYear(t time.Time) string {
+	startMonth, err := strconv.Atoi(os.Getenv("FISCAL_YEAR_START_MONTH"))
+	if err != nil || startMonth < 1 || startMonth > 12 {
+		startMonth = 4
+	}
+
+	startYear := t.Year()
+	if int(t.Month()) < startMonth {
+		startYear--
+	}
+	if startMonth == 1 {
+		return strconv.Itoa(startYear)
+	}
+	return fmt.Sprintf("%d-%02d", startYear, (startYear+1)%100)
+}
+
+// OutletCode identifies the outlet this server runs for, from OUTLET_CODE
+func OutletCode() string {
+	return os.Getenv("OUTLET_CODE")
+}
+
+// InvoicePrefix returns the configured invoice number prefix, falling back to the outlet code
+func InvoicePrefix() string {
+	if prefix := os.Getenv("INVOICE_PREFIX"); prefix != "" {
+		return prefix
+	}
+	if outlet := OutletCode(); outlet != "" {
+		return outlet
+	}
+	return "INV"
+}
+
+// NextInvoiceNumber allocates the next number in the prefix's series for the fiscal year of t,
+// e.g. BLR1/2026-27/000123. Every prefix and fiscal year has its own counter.
+func NextInvoiceNumber(ctx context.Context, prefix string, t time.Time) (string, error) {
+	fiscalYear := FiscalYear(t)
+	seq, err := NextSequence(ctx, "invoice:"+prefix+":"+fiscalYear)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s/%06d", prefix, fiscalYear, seq), nil
+}
@@ -0,0 +1,224 @@
+package helper
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+)
+
+// ExportFormat checks a ?format= value; "" (plain JSON) is not an export
+func ExportFormat(format string) (string, bool) {
+	format = strings.ToLower(format)
+	return format, format == ExportCSV || format == ExportXLSX
+}
+
+// TableWriter writes a spreadsheet one row at a time, straight to the response.
+// Cells may be strings, numbers, bools, times or pointers to them; nil is an empty cell.
+type TableWriter interface {
+	WriteRow(cells ...interface{}) error
+	Close() error
+}
+
+// NewTableWriter sets the download headers for name (without extension) and returns a writer for the format
+func NewTableWriter(w http.ResponseWriter, format, name string) (TableWriter, error) {
+	filename := name + "-" + time.Now().Format("20060102-150405") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	switch format {
+	case ExportCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		return &csvTableWriter{writer: csv.NewWriter(w)}, nil
+	case ExportXLSX:
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		return newXLSXTableWriter(w, name)
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// Spreadsheet programs read text starting with one of these as a formula
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeFormula quotes text a spreadsheet would otherwise run as a formula, such as a
+// customer name of =HYPERLINK(...), so it is shown as typed
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune(formulaPrefixes, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// UnescapeExportCell undoes the quoting of formula-like text, for files exported here and read back in
+func UnescapeExportCell(text string) string {
+	if len(text) > 1 && text[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(text[1])) {
+		return text[1:]
+	}
+	return text
+}
+
+// exportCell turns a cell into its text, and whether it is a number. Text that looks like a
+// formula is quoted; numbers are written as numbers and need no quoting.
+func exportCell(cell interface{}) (string, bool) {
+	switch value := cell.(type) {
+	case nil:
+		return "", false
+	case string:
+		return escapeFormula(value), false
+	case *string:
+		if value == nil {
+			return "", false
+		}
+		return escapeFormula(*value), false
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case *float64:
+		if value == nil {
+			return "", false
+		}
+		return strconv.FormatFloat(*value, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(value), true
+	case *int:
+		if value == nil {
+			return "", false
+		}
+		return strconv.Itoa(*value), true
+	case int32:
+		return strconv.FormatInt(int64(value), 10), true
+	case int64:
+		return strconv.FormatInt(value, 10), true
+	case bool:
+		return strconv.FormatBool(value), false
+	case time.Time:
+		if value.IsZero() {
+			return "", false
+		}
+		return value.In(time.Local).Format("2006-01-02 15:04:05"), false
+	case *time.Time:
+		if value == nil || value.IsZero() {
+			return "", false
+		}
+		return value.In(time.Local).Format("2006-01-02 15:04:05"), false
+	}
+	return escapeFormula(fmt.Sprint(cell)), false
+}
+
+type csvTableWriter struct {
+	writer *csv.Writer
+}
+
+func (t *csvTableWriter) WriteRow(cells ...interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		record[i], _ = exportCell(cell)
+	}
+	return t.writer.Write(record)
+}
+
+func (t *csvTableWriter) Close() error {
+	t.writer.Flush()
+	return t.writer.Error()
+}
+
+// xlsxTableWriter streams a single-sheet workbook. The fixed parts are written up front and
+// the sheet stays open as the last zip entry, so rows go out as they are written.
+type xlsxTableWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	rows    int
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+func newXLSXTableWriter(w io.Writer, sheetName string) (*xlsxTableWriter, error) {
+	// Sheet names are at most 31 characters and cannot hold []:*?/\
+	sheetName = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, sheetName)
+	if len(sheetName) > 31 {
+		sheetName = sheetName[:31]
+	}
+
+	archive := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, xmlEscape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		entry, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n"+
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+	return &xlsxTableWriter{archive: archive, sheet: sheet}, nil
+}
+
+func xmlEscape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+func (t *xlsxTableWriter) WriteRow(cells ...interface{}) error {
+	t.rows++
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, t.rows)
+	for _, cell := range cells {
+		text, number := exportCell(cell)
+		switch {
+		case text == "":
+			b.WriteString(`<c/>`)
+		case number:
+			b.WriteString(`<c><v>` + text + `</v></c>`)
+		default:
+			b.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">` + xmlEscape(text) + `</t></is></c>`)
+		}
+	}
+	b.WriteString(`</row>`)
+	_, err := io.WriteString(t.sheet, b.String())
+	return err
+}
+
+func (t *xlsxTableWriter) Close() error {
+	if _, err := io.WriteString(t.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return t.archive.Close()
+}
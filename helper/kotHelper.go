@@ -0,0 +1,110 @@
+package helper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+)
+
+// KOT banners printed above the ticket body
+const (
+	KotReprint   = "REPRINT"
+	KotCancelled = "CANCELLED"
+)
+
+// RenderKOTESCPOS renders a kitchen order ticket for a thermal printer.
+// A non-empty banner (KotReprint, KotCancelled) is printed in large type above the ticket.
+func RenderKOTESCPOS(kot models.Kot, paperWidth int, banner string) []byte {
+	columns, ok := escposColumns[paperWidth]
+	if !ok {
+		columns = escposColumns[80]
+	}
+	divider := strings.Repeat("-", columns) + "\n"
+
+	var buf bytes.Buffer
+	buf.Write(escposInit)
+
+	buf.Write(escposAlignCenter)
+	if banner != "" {
+		buf.Write(escposBoldOn)
+		buf.Write(escposDoubleSize)
+		buf.WriteString("*** " + banner + " ***\n")
+		buf.Write(escposNormalSize)
+		buf.Write(escposBoldOff)
+	}
+	buf.Write(escposBoldOn)
+	buf.WriteString(printableText(strings.ToUpper(kot.Station_name)) + "\n")
+	buf.Write(escposDoubleSize)
+	buf.WriteString(fmt.Sprintf("KOT #%d\n", kot.Kot_number))
+	buf.Write(escposNormalSize)
+	if kot.Amendment {
+		buf.WriteString("AMENDMENT\n")
+	}
+	buf.Write(escposBoldOff)
+
+	buf.Write(escposAlignLeft)
+	if kot.Table_number > 0 {
+		buf.WriteString(escposRow("Table", fmt.Sprintf("%d", kot.Table_number), columns) + "\n")
+	}
+	buf.WriteString(escposRow("Waiter", kot.Waiter_name, columns) + "\n")
+	buf.WriteString(escposRow("Time", kot.Created_at.Format("02 Jan 15:04"), columns) + "\n")
+	if banner == KotCancelled && kot.Cancel_reason != "" {
+		buf.WriteString(escposRow("Reason", kot.Cancel_reason, columns) + "\n")
+	}
+	buf.WriteString(divider)
+
+	// Quantities first and large, the way the line reads them; amendments show the change
+	quantityFormat := "%3d x %s"
+	if kot.Amendment {
+		quantityFormat = "%+3d x %s"
+	}
+	buf.Write(escposBoldOn)
+	for _, item := range kot.Items {
+		buf.WriteString(printableText(fmt.Sprintf(quantityFormat, item.Quantity, item.Name)) + "\n")
+	}
+	buf.Write(escposBoldOff)
+
+	if kot.Notes != "" {
+		buf.WriteString(divider)
+		buf.WriteString(printableText("Note: "+kot.Notes) + "\n")
+	}
+
+	buf.Write(escposFeedCut)
+	return buf.Bytes()
+}
+
+// ValidPrinterAddress accepts ip:port where the IP is on a private network, since kitchen
+// printers sit on the restaurant's LAN. Anything else would let the API dial out for a caller.
+func ValidPrinterAddress(address string) bool {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsPrivate()
+}
+
+// SendToPrinter writes raw bytes to a network printer listening on host:port (usually port 9100)
+func SendToPrinter(address string, data []byte) error {
+	if !ValidPrinterAddress(address) {
+		return errors.New("printer address must be a private network ip:port")
+	}
+	conn, err := net.DialTimeout("tcp", address, 3*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Write(data)
+	return err
+}
@@ -0,0 +1,97 @@
+package helper
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoyaltyTier earns points faster once a customer's lifetime points reach Min_points
+type LoyaltyTier struct {
+	Name       string  `json:"name"`
+	Min_points int64   `json:"min_points"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+var defaultLoyaltyTiers = []LoyaltyTier{
+	{Name: "BRONZE", Min_points: 0, Multiplier: 1},
+	{Name: "SILVER", Min_points: 1000, Multiplier: 1.25},
+	{Name: "GOLD", Min_points: 5000, Multiplier: 1.5},
+}
+
+// envFloat reads a positive number setting, falling back when it is missing or malformed
+func envFloat(name string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// LoyaltyEarnRate is the points earned per unit of currency spent before taxes (LOYALTY_EARN_RATE, default 1)
+func LoyaltyEarnRate() float64 {
+	return envFloat("LOYALTY_EARN_RATE", 1)
+}
+
+// LoyaltyPointValue is what a point takes off a bill (LOYALTY_POINT_VALUE, default 0.1)
+func LoyaltyPointValue() float64 {
+	return envFloat("LOYALTY_POINT_VALUE", 0.1)
+}
+
+// LoyaltyExpiry is how long points last once earned (LOYALTY_EXPIRY_DAYS, default 365)
+func LoyaltyExpiry() time.Duration {
+	return time.Duration(envInt("LOYALTY_EXPIRY_DAYS", 365)) * 24 * time.Hour
+}
+
+// LoyaltyTiers parses LOYALTY_TIERS, a comma separated list of NAME:MIN_POINTS:MULTIPLIER such as
+// "BRONZE:0:1,SILVER:1000:1.25", lowest first. Malformed entries are skipped, and without any
+// valid entry the default tiers apply. A customer is always in the lowest tier at least.
+func LoyaltyTiers() []LoyaltyTier {
+	var tiers []LoyaltyTier
+	for _, entry := range strings.Split(os.Getenv("LOYALTY_TIERS"), ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 3 || strings.TrimSpace(parts[0]) == "" {
+			continue
+		}
+		minPoints, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil || minPoints < 0 {
+			continue
+		}
+		multiplier, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+		if err != nil || multiplier <= 0 {
+			continue
+		}
+		tiers = append(tiers, LoyaltyTier{Name: strings.ToUpper(strings.TrimSpace(parts[0])), Min_points: minPoints, Multiplier: multiplier})
+	}
+	if len(tiers) == 0 {
+		return append([]LoyaltyTier(nil), defaultLoyaltyTiers...)
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].Min_points < tiers[j].Min_points })
+	return tiers
+}
+
+// LoyaltyTierFor returns the tier for a customer's lifetime points and the next tier up, if any
+func LoyaltyTierFor(lifetimePoints int64) (LoyaltyTier, *LoyaltyTier) {
+	tiers := LoyaltyTiers()
+	current := 0
+	for i, tier := range tiers {
+		if lifetimePoints >= tier.Min_points {
+			current = i
+		}
+	}
+	if current+1 < len(tiers) {
+		return tiers[current], &tiers[current+1]
+	}
+	return tiers[current], nil
+}
+
+// LoyaltyPointsFor is the whole points earned on an amount at a tier
+func LoyaltyPointsFor(amount float64, tier LoyaltyTier) int64 {
+	if amount <= 0 {
+		return 0
+	}
+	return int64(math.Floor(amount*LoyaltyEarnRate()*tier.Multiplier + 1e-9))
+}
@@ -0,0 +1,54 @@
+package helper
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// Pay period lengths accepted in PAY_PERIOD
+const (
+	PayWeekly   = "WEEKLY"
+	PayBiweekly = "BIWEEKLY"
+	PayMonthly  = "MONTHLY"
+)
+
+// Weekly and biweekly periods count from PAY_PERIOD_START, a Monday unless configured
+var defaultPayPeriodStart = "2024-01-01"
+
+// PayPeriod reads PAY_PERIOD (WEEKLY, BIWEEKLY or MONTHLY), defaulting to WEEKLY
+func PayPeriod() string {
+	switch period := strings.ToUpper(os.Getenv("PAY_PERIOD")); period {
+	case PayBiweekly, PayMonthly:
+		return period
+	default:
+		return PayWeekly
+	}
+}
+
+// PayPeriodBounds returns the start and exclusive end of the pay period t falls in, in t's location
+func PayPeriodBounds(t time.Time) (time.Time, time.Time) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	period := PayPeriod()
+	if period == PayMonthly {
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 1, 0)
+	}
+
+	length := 7
+	if period == PayBiweekly {
+		length = 14
+	}
+	anchor, err := time.ParseInLocation("2006-01-02", os.Getenv("PAY_PERIOD_START"), t.Location())
+	if err != nil {
+		anchor, _ = time.ParseInLocation("2006-01-02", defaultPayPeriodStart, t.Location())
+	}
+	// Whole days between the dates, rounded so DST changes do not shift the period
+	days := int(day.Sub(anchor).Round(24*time.Hour) / (24 * time.Hour))
+	offset := days % length
+	if offset < 0 {
+		offset += length
+	}
+	start := day.AddDate(0, 0, -offset)
+	return start, start.AddDate(0, 0, length)
+}
@@ -0,0 +1,85 @@
+package helper
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PosSessionSubject marks the short-lived tokens staff get by PIN on a POS terminal
+const PosSessionSubject = "pos_session"
+
+var posTerminalCollection *mongo.Collection = database.OpenCollection(database.Client, "pos_terminal")
+
+// envInt reads a positive integer setting, falling back when it is missing or malformed
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// PosSessionLength is how long a PIN login on a terminal lasts (POS_SESSION_MINUTES, default 15)
+func PosSessionLength() time.Duration {
+	return time.Duration(envInt("POS_SESSION_MINUTES", 15)) * time.Minute
+}
+
+// PinMaxAttempts is how many wrong PINs in a row lock an employee out (PIN_MAX_ATTEMPTS, default 5).
+// A terminal locks after twice as many failed logins, whoever they were for.
+func PinMaxAttempts() int {
+	return envInt("PIN_MAX_ATTEMPTS", 5)
+}
+
+// PinLockout is how long a lockout lasts (PIN_LOCKOUT_MINUTES, default 15)
+func PinLockout() time.Duration {
+	return time.Duration(envInt("PIN_LOCKOUT_MINUTES", 15)) * time.Minute
+}
+
+// NewDeviceKey returns a random key a POS terminal authenticates with, and the hash stored for it
+func NewDeviceKey() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	key := hex.EncodeToString(raw)
+	return key, HashDeviceKey(key), nil
+}
+
+// HashDeviceKey is what a device key is stored and compared as
+func HashDeviceKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GeneratePOSToken signs a token for a user switched onto a terminal. It only works together with
+// the terminal's device key and until the next user switches on.
+func GeneratePOSToken(email, firstName, lastName, uid, terminalId, sessionId string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(PosSessionLength())
+	claims := &SignedDetails{
+		Email:      email,
+		FirstName:  firstName,
+		LastName:   lastName,
+		Uid:        uid,
+		TerminalId: terminalId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   PosSessionSubject,
+			ID:        sessionId,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(SECRET_KEY))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
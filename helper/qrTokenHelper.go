@@ -0,0 +1,123 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Subjects used to tell table QR and guest tokens apart from staff tokens
+const (
+	TableQRSubject      = "table_qr"
+	GuestSessionSubject = "guest_session"
+)
+
+type TableQRDetails struct {
+	Table_id   string `json:"table_id"`
+	Qr_version int    `json:"qr_version"`
+	jwt.RegisteredClaims
+}
+
+type GuestDetails struct {
+	Table_id   string `json:"table_id"`
+	Qr_version int    `json:"qr_version"`
+	Session_id string `json:"session_id"`
+	jwt.RegisteredClaims
+}
+
+var tableCollection *mongo.Collection = database.OpenCollection(database.Client, "table")
+
+// GenerateTableQRToken signs the token printed in a table's QR code.
+// It does not expire; bumping the table's qr_version invalidates it.
+func GenerateTableQRToken(tableId string, version int) (string, error) {
+	claims := &TableQRDetails{
+		Table_id:   tableId,
+		Qr_version: version,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:  TableQRSubject,
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(SECRET_KEY))
+}
+
+// GenerateGuestToken creates a short-lived session token scoped to a single table
+func GenerateGuestToken(tableId string, version int) (string, string, error) {
+	sessionId := primitive.NewObjectID().Hex()
+	claims := &GuestDetails{
+		Table_id:   tableId,
+		Qr_version: version,
+		Session_id: sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   GuestSessionSubject,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(4 * time.Hour)), // 4 hours expiration
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(SECRET_KEY))
+	if err != nil {
+		return "", "", err
+	}
+	return token, sessionId, nil
+}
+
+// ValidateTableQRToken checks a scanned QR token against the table's current qr_version
+func ValidateTableQRToken(signedToken string) (*TableQRDetails, string) {
+	claims := &TableQRDetails{}
+	token, err := jwt.ParseWithClaims(signedToken, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(SECRET_KEY), nil
+	})
+	if err != nil {
+		return nil, fmt.Sprintf("token parsing error: %v", err)
+	}
+	if !token.Valid || claims.Subject != TableQRSubject {
+		return nil, "the QR code is invalid"
+	}
+
+	if msg := checkTableQRVersion(claims.Table_id, claims.Qr_version); msg != "" {
+		return nil, msg
+	}
+	return claims, ""
+}
+
+// ValidateGuestToken checks a guest session token and that the table QR was not rotated since
+func ValidateGuestToken(signedToken string) (*GuestDetails, string) {
+	claims := &GuestDetails{}
+	token, err := jwt.ParseWithClaims(signedToken, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(SECRET_KEY), nil
+	})
+	if err != nil {
+		return nil, fmt.Sprintf("token parsing error: %v", err)
+	}
+	if !token.Valid || claims.Subject != GuestSessionSubject {
+		return nil, "the guest session is invalid"
+	}
+
+	if msg := checkTableQRVersion(claims.Table_id, claims.Qr_version); msg != "" {
+		return nil, msg
+	}
+	return claims, ""
+}
+
+func checkTableQRVersion(tableId string, version int) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var table models.Table
+	if err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table); err != nil {
+		return "table not found"
+	}
+	if table.Qr_version != version {
+		return "the QR code has been rotated, please scan again"
+	}
+	return ""
+}
@@ -0,0 +1,283 @@
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/go-pdf/fpdf"
+)
+
+// Receipt is everything printed on an invoice, gathered from the invoice, its order and the outlet template
+type Receipt struct {
+	Template       models.ReceiptTemplate
+	Invoice_number string
+	Order_id       string
+	Table_number   int
+	Date           time.Time
+	Lines          []models.OrderLine
+	Subtotal       float64
+	Discount       float64
+	Taxes          []models.TaxLine
+	Tax_amount     float64
+	Total          float64
+	Refunded_total float64
+	Tip            float64
+	Payment_method string
+	Payment_status string
+}
+
+// Characters per line in font A on common thermal paper widths
+var escposColumns = map[int]int{58: 32, 80: 48}
+
+// ESC/POS control sequences
+var (
+	escposInit        = []byte{0x1B, 0x40}
+	escposAlignLeft   = []byte{0x1B, 0x61, 0x00}
+	escposAlignCenter = []byte{0x1B, 0x61, 0x01}
+	escposBoldOn      = []byte{0x1B, 0x45, 0x01}
+	escposBoldOff     = []byte{0x1B, 0x45, 0x00}
+	escposDoubleSize  = []byte{0x1D, 0x21, 0x11}
+	escposNormalSize  = []byte{0x1D, 0x21, 0x00}
+	escposFeedCut     = []byte{0x1D, 0x56, 0x42, 0x03}
+)
+
+// printableText replaces characters the core PDF fonts and printer code pages cannot show
+func printableText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return ' '
+		}
+		if r < 0x20 || r > 0x7E {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+func formatAmount(currency string, amount float64) string {
+	if currency == "" {
+		return fmt.Sprintf("%.2f", amount)
+	}
+	return fmt.Sprintf("%s %.2f", currency, amount)
+}
+
+func formatRate(rate float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", rate), "0"), ".") + "%"
+}
+
+// receiptDetails lists the label/value rows printed between the header and the order lines
+func receiptDetails(receipt Receipt) [][2]string {
+	details := [][2]string{
+		{"Invoice", receipt.Invoice_number},
+		{"Date", receipt.Date.Format("02 Jan 2006 15:04")},
+	}
+	if receipt.Table_number > 0 {
+		details = append(details, [2]string{"Table", fmt.Sprintf("%d", receipt.Table_number)})
+	}
+	details = append(details, [2]string{"Order", receipt.Order_id})
+	return details
+}
+
+// receiptTotals lists the label/value rows printed under the order lines
+func receiptTotals(receipt Receipt) [][2]string {
+	currency := receipt.Template.Currency
+	totals := [][2]string{{"Subtotal", formatAmount(currency, receipt.Subtotal)}}
+	if receipt.Discount > 0 {
+		totals = append(totals, [2]string{"Discount", "-" + formatAmount(currency, receipt.Discount)})
+	}
+	for _, tax := range receipt.Taxes {
+		totals = append(totals, [2]string{tax.Name + " @ " + formatRate(tax.Rate), formatAmount(currency, tax.Amount)})
+	}
+	totals = append(totals, [2]string{"Total", formatAmount(currency, receipt.Total)})
+	if receipt.Tip > 0 {
+		totals = append(totals, [2]string{"Tip", formatAmount(currency, receipt.Tip)})
+		totals = append(totals, [2]string{"Amount paid", formatAmount(currency, RoundMoney(receipt.Total+receipt.Tip))})
+	}
+	if receipt.Refunded_total > 0 {
+		totals = append(totals, [2]string{"Refunded", formatAmount(currency, receipt.Refunded_total)})
+	}
+	return totals
+}
+
+func paymentSummary(receipt Receipt) string {
+	if receipt.Payment_method == "" {
+		return receipt.Payment_status
+	}
+	return receipt.Payment_status + " - " + receipt.Payment_method
+}
+
+// RenderReceiptPDF renders the receipt as an A4 PDF for email and office printers
+func RenderReceiptPDF(receipt Receipt) ([]byte, error) {
+	template := receipt.Template
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(printableText("Invoice "+receipt.Invoice_number), false)
+	pdf.SetMargins(15, 15, 15)
+	pdf.AddPage()
+
+	// Restaurant header
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 9, printableText(template.Restaurant_name), "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range template.Address_lines {
+		pdf.CellFormat(0, 5, printableText(line), "", 1, "C", false, 0, "")
+	}
+	if template.Phone != "" {
+		pdf.CellFormat(0, 5, printableText("Phone: "+template.Phone), "", 1, "C", false, 0, "")
+	}
+	if template.Tax_id != "" {
+		pdf.CellFormat(0, 5, printableText("Tax ID: "+template.Tax_id), "", 1, "C", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 8, "TAX INVOICE", "", 1, "C", false, 0, "")
+	pdf.Ln(2)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, detail := range receiptDetails(receipt) {
+		pdf.CellFormat(30, 6, detail[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, printableText(detail[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	// Order lines
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(235, 235, 235)
+	pdf.CellFormat(95, 7, "Item", "B", 0, "L", true, 0, "")
+	pdf.CellFormat(20, 7, "Qty", "B", 0, "R", true, 0, "")
+	pdf.CellFormat(32, 7, "Price", "B", 0, "R", true, 0, "")
+	pdf.CellFormat(33, 7, "Amount", "B", 1, "R", true, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range receipt.Lines {
+		pdf.CellFormat(95, 6, printableText(line.Name), "", 0, "L", false, 0, "")
+		pdf.CellFormat(20, 6, fmt.Sprintf("%d", line.Quantity), "", 0, "R", false, 0, "")
+		pdf.CellFormat(32, 6, fmt.Sprintf("%.2f", line.Unit_price), "", 0, "R", false, 0, "")
+		pdf.CellFormat(33, 6, fmt.Sprintf("%.2f", line.Amount), "", 1, "R", false, 0, "")
+	}
+	pdf.CellFormat(0, 2, "", "T", 1, "", false, 0, "")
+
+	// Tax breakdown and totals
+	for _, total := range receiptTotals(receipt) {
+		style := ""
+		if total[0] == "Total" {
+			style = "B"
+		}
+		pdf.SetFont("Helvetica", style, 10)
+		pdf.CellFormat(147, 6, printableText(total[0]), "", 0, "R", false, 0, "")
+		pdf.CellFormat(33, 6, printableText(total[1]), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(2)
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, printableText("Payment: "+paymentSummary(receipt)), "", 1, "L", false, 0, "")
+	pdf.Ln(6)
+
+	// Footer
+	pdf.SetFont("Helvetica", "I", 9)
+	for _, line := range template.Footer_lines {
+		pdf.CellFormat(0, 5, printableText(line), "", 1, "C", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// escposRow lays out a label on the left and a value on the right of a line
+func escposRow(left, right string, columns int) string {
+	left, right = printableText(left), printableText(right)
+	space := columns - len(left) - len(right)
+	if space < 1 {
+		// Give the value priority and truncate the label
+		maxLeft := columns - len(right) - 1
+		if maxLeft < 0 {
+			maxLeft = 0
+		}
+		if len(left) > maxLeft {
+			left = left[:maxLeft]
+		}
+		space = columns - len(left) - len(right)
+		if space < 1 {
+			space = 1
+		}
+	}
+	return left + strings.Repeat(" ", space) + right
+}
+
+// RenderReceiptESCPOS renders the receipt as ESC/POS commands for a 58mm or 80mm thermal printer
+func RenderReceiptESCPOS(receipt Receipt, paperWidth int) []byte {
+	columns, ok := escposColumns[paperWidth]
+	if !ok {
+		columns = escposColumns[80]
+	}
+	template := receipt.Template
+	divider := strings.Repeat("-", columns) + "\n"
+
+	var buf bytes.Buffer
+	buf.Write(escposInit)
+
+	// Restaurant header
+	buf.Write(escposAlignCenter)
+	buf.Write(escposBoldOn)
+	buf.Write(escposDoubleSize)
+	buf.WriteString(printableText(template.Restaurant_name) + "\n")
+	buf.Write(escposNormalSize)
+	buf.Write(escposBoldOff)
+	for _, line := range template.Address_lines {
+		buf.WriteString(printableText(line) + "\n")
+	}
+	if template.Phone != "" {
+		buf.WriteString(printableText("Phone: "+template.Phone) + "\n")
+	}
+	if template.Tax_id != "" {
+		buf.WriteString(printableText("Tax ID: "+template.Tax_id) + "\n")
+	}
+	buf.WriteString("\n")
+	buf.Write(escposBoldOn)
+	buf.WriteString("TAX INVOICE\n")
+	buf.Write(escposBoldOff)
+
+	buf.Write(escposAlignLeft)
+	for _, detail := range receiptDetails(receipt) {
+		buf.WriteString(escposRow(detail[0], detail[1], columns) + "\n")
+	}
+	buf.WriteString(divider)
+
+	// Order lines: the name on its own line, then quantity x price and the amount
+	for _, line := range receipt.Lines {
+		buf.WriteString(printableText(line.Name) + "\n")
+		buf.WriteString(escposRow(fmt.Sprintf("  %d x %.2f", line.Quantity, line.Unit_price), fmt.Sprintf("%.2f", line.Amount), columns) + "\n")
+	}
+	buf.WriteString(divider)
+
+	// Tax breakdown and totals
+	for _, total := range receiptTotals(receipt) {
+		if total[0] == "Total" {
+			buf.Write(escposBoldOn)
+			buf.WriteString(escposRow(total[0], total[1], columns) + "\n")
+			buf.Write(escposBoldOff)
+			continue
+		}
+		buf.WriteString(escposRow(total[0], total[1], columns) + "\n")
+	}
+	buf.WriteString(divider)
+	buf.WriteString(escposRow("Payment", paymentSummary(receipt), columns) + "\n")
+
+	// Footer
+	if len(template.Footer_lines) > 0 {
+		buf.WriteString("\n")
+		buf.Write(escposAlignCenter)
+		for _, line := range template.Footer_lines {
+			buf.WriteString(printableText(line) + "\n")
+		}
+	}
+
+	buf.Write(escposFeedCut)
+	return buf.Bytes()
+}
@@ -0,0 +1,64 @@
+package helper
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// ShiftWindow is a named part of the trading day, e.g. LUNCH from 11:00 to 16:00.
+// A window whose end is before its start runs past midnight.
+type ShiftWindow struct {
+	Name  string
+	Start time.Duration // offset from midnight
+	End   time.Duration
+}
+
+var defaultShiftWindows = "LUNCH:11:00-16:00,DINNER:16:00-23:59"
+
+func parseClock(s string) (time.Duration, bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, true
+}
+
+// ShiftWindows parses SHIFTS ("NAME:HH:MM-HH:MM,..."), skipping malformed entries.
+// Without SHIFTS the day is split into LUNCH and DINNER.
+func ShiftWindows() []ShiftWindow {
+	config := os.Getenv("SHIFTS")
+	if config == "" {
+		config = defaultShiftWindows
+	}
+
+	var windows []ShiftWindow
+	for _, entry := range strings.Split(config, ",") {
+		name, span, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || name == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(span, "-")
+		if !ok {
+			continue
+		}
+		start, okStart := parseClock(from)
+		end, okEnd := parseClock(to)
+		if !okStart || !okEnd || start == end {
+			continue
+		}
+		windows = append(windows, ShiftWindow{Name: strings.ToUpper(name), Start: start, End: end})
+	}
+	return windows
+}
+
+// Bounds returns when the shift starts and ends on the given day, in the day's location
+func (s ShiftWindow) Bounds(day time.Time) (time.Time, time.Time) {
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	start := midnight.Add(s.Start)
+	end := midnight.Add(s.End)
+	if s.End < s.Start {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end
+}
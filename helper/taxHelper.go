@@ -0,0 +1,44 @@
+package helper
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+)
+
+// RoundMoney rounds an amount to whole cents
+func RoundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// TaxRates parses TAX_RATES, a comma separated list of NAME:PERCENT pairs such as "CGST:2.5,SGST:2.5".
+// Malformed entries are skipped.
+func TaxRates() []models.TaxLine {
+	var rates []models.TaxLine
+	for _, entry := range strings.Split(os.Getenv("TAX_RATES"), ",") {
+		name, rate, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || name == "" {
+			continue
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+		if err != nil || percent < 0 {
+			continue
+		}
+		rates = append(rates, models.TaxLine{Name: strings.TrimSpace(name), Rate: percent})
+	}
+	return rates
+}
+
+// ApplyTaxes charges every configured tax on the subtotal and returns the lines and the tax total
+func ApplyTaxes(subtotal float64) ([]models.TaxLine, float64) {
+	taxes := TaxRates()
+	var taxAmount float64
+	for i := range taxes {
+		taxes[i].Amount = RoundMoney(subtotal * taxes[i].Rate / 100)
+		taxAmount += taxes[i].Amount
+	}
+	return taxes, RoundMoney(taxAmount)
+}
@@ -16,10 +16,15 @@ import (
 )
 
 type SignedDetails struct {
-	Email     string `json:"email"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Uid       string `json:"uid"`
+	Email      string `json:"email"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Uid        string `json:"uid"`
+	Role       string `json:"-"`                   // loaded from the user record on validation, never trusted from the token
+	OutletId   string `json:"outlet_id,omitempty"` // refreshed from the user record on validation, like the role
+	HeadOffice bool   `json:"-"`
+	TerminalId string `json:"terminal_id,omitempty"` // POS terminal a PIN login is tied to
+	KeyHash    string `json:"-"`                     // the terminal's device key hash, loaded on validation
 	jwt.RegisteredClaims
 }
 
@@ -28,12 +33,13 @@ var userCollection *mongo.Collection = database.OpenCollection(database.Client,
 var SECRET_KEY string = os.Getenv("SECRET_KEY")
 
 // GenerateAllTokens creates JWT and refresh tokens
-func GenerateAllTokens(email, firstName, lastName, uid string) (signedToken string, signedRefreshToken string, err error) {
+func GenerateAllTokens(email, firstName, lastName, uid, outletId string) (signedToken string, signedRefreshToken string, err error) {
 	claims := &SignedDetails{
 		Email:     email,
 		FirstName: firstName,
 		LastName:  lastName,
 		Uid:       uid,
+		OutletId:  outletId,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24 hours expiration
 		},
@@ -101,6 +107,11 @@ func ValidateToken(signedToken string) (*SignedDetails, string) {
 		return nil, "the token is invalid"
 	}
 
+	// Table QR and guest session tokens share the signing key but never grant staff access
+	if claims.Subject == TableQRSubject || claims.Subject == GuestSessionSubject {
+		return nil, "the token is not a staff token"
+	}
+
 	// Check token expiration
 	if claims.ExpiresAt.Time.Before(time.Now()) {
 		return nil, "token is expired"
@@ -112,9 +123,41 @@ func ValidateToken(signedToken string) (*SignedDetails, string) {
 
 	var user models.User
 	err = userCollection.FindOne(ctx, bson.M{"user_id": claims.Uid}).Decode(&user)
-	if err != nil || user.Token == nil || *user.Token != signedToken {
+	if err != nil {
+		return nil, "invalid or expired token"
+	}
+
+	// A POS token lives alongside the user's own login and ends when someone else switches on
+	var terminal models.PosTerminal
+	if claims.Subject == PosSessionSubject {
+		err = posTerminalCollection.FindOne(ctx, bson.M{"terminal_id": claims.TerminalId, "deleted_at": nil}).Decode(&terminal)
+		if err != nil || terminal.Session_id == "" || terminal.Session_id != claims.ID {
+			return nil, "the POS session has ended"
+		}
+	} else if user.Token == nil || *user.Token != signedToken {
 		return nil, "invalid or expired token"
 	}
 
+	claims.Role = "USER"
+	if user.Role != nil && *user.Role != "" {
+		claims.Role = *user.Role
+	}
+	claims.OutletId = ""
+	if user.Outlet_id != nil {
+		claims.OutletId = *user.Outlet_id
+	}
+	// Only users flagged for it run head office; having no outlet is not enough
+	claims.HeadOffice = user.Head_office
+
+	// On a terminal managers and admins act as staff, and only for the terminal's outlet
+	if claims.Subject == PosSessionSubject {
+		if claims.Role == "ADMIN" || claims.Role == "MANAGER" {
+			claims.Role = "STAFF"
+		}
+		claims.OutletId = terminal.Outlet_id
+		claims.HeadOffice = false
+		claims.KeyHash = terminal.Key_hash
+	}
+
 	return claims, ""
 }
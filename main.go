@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
 	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
 	routes "github.com/02priyeshraj/Hotel_Management_Backend/routes"
 	"github.com/joho/godotenv"
@@ -23,6 +24,8 @@ func main() {
 	// Load environment variables
 	LoadEnv()
 
+	database.EnsureIndexes(database.Client)
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8000"
@@ -32,10 +35,18 @@ func main() {
 
 	// Public Routes (No Authentication)
 	routes.UserPublicRoutes(router)
+	routes.GuestPublicRoutes(router)
+	routes.PaymentPublicRoutes(router)
+	routes.PosPublicRoutes(router)
+
+	// Guest Routes (table QR session, never reach staff endpoints)
+	guestRoutes := router.PathPrefix("/guest").Subrouter()
+	guestRoutes.Use(middleware.GuestAuthentication, middleware.Audit)
+	routes.GuestProtectedRoutes(guestRoutes)
 
 	//Authentication Middleware to Protected Routes
 	securedRoutes := router.PathPrefix("/").Subrouter()
-	securedRoutes.Use(middleware.Authentication)
+	securedRoutes.Use(middleware.Authentication, middleware.OutletScope, middleware.Audit)
 	routes.UserProtectedRoutes(securedRoutes)
 	routes.TableProtectedRoutes(securedRoutes)
 	routes.MenuProtectedRoutes(securedRoutes)
@@ -43,6 +54,17 @@ func main() {
 	routes.OrderProtectedRoutes(securedRoutes)
 	routes.OrderItemProtectedRoutes(securedRoutes)
 	routes.InvoiceProtectedRoutes(securedRoutes)
+	routes.WaitlistProtectedRoutes(securedRoutes)
+	routes.KotProtectedRoutes(securedRoutes)
+	routes.GiftCardProtectedRoutes(securedRoutes)
+	routes.CashDrawerProtectedRoutes(securedRoutes)
+	routes.StaffProtectedRoutes(securedRoutes)
+	routes.PosProtectedRoutes(securedRoutes)
+	routes.ServerProtectedRoutes(securedRoutes)
+	routes.CustomerProtectedRoutes(securedRoutes)
+	routes.OutletProtectedRoutes(securedRoutes)
+	routes.ReportProtectedRoutes(securedRoutes)
+	routes.AuditProtectedRoutes(securedRoutes)
 
 	log.Printf("Server running on port %s", port)
 	http.ListenAndServe(":"+port, router)
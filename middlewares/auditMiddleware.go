@@ -0,0 +1,312 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/02priyeshraj/Hotel_Management_Backend/models"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var auditCollection *mongo.Collection = database.OpenCollection(database.Client, "audit_log")
+
+type auditEntity struct {
+	collection string
+	idField    string
+	redacted   []string // fields of this collection never copied into the audit log
+}
+
+// Route prefixes mapped to the collection and ID path variable they mutate
+var auditEntities = map[string]auditEntity{
+	"users":            {collection: "user", idField: "user_id"},
+	"tables":           {collection: "table", idField: "table_id"},
+	"menus":            {collection: "menu", idField: "menu_id"},
+	"foods":            {collection: "food", idField: "food_id"},
+	"orders":           {collection: "order", idField: "order_id"},
+	"orderitems":       {collection: "orderitems", idField: "order_item_id"},
+	"invoices":         {collection: "invoice", idField: "invoice_id"},
+	"waitlist":         {collection: "waitlist", idField: "waitlist_id"},
+	"kots":             {collection: "kot", idField: "kot_id"},
+	"kitchen-stations": {collection: "kitchen_station", idField: "station_id"},
+	"gift-cards":       {collection: "gift_card", idField: "gift_card_id", redacted: []string{"code"}},
+	"cash-drawer":      {collection: "cash_drawer_session", idField: "session_id"},
+	"outlets":          {collection: "outlet", idField: "outlet_id"},
+	"staff":            {collection: "staff", idField: "staff_id"},
+	"shifts":           {collection: "shift", idField: "shift_id"},
+	"time-entries":     {collection: "time_entry", idField: "time_entry_id"},
+	"pos-terminals":    {collection: "pos_terminal", idField: "terminal_id"},
+	"sections":         {collection: "section_assignment", idField: "section"},
+	"servers":          {collection: "user", idField: "user_id"},
+	"customers":        {collection: "customer_profile", idField: "user_id"},
+}
+
+// Fields never copied into the audit log; a dotted name is a field of an embedded document
+var auditRedactedFields = []string{"_id", "password", "token", "refresh_token", "pin", "key_hash", "payment_details.gift_card_code"}
+
+// auditRecorder captures the status and body of a response while passing it through
+type auditRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *auditRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *auditRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Audit records every create, update and delete with the acting user, the affected document
+// before and after the change, and the caller's IP. Failed requests are recorded with their
+// status code and no after document. It runs after Authentication or GuestAuthentication,
+// or on its own for the sign-up and login routes.
+func Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action := auditAction(r.Method)
+		if action == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		path := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				path = template
+			}
+		}
+		segments := strings.Split(strings.Trim(path, "/"), "/")
+		if segments[0] == "guest" && len(segments) > 1 {
+			segments = segments[1:]
+		}
+
+		entityName := segments[0]
+		entity, known := auditEntities[entityName]
+		if !known {
+			entity = auditEntity{collection: entityName}
+		}
+
+		entityId := ""
+		if entity.idField != "" {
+			entityId = mux.Vars(r)[entity.idField]
+		}
+		if entityId != "" && action == "create" {
+			action = "update" // e.g. POST /orders/{order_id}/transfer
+		}
+		if entityName == "users" && len(segments) == 2 && (segments[1] == "login" || segments[1] == "logout") {
+			action = segments[1]
+		}
+
+		before := loadAuditDocument(entity, entityId)
+
+		rec := &auditRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		failed := rec.status >= http.StatusBadRequest
+
+		// Created documents are identified from the response body
+		if entityId == "" && entity.idField != "" && !failed {
+			entityId = responseField(rec.body.Bytes(), entity.idField)
+		}
+		var after bson.M
+		if !failed {
+			after = loadAuditDocument(entity, entityId)
+		}
+
+		entry := models.AuditLog{
+			ID:          primitive.NewObjectID(),
+			Entity:      entityName,
+			Entity_id:   entityId,
+			Action:      action,
+			Method:      r.Method,
+			Path:        path,
+			Status_code: rec.status,
+			Before:      before,
+			After:       after,
+			Changes:     diffDocuments(before, after),
+			Ip:          clientIP(r),
+			Created_at:  time.Now(),
+		}
+		entry.Audit_id = entry.ID.Hex()
+
+		email, _, _, uid := GetUserFromContext(r)
+		if tableId, sessionId := GetGuestFromContext(r); uid == "" && tableId != "" {
+			entry.Actor_type = "guest"
+			entry.Actor_id = sessionId
+		} else if uid != "" {
+			entry.Actor_type = "user"
+			entry.Actor_id = uid
+			entry.Actor_email = email
+		} else if entityName == "users" && !failed {
+			// A sign-up or login acts as the user it creates or signs in
+			entry.Actor_type = "user"
+			entry.Actor_id = entityId
+			entry.Actor_email = responseField(rec.body.Bytes(), "email")
+		} else {
+			entry.Actor_type = "anonymous"
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+		if _, err := auditCollection.InsertOne(ctx, entry); err != nil {
+			log.Printf("audit: failed to record %s %s: %v", r.Method, path, err)
+		}
+	})
+}
+
+func auditAction(method string) string {
+	switch method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut, http.MethodPatch:
+		return "update"
+	case http.MethodDelete:
+		return "delete"
+	}
+	return ""
+}
+
+func loadAuditDocument(entity auditEntity, entityId string) bson.M {
+	if entity.idField == "" || entityId == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var document bson.M
+	err := database.OpenCollection(database.Client, entity.collection).FindOne(ctx, bson.M{entity.idField: entityId}).Decode(&document)
+	if err != nil {
+		return nil
+	}
+	for _, field := range append(auditRedactedFields, entity.redacted...) {
+		redactField(document, strings.Split(field, "."))
+	}
+	return document
+}
+
+func redactField(document bson.M, path []string) {
+	if len(path) == 1 {
+		delete(document, path[0])
+		return
+	}
+	switch embedded := document[path[0]].(type) {
+	case bson.M:
+		redactField(embedded, path[1:])
+	case bson.D:
+		embeddedMap := make(bson.M, len(embedded))
+		for _, element := range embedded {
+			embeddedMap[element.Key] = element.Value
+		}
+		redactField(embeddedMap, path[1:])
+		document[path[0]] = embeddedMap
+	}
+}
+
+func responseField(body []byte, field string) string {
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ""
+	}
+	value, _ := response.Data[field].(string)
+	return value
+}
+
+// diffDocuments lists the top-level fields that differ, ignoring the updated_at timestamp
+func diffDocuments(before, after bson.M) map[string]models.AuditChange {
+	changes := make(map[string]models.AuditChange)
+	for key, from := range before {
+		if to, ok := after[key]; !ok || !reflect.DeepEqual(from, to) {
+			changes[key] = models.AuditChange{From: from, To: after[key]}
+		}
+	}
+	for key, to := range after {
+		if _, ok := before[key]; !ok {
+			changes[key] = models.AuditChange{From: nil, To: to}
+		}
+	}
+	delete(changes, "updated_at")
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// trustedProxies parses TRUSTED_PROXIES, a comma-separated list of IPs and CIDR ranges
+func trustedProxies() []*net.IPNet {
+	var proxies []*net.IPNet
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			proxies = append(proxies, network)
+		}
+	}
+	return proxies
+}
+
+func isTrustedProxy(proxies []*net.IPNet, host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP is the connecting address, or when that is a trusted proxy the last address in
+// X-Forwarded-For that is not one, so clients cannot spoof it by sending the header themselves
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	proxies := trustedProxies()
+	if !isTrustedProxy(proxies, host) {
+		return host
+	}
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !isTrustedProxy(proxies, hop) {
+			return hop
+		}
+		host = hop
+	}
+	return host
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
@@ -12,10 +13,22 @@ import (
 type contextKey string
 
 const (
-	EmailKey     contextKey = "email"
-	FirstNameKey contextKey = "first_name"
-	LastNameKey  contextKey = "last_name"
-	UidKey       contextKey = "uid"
+	EmailKey      contextKey = "email"
+	FirstNameKey  contextKey = "first_name"
+	LastNameKey   contextKey = "last_name"
+	UidKey        contextKey = "uid"
+	RoleKey       contextKey = "role"
+	OutletKey     contextKey = "outlet_id"
+	HeadOfficeKey contextKey = "head_office"
+)
+
+// Header head-office users pick an outlet with; without it they see every outlet
+const OutletHeader = "X-Outlet-Id"
+
+// Headers a POS terminal identifies itself with on PIN login and, for the key, on every request
+const (
+	TerminalIdHeader  = "X-Terminal-Id"
+	TerminalKeyHeader = "X-Terminal-Key"
 )
 
 // Authentication middleware for Gorilla Mux
@@ -41,11 +54,29 @@ func Authentication(next http.Handler) http.Handler {
 			return
 		}
 
+		// A POS token is only good on the terminal it was issued to
+		if claims.TerminalId != "" {
+			keyHash := helper.HashDeviceKey(r.Header.Get(TerminalKeyHeader))
+			if subtle.ConstantTimeCompare([]byte(keyHash), []byte(claims.KeyHash)) != 1 {
+				http.Error(w, "POS token used off its terminal", http.StatusUnauthorized)
+				return
+			}
+		}
+
 		// Store user details in the request context
 		ctx := context.WithValue(r.Context(), EmailKey, claims.Email)
 		ctx = context.WithValue(ctx, FirstNameKey, claims.FirstName)
 		ctx = context.WithValue(ctx, LastNameKey, claims.LastName)
 		ctx = context.WithValue(ctx, UidKey, claims.Uid)
+		ctx = context.WithValue(ctx, RoleKey, claims.Role)
+
+		// Everyone else is held to the outlet on their user record
+		outletId := claims.OutletId
+		if claims.HeadOffice {
+			outletId = r.Header.Get(OutletHeader)
+		}
+		ctx = context.WithValue(ctx, OutletKey, outletId)
+		ctx = context.WithValue(ctx, HeadOfficeKey, claims.HeadOffice)
 
 		// Pass modified request with context to the next handler
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -60,3 +91,42 @@ func GetUserFromContext(r *http.Request) (email, firstName, lastName, uid string
 	uid, _ = r.Context().Value(UidKey).(string)
 	return
 }
+
+// GetRoleFromContext retrieves the authenticated user's role from the request context
+func GetRoleFromContext(r *http.Request) string {
+	role, _ := r.Context().Value(RoleKey).(string)
+	return role
+}
+
+// GetOutletFromContext returns the outlet the request acts for and whether the user is head office.
+// A head-office user with no outlet picked gets "" and sees every outlet.
+func GetOutletFromContext(r *http.Request) (outletId string, headOffice bool) {
+	outletId, _ = r.Context().Value(OutletKey).(string)
+	headOffice, _ = r.Context().Value(HeadOfficeKey).(bool)
+	return
+}
+
+// RequireHeadOffice only lets head-office users reach the handler
+func RequireHeadOffice(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, headOffice := GetOutletFromContext(r); !headOffice {
+			http.Error(w, `{"success": false, "message": "Only head office can access this resource"}`, http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RequireRole only lets users with one of the given roles reach the handler
+func RequireRole(next http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		role := GetRoleFromContext(r)
+		for _, allowed := range roles {
+			if role == allowed {
+				next(w, r)
+				return
+			}
+		}
+		http.Error(w, `{"success": false, "message": "You do not have permission to access this resource"}`, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	helper "github.com/02priyeshraj/Hotel_Management_Backend/helper"
+)
+
+const (
+	GuestTableKey   contextKey = "guest_table_id"
+	GuestSessionKey contextKey = "guest_session_id"
+)
+
+// GuestAuthentication accepts only guest session tokens issued from a table QR code
+func GuestAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientToken := r.Header.Get("Authorization")
+		if clientToken == "" {
+			http.Error(w, "No Authorization header provided", http.StatusUnauthorized)
+			return
+		}
+
+		// Token format should be "Bearer <token>"
+		tokenParts := strings.Split(clientToken, " ")
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+			http.Error(w, "Invalid Authorization format", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := helper.ValidateGuestToken(tokenParts[1])
+		if err != "" {
+			http.Error(w, err, http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), GuestTableKey, claims.Table_id)
+		ctx = context.WithValue(ctx, GuestSessionKey, claims.Session_id)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetGuestFromContext retrieves the guest session's table from the request context
+func GetGuestFromContext(r *http.Request) (tableId, sessionId string) {
+	tableId, _ = r.Context().Value(GuestTableKey).(string)
+	sessionId, _ = r.Context().Value(GuestSessionKey).(string)
+	return
+}
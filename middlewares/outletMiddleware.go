@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	database "github.com/02priyeshraj/Hotel_Management_Backend/config"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var orderCollection = database.OpenCollection(database.Client, "order")
+
+// Path variables naming a document that belongs to an outlet, the collection it lives in, and
+// whether documents without an outlet are the master menu every outlet shares. Documents of an
+// order, such as KOTs, take their outlet from the order.
+var outletScopedEntities = []struct {
+	pathVar    string
+	collection *mongo.Collection
+	master     bool
+	viaOrder   bool
+}{
+	{"table_id", database.OpenCollection(database.Client, "table"), false, false},
+	{"menu_id", database.OpenCollection(database.Client, "menu"), true, false},
+	{"food_id", database.OpenCollection(database.Client, "food"), true, false},
+	{"order_id", orderCollection, false, false},
+	{"order_item_id", database.OpenCollection(database.Client, "orderitems"), false, true},
+	{"kot_id", database.OpenCollection(database.Client, "kot"), false, true},
+	{"invoice_id", database.OpenCollection(database.Client, "invoice"), false, false},
+	{"staff_id", database.OpenCollection(database.Client, "staff"), false, false},
+	{"shift_id", database.OpenCollection(database.Client, "shift"), false, false},
+	{"time_entry_id", database.OpenCollection(database.Client, "time_entry"), false, false},
+	{"terminal_id", database.OpenCollection(database.Client, "pos_terminal"), false, false},
+	{"station_id", database.OpenCollection(database.Client, "kitchen_station"), false, false},
+	{"waitlist_id", database.OpenCollection(database.Client, "waitlist"), false, false},
+}
+
+// Outlets change master foods for themselves only through these routes
+const foodOverridePrefix = "/food-overrides/"
+
+// OutletScope answers 404 for a table, menu, food, order, order item, KOT, invoice, staff record,
+// kitchen station or waitlist entry in the path that belongs to another outlet, so by-id routes
+// never reach across outlets. Outlets can read the master menu but only head office changes it.
+// List queries are scoped by the handlers.
+func OutletScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outletId, headOffice := GetOutletFromContext(r)
+		if headOffice && outletId == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		vars := mux.Vars(r)
+		opts := options.FindOne().SetProjection(bson.M{"outlet_id": 1, "order_id": 1})
+		for _, entity := range outletScopedEntities {
+			id := vars[entity.pathVar]
+			if id == "" {
+				continue
+			}
+			var doc struct {
+				Outlet_id string `bson:"outlet_id"`
+				Order_id  string `bson:"order_id"`
+			}
+			err := entity.collection.FindOne(ctx, bson.M{entity.pathVar: id}, opts).Decode(&doc)
+			if err == nil && entity.viaOrder {
+				var order struct {
+					Outlet_id string `bson:"outlet_id"`
+				}
+				// An order that is gone leaves the document with no outlet
+				if err = orderCollection.FindOne(ctx, bson.M{"order_id": doc.Order_id}, opts).Decode(&order); errors.Is(err, mongo.ErrNoDocuments) {
+					err = nil
+				}
+				doc.Outlet_id = order.Outlet_id
+			}
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				continue // the handler reports it missing
+			} else if err != nil {
+				http.Error(w, `{"success": false, "message": "Error checking outlet"}`, http.StatusInternalServerError)
+				return
+			}
+			// Checked first, so users without an outlet cannot change the master menu either
+			if entity.master && doc.Outlet_id == "" {
+				if r.Method == http.MethodGet || strings.HasPrefix(r.URL.Path, foodOverridePrefix) {
+					continue
+				}
+				http.Error(w, `{"success": false, "message": "The master menu is managed by head office, use a food override"}`, http.StatusForbidden)
+				return
+			}
+			if doc.Outlet_id == outletId {
+				continue
+			}
+			http.Error(w, `{"success": false, "message": "Not found"}`, http.StatusNotFound)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
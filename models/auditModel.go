@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditChange struct {
+	From interface{} `json:"from" bson:"from"`
+	To   interface{} `json:"to" bson:"to"`
+}
+
+// AuditLog records one create, update or delete made through the API
+type AuditLog struct {
+	ID          primitive.ObjectID     `bson:"_id,omitempty" json:"-"`
+	Audit_id    string                 `json:"audit_id" bson:"audit_id"`
+	Actor_id    string                 `json:"actor_id" bson:"actor_id"`
+	Actor_email string                 `json:"actor_email" bson:"actor_email"`
+	Actor_type  string                 `json:"actor_type" bson:"actor_type"` // actor_type field: user / guest / anonymous
+	Entity      string                 `json:"entity" bson:"entity"`
+	Entity_id   string                 `json:"entity_id" bson:"entity_id"`
+	Action      string                 `json:"action" bson:"action"` // action field: create / update / delete
+	Method      string                 `json:"method" bson:"method"`
+	Path        string                 `json:"path" bson:"path"`
+	Status_code int                    `json:"status_code" bson:"status_code"`
+	Before      bson.M                 `json:"before,omitempty" bson:"before,omitempty"`
+	After       bson.M                 `json:"after,omitempty" bson:"after,omitempty"`
+	Changes     map[string]AuditChange `json:"changes,omitempty" bson:"changes,omitempty"`
+	Ip          string                 `json:"ip" bson:"ip"`
+	Created_at  time.Time              `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CashDrawerSession is the cash drawer from its opening float to the counted close
+type CashDrawerSession struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Session_id    string             `bson:"session_id" json:"session_id"`
+	Outlet_id     string             `bson:"outlet_id,omitempty" json:"outlet_id,omitempty"`
+	Status        string             `bson:"status" json:"status"`               // OPEN / CLOSED
+	Business_date string             `bson:"business_date" json:"business_date"` // YYYY-MM-DD the drawer was opened on
+	Opening_float float64            `bson:"opening_float" json:"opening_float"`
+	Entries       []CashDrawerEntry  `bson:"entries" json:"entries"`
+	Opened_by     string             `bson:"opened_by" json:"opened_by"`
+	Opened_at     time.Time          `bson:"opened_at" json:"opened_at"`
+	Closed_by     string             `bson:"closed_by,omitempty" json:"closed_by,omitempty"`
+	Closed_at     *time.Time         `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	Cash_sales    float64            `bson:"cash_sales" json:"cash_sales"`     // CASH invoices paid while open, tips included
+	Cash_refunds  float64            `bson:"cash_refunds" json:"cash_refunds"` // credit notes on CASH invoices issued while open
+	Expected_cash *float64           `bson:"expected_cash,omitempty" json:"expected_cash,omitempty"`
+	Counted_cash  *float64           `bson:"counted_cash,omitempty" json:"counted_cash,omitempty"`
+	Variance      *float64           `bson:"variance,omitempty" json:"variance,omitempty"` // counted less expected; negative is a shortage
+	Close_notes   string             `bson:"close_notes,omitempty" json:"close_notes,omitempty"`
+}
+
+// CashDrawerEntry is cash put into (CASH_IN) or taken out of (CASH_OUT) the drawer outside of a sale
+type CashDrawerEntry struct {
+	Entry_id   string    `bson:"entry_id" json:"entry_id"`
+	Type       string    `bson:"type" json:"type"`
+	Amount     float64   `bson:"amount" json:"amount"`
+	Reason     string    `bson:"reason" json:"reason"`
+	Created_by string    `bson:"created_by" json:"created_by"`
+	Created_at time.Time `bson:"created_at" json:"created_at"`
+}
+
+// ZReport is the end-of-day summary of a business date. Once it exists the date is closed
+// and its invoices can no longer be edited.
+type ZReport struct {
+	ID               primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Z_number         int64                `bson:"z_number" json:"z_number"`
+	Outlet_id        string               `bson:"outlet_id,omitempty" json:"outlet_id,omitempty"`
+	Business_date    string               `bson:"business_date" json:"business_date"`
+	Invoices         int64                `bson:"invoices" json:"invoices"`
+	Pending_invoices int64                `bson:"pending_invoices" json:"pending_invoices"` // left unpaid at close; dates with pending invoices can no longer be closed
+	Voided_invoices  int64                `bson:"voided_invoices" json:"voided_invoices"`
+	Gross_sales      float64              `bson:"gross_sales" json:"gross_sales"`
+	Discounts        float64              `bson:"discounts" json:"discounts"`
+	Refunds          float64              `bson:"refunds" json:"refunds"`                 // money returned, taxes included
+	Refunds_pre_tax  float64              `bson:"refunds_pre_tax" json:"refunds_pre_tax"` // what net sales take off
+	Net_sales        float64              `bson:"net_sales" json:"net_sales"`
+	Taxes            float64              `bson:"taxes" json:"taxes"`
+	Total_sales      float64              `bson:"total_sales" json:"total_sales"`
+	Tips             float64              `bson:"tips" json:"tips"`
+	Payment_methods  []PaymentMethodTotal `bson:"payment_methods" json:"payment_methods"`
+	Drawer_sessions  []ZReportDrawer      `bson:"drawer_sessions" json:"drawer_sessions"`
+	Cash_variance    float64              `bson:"cash_variance" json:"cash_variance"`
+	Closed_by        string               `bson:"closed_by" json:"closed_by"`
+	Created_at       time.Time            `bson:"created_at" json:"created_at"`
+}
+
+// PaymentMethodTotal is what was taken through one payment method
+type PaymentMethodTotal struct {
+	Method   string  `bson:"method" json:"method"`
+	Invoices int64   `bson:"invoices" json:"invoices"`
+	Amount   float64 `bson:"amount" json:"amount"`
+}
+
+// ZReportDrawer is a drawer session closed on the business date
+type ZReportDrawer struct {
+	Session_id    string  `bson:"session_id" json:"session_id"`
+	Opened_by     string  `bson:"opened_by" json:"opened_by"`
+	Closed_by     string  `bson:"closed_by" json:"closed_by"`
+	Expected_cash float64 `bson:"expected_cash" json:"expected_cash"`
+	Counted_cash  float64 `bson:"counted_cash" json:"counted_cash"`
+	Variance      float64 `bson:"variance" json:"variance"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreditNote records money returned against a paid invoice
+type CreditNote struct {
+	ID                 primitive.ObjectID `bson:"_id,omitempty"`
+	Credit_note_id     string             `json:"credit_note_id" bson:"credit_note_id"`
+	Invoice_id         string             `json:"invoice_id" bson:"invoice_id"`
+	Order_id           *string            `json:"order_id" bson:"order_id"`
+	Outlet_id          string             `json:"outlet_id,omitempty" bson:"outlet_id,omitempty"`
+	Amount             float64            `json:"amount" bson:"amount"`
+	Reason             string             `json:"reason" bson:"reason"`
+	Full_refund        bool               `json:"full_refund" bson:"full_refund"`
+	Approved_by        string             `json:"approved_by" bson:"approved_by"`
+	Approved_by_email  string             `json:"approved_by_email" bson:"approved_by_email"`
+	Provider_refund_id string             `json:"provider_refund_id,omitempty" bson:"provider_refund_id,omitempty"` // set when the money went back through a payment provider
+	Gift_card_entry_id string             `json:"gift_card_entry_id,omitempty" bson:"gift_card_entry_id,omitempty"` // set when the money went back onto a gift card
+	Created_at         time.Time          `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CustomerProfile holds what staff record about a customer; visits and spend come from their orders
+type CustomerProfile struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	User_id    string             `json:"user_id" bson:"user_id"`
+	Allergies  []string           `json:"allergies" bson:"allergies"`
+	Notes      string             `json:"notes" bson:"notes" validate:"max=1000"`
+	Updated_by string             `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	Updated_at time.Time          `json:"updated_at" bson:"updated_at"`
+}
@@ -11,9 +11,14 @@ type Food struct {
 	Food_id      string             `json:"food_id"`
 	Name         *string            `json:"name" validate:"required,min=2,max=100"`
 	Price        *float64           `json:"price" validate:"required"`
+	Cost         *float64           `json:"cost,omitempty" bson:"cost,omitempty" validate:"omitempty,gte=0"` // recipe cost per portion, for menu engineering
 	Food_image   *string            `json:"food_image"`
+	Available    *bool              `json:"available,omitempty" bson:"available,omitempty"` // nil means available
 	Menu_id      *string            `json:"menu_id" validate:"required"`
 	Created_at   time.Time          `json:"created_at"`
 	Updated_at   time.Time          `json:"updated_at"`
 	UniqueFoodID string             `bson:"unique_food_id" json:"unique_food_id"`
+	Outlet_id    string             `json:"outlet_id,omitempty" bson:"outlet_id,omitempty"`
+	Deleted_at   *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	Deleted_by   *string            `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
 }
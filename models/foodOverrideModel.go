@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FoodOverride is an outlet's change to a food of the master menu. Unset fields follow the master,
+// so the master can change underneath without losing the outlet's own settings.
+type FoodOverride struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Outlet_id  string             `bson:"outlet_id" json:"outlet_id"`
+	Food_id    string             `bson:"food_id" json:"food_id"`
+	Price      *float64           `bson:"price,omitempty" json:"price,omitempty" validate:"omitempty,gt=0"`
+	Available  *bool              `bson:"available,omitempty" json:"available,omitempty"`
+	Hidden     bool               `bson:"hidden" json:"hidden"` // left off the outlet's menu altogether
+	Updated_by string             `bson:"updated_by" json:"updated_by"`
+	Updated_at time.Time          `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GiftCard is a house gift card; Balance is kept in step with its ledger
+type GiftCard struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Gift_card_id   string             `bson:"gift_card_id" json:"gift_card_id"`
+	Code           string             `bson:"code" json:"code"`
+	Initial_amount float64            `bson:"initial_amount" json:"initial_amount"`
+	Balance        float64            `bson:"balance" json:"balance"`
+	Expires_at     *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	Issued_by      string             `bson:"issued_by" json:"issued_by"`
+	Created_at     time.Time          `bson:"created_at" json:"created_at"`
+	Updated_at     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// GiftCardEntry is one movement on a gift card: ISSUE and REFUND add to the balance, REDEEM takes from it
+type GiftCardEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Entry_id      string             `bson:"entry_id" json:"entry_id"`
+	Gift_card_id  string             `bson:"gift_card_id" json:"gift_card_id"`
+	Code          string             `bson:"code" json:"code"`
+	Type          string             `bson:"type" json:"type"`
+	Amount        float64            `bson:"amount" json:"amount"`
+	Balance_after float64            `bson:"balance_after" json:"balance_after"`
+	Invoice_id    string             `bson:"invoice_id,omitempty" json:"invoice_id,omitempty"`
+	Created_by    string             `bson:"created_by" json:"created_by"`
+	Created_at    time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -7,14 +7,54 @@ import (
 )
 
 type Invoice struct {
-	ID             primitive.ObjectID `bson:"_id,omitempty"`
-	Invoice_id     string             `json:"invoice_id"`
-	Order_id       *string            `json:"order_id"`
-	User_id        *string            `json:"user_id"`
-	Payment_method *string            `json:"payment_method" validate:"eq=CARD|eq=CASH|eq="`
-	Payment_status *string            `json:"payment_status" validate:"required,eq=PENDING|eq=PAID"`
-	TotalPrice     float64            `json:"total_price" bson:"total_price"`
-	Payment_date   time.Time          `json:"payment_date"`
-	Created_at     time.Time          `json:"created_at"`
-	Updated_at     time.Time          `json:"updated_at"`
+	ID                      primitive.ObjectID `bson:"_id,omitempty"`
+	Invoice_id              string             `json:"invoice_id"`
+	Invoice_number          string             `json:"invoice_number" bson:"invoice_number,omitempty"` // sequential tax invoice number, e.g. BLR1/2026-27/000123
+	Order_id                *string            `json:"order_id"`
+	User_id                 *string            `json:"user_id"`
+	Outlet_id               string             `json:"outlet_id,omitempty" bson:"outlet_id,omitempty"`
+	Payment_method          *string            `json:"payment_method" validate:"eq=CARD|eq=CASH|eq=UPI|eq=WALLET|eq=GIFT_CARD|eq="`
+	Payment_details         *PaymentDetails    `json:"payment_details,omitempty" bson:"payment_details,omitempty"`
+	Payment_status          *string            `json:"payment_status" validate:"required,eq=PENDING|eq=PAID"` // VOID / REFUNDED / PARTIALLY_REFUNDED are set by the void and refund workflow
+	Subtotal                float64            `json:"subtotal" bson:"subtotal"`
+	Discount_amount         float64            `json:"discount_amount" bson:"discount_amount,omitempty"` // taken off the subtotal before taxes
+	Discount_reason         string             `json:"discount_reason,omitempty" bson:"discount_reason,omitempty"`
+	Customer_id             string             `json:"customer_id,omitempty" bson:"customer_id,omitempty"` // customer of the order, who earns and redeems points
+	Loyalty_points_redeemed int64              `json:"loyalty_points_redeemed,omitempty" bson:"loyalty_points_redeemed,omitempty"`
+	Loyalty_discount        float64            `json:"loyalty_discount,omitempty" bson:"loyalty_discount,omitempty"` // the redeemed points' value, part of discount_amount
+	Loyalty_points_earned   int64              `json:"loyalty_points_earned,omitempty" bson:"loyalty_points_earned,omitempty"`
+	Taxes                   []TaxLine          `json:"taxes,omitempty" bson:"taxes,omitempty"`
+	Tax_amount              float64            `json:"tax_amount" bson:"tax_amount"`
+	TotalPrice              float64            `json:"total_price" bson:"total_price"` // subtotal plus taxes
+	Refunded_total          float64            `json:"refunded_total" bson:"refunded_total"`
+	Tip_amount              float64            `json:"tip_amount" bson:"tip_amount,omitempty"`                 // paid on top of total_price, not revenue
+	Tip_percent             float64            `json:"tip_percent,omitempty" bson:"tip_percent,omitempty"`     // set when the tip was given as a percentage of the subtotal
+	Tip_server_id           string             `json:"tip_server_id,omitempty" bson:"tip_server_id,omitempty"` // server the tip is attributed to
+	Payment_provider        string             `json:"payment_provider,omitempty" bson:"payment_provider,omitempty"`
+	Payment_intent_id       string             `json:"payment_intent_id,omitempty" bson:"payment_intent_id,omitempty"`
+	Payment_reference       string             `json:"payment_reference,omitempty" bson:"payment_reference,omitempty"` // provider transaction reference
+	Void_reason             *string            `json:"void_reason,omitempty" bson:"void_reason,omitempty"`
+	Voided_by               *string            `json:"voided_by,omitempty" bson:"voided_by,omitempty"`
+	Voided_at               *time.Time         `json:"voided_at,omitempty" bson:"voided_at,omitempty"`
+	Payment_date            time.Time          `json:"payment_date"`
+	Created_at              time.Time          `json:"created_at"`
+	Updated_at              time.Time          `json:"updated_at"`
+	Deleted_at              *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	Deleted_by              *string            `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+}
+
+// PaymentDetails holds the method-specific reference for a payment
+type PaymentDetails struct {
+	Upi_reference    string `json:"upi_reference,omitempty" bson:"upi_reference,omitempty" validate:"omitempty,alphanum,max=35"` // UTR / transaction id from the UPI app
+	Card_last4       string `json:"card_last4,omitempty" bson:"card_last4,omitempty" validate:"omitempty,len=4,numeric"`
+	Wallet_provider  string `json:"wallet_provider,omitempty" bson:"wallet_provider,omitempty" validate:"omitempty,max=30"`
+	Wallet_reference string `json:"wallet_reference,omitempty" bson:"wallet_reference,omitempty" validate:"omitempty,max=64"`
+	Gift_card_code   string `json:"gift_card_code,omitempty" bson:"gift_card_code,omitempty" validate:"omitempty,max=32"`
+}
+
+// TaxLine is one tax charged on an invoice, e.g. CGST at 2.5%
+type TaxLine struct {
+	Name   string  `json:"name" bson:"name"`
+	Rate   float64 `json:"rate" bson:"rate"` // percent
+	Amount float64 `json:"amount" bson:"amount"`
 }
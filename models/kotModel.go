@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kot is a kitchen order ticket: the items of one order item batch that go to one station
+type Kot struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Kot_id          string             `json:"kot_id" bson:"kot_id"`
+	Kot_number      int64              `json:"kot_number" bson:"kot_number"` // sequential per kot_date
+	Kot_date        string             `json:"kot_date" bson:"kot_date"`     // YYYY-MM-DD
+	Order_id        string             `json:"order_id" bson:"order_id"`
+	Order_item_id   string             `json:"order_item_id" bson:"order_item_id"`
+	Table_id        string             `json:"table_id" bson:"table_id"`
+	Table_number    int                `json:"table_number" bson:"table_number"`
+	Waiter_id       string             `json:"waiter_id" bson:"waiter_id"`
+	Waiter_name     string             `json:"waiter_name" bson:"waiter_name"`
+	Station_id      string             `json:"station_id" bson:"station_id"`
+	Station_name    string             `json:"station_name" bson:"station_name"`
+	Items           []KotItem          `json:"items" bson:"items"`
+	Amendment       bool               `json:"amendment,omitempty" bson:"amendment,omitempty"` // items are quantity changes to earlier tickets, negative for less
+	Notes           string             `json:"notes,omitempty" bson:"notes,omitempty"`
+	Status          string             `json:"status" bson:"status"` // ACTIVE / CANCELLED
+	Cancel_reason   string             `json:"cancel_reason,omitempty" bson:"cancel_reason,omitempty"`
+	Cancelled_by    string             `json:"cancelled_by,omitempty" bson:"cancelled_by,omitempty"`
+	Cancelled_at    *time.Time         `json:"cancelled_at,omitempty" bson:"cancelled_at,omitempty"`
+	Print_count     int                `json:"print_count" bson:"print_count"`
+	Last_printed_at *time.Time         `json:"last_printed_at,omitempty" bson:"last_printed_at,omitempty"`
+	Print_error     string             `json:"print_error,omitempty" bson:"print_error,omitempty"`
+	Created_at      time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type KotItem struct {
+	Food_id  string `json:"food_id" bson:"food_id"`
+	Name     string `json:"name" bson:"name"`
+	Quantity int    `json:"quantity" bson:"quantity"`
+}
+
+// KitchenStation is a preparation area with its own ticket printer, fed by menu categories
+type KitchenStation struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Station_id      string             `json:"station_id" bson:"station_id"`
+	Name            *string            `json:"name" bson:"name" validate:"required,min=2,max=50"`
+	Categories      []string           `json:"categories" bson:"categories"`           // menu categories prepared here
+	Printer_address string             `json:"printer_address" bson:"printer_address"` // ip:port of a raw TCP (port 9100) printer on a private network
+	Paper_width     int                `json:"paper_width" bson:"paper_width" validate:"omitempty,oneof=58 80"`
+	Default         bool               `json:"default" bson:"default"` // receives items whose category no station claims
+	Outlet_id       string             `json:"outlet_id,omitempty" bson:"outlet_id,omitempty"`
+	Created_at      time.Time          `json:"created_at" bson:"created_at"`
+	Updated_at      time.Time          `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoyaltyAccount is a customer's points; Balance is kept in step with their ledger
+type LoyaltyAccount struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	User_id         string             `bson:"user_id" json:"user_id"`
+	Balance         int64              `bson:"balance" json:"balance"`
+	Lifetime_points int64              `bson:"lifetime_points" json:"lifetime_points"` // earned less reversed, sets the tier
+	Created_at      time.Time          `bson:"created_at" json:"created_at"`
+	Updated_at      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// LoyaltyEntry is one movement of points. EARN and RETURN add points that expire, REDEEM, REVERSE
+// and EXPIRE take them away, oldest first.
+type LoyaltyEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Entry_id      string             `bson:"entry_id" json:"entry_id"`
+	User_id       string             `bson:"user_id" json:"user_id"`
+	Type          string             `bson:"type" json:"type"`
+	Points        int64              `bson:"points" json:"points"` // negative when taken away
+	Balance_after int64              `bson:"balance_after" json:"balance_after"`
+	Remaining     int64              `bson:"remaining,omitempty" json:"remaining,omitempty"` // points of an EARN or RETURN still to use
+	Expires_at    *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	Invoice_id    string             `bson:"invoice_id,omitempty" json:"invoice_id,omitempty"`
+	Tier          string             `bson:"tier,omitempty" json:"tier,omitempty"` // tier the points were earned at
+	Created_by    string             `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	Created_at    time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -14,4 +14,7 @@ type Menu struct {
 	Created_at time.Time          `json:"created_at" bson:"created_at"`
 	Updated_at time.Time          `json:"updated_at" bson:"updated_at"`
 	Menu_id    string             `json:"menu_id" bson:"menu_id"`
+	Outlet_id  string             `json:"outlet_id,omitempty" bson:"outlet_id,omitempty"`
+	Deleted_at *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	Deleted_by *string            `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
 }
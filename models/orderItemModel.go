@@ -15,4 +15,15 @@ type OrderItem struct {
 	Order_item_id string             `bson:"order_item_id" json:"order_item_id"`
 	Order_id      string             `bson:"order_id" json:"order_id" validate:"required"`
 	Table_id      string             `bson:"table_id" json:"table_id" validate:"required"`
+	Lines         []OrderLine        `bson:"lines,omitempty" json:"lines,omitempty"` // priced lines captured when the items were ordered
+	Notes         string             `bson:"notes,omitempty" json:"notes,omitempty"` // kitchen instructions printed on the KOT
+}
+
+// OrderLine is one food on an order item, priced at the time it was ordered
+type OrderLine struct {
+	Food_id    string  `bson:"food_id" json:"food_id"`
+	Name       string  `bson:"name" json:"name"`
+	Quantity   int     `bson:"quantity" json:"quantity"`
+	Unit_price float64 `bson:"unit_price" json:"unit_price"`
+	Amount     float64 `bson:"amount" json:"amount"`
 }
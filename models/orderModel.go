@@ -7,12 +7,20 @@ import (
 )
 
 type Order struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty"`
-	Order_Date time.Time          `json:"order_date" validate:"required"`
-	Created_at time.Time          `json:"created_at"`
-	Updated_at time.Time          `json:"updated_at"`
-	Order_id   string             `json:"order_id"`
-	Table_id   *string            `json:"table_id" validate:"required"`
-	User_id    *string            `json:"user_id" validate:"required"`
-	Status     string             `json:"status" bson:"status"` //status field: Pending / Placed / Confirmed / Preparing / Served / Piad / Cancelled / Rejected
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Order_Date  time.Time          `json:"order_date" validate:"required"`
+	Created_at  time.Time          `json:"created_at"`
+	Updated_at  time.Time          `json:"updated_at"`
+	Order_id    string             `json:"order_id"`
+	Table_id    *string            `json:"table_id" validate:"required"`
+	User_id     *string            `json:"user_id" validate:"required"`
+	Covers      *int               `json:"covers,omitempty" bson:"covers,omitempty" validate:"omitempty,min=1,max=100"` // guests seated for the order
+	Waiter_id   *string            `json:"waiter_id,omitempty" bson:"waiter_id,omitempty"`                              // server looking after the table, the order's user when missing
+	Customer_id *string            `json:"customer_id,omitempty" bson:"customer_id,omitempty"`                          // customer the party is, for their profile
+	Status      string             `json:"status" bson:"status"`                                                        //status field: Pending / Placed / Confirmed / Preparing / Served / Piad / Cancelled / Rejected / Refunded
+	Outlet_id   string             `json:"outlet_id,omitempty" bson:"outlet_id,omitempty"`
+
+	Bill_requested_at *time.Time `json:"bill_requested_at,omitempty" bson:"bill_requested_at,omitempty"`
+	Deleted_at        *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	Deleted_by        *string    `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
 }
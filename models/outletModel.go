@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Outlet is one restaurant of the group. Tables, menus, foods, orders and invoices belong to one.
+type Outlet struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Outlet_id      string             `bson:"outlet_id" json:"outlet_id"`
+	Code           string             `bson:"code" json:"code" validate:"required,alphanum,min=2,max=10"` // short code, e.g. BLR1; keys the receipt template
+	Name           string             `bson:"name" json:"name" validate:"required,min=2,max=100"`
+	Address        string             `bson:"address" json:"address" validate:"max=300"`
+	Phone          string             `bson:"phone" json:"phone" validate:"max=20"`
+	Invoice_prefix string             `bson:"invoice_prefix,omitempty" json:"invoice_prefix,omitempty" validate:"omitempty,max=20"` // the code when empty
+	Created_at     time.Time          `bson:"created_at" json:"created_at"`
+	Updated_at     time.Time          `bson:"updated_at" json:"updated_at"`
+	Deleted_at     *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	Deleted_by     *string            `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PosTerminal is a shared device at an outlet that staff switch onto with their PIN.
+// It proves itself with the device key handed out when it was registered.
+type PosTerminal struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Terminal_id      string             `bson:"terminal_id" json:"terminal_id"`
+	Outlet_id        string             `bson:"outlet_id,omitempty" json:"outlet_id,omitempty"`
+	Name             string             `bson:"name" json:"name" validate:"required,min=2,max=50"`
+	Key_hash         string             `bson:"key_hash" json:"-"`                                            // SHA-256 of the device key
+	Session_id       string             `bson:"session_id,omitempty" json:"-"`                                // the only POS token accepted from the terminal
+	Session_staff_id string             `bson:"session_staff_id,omitempty" json:"session_staff_id,omitempty"` // who is signed in now
+	Failed_logins    int                `bson:"failed_logins,omitempty" json:"failed_logins,omitempty"`       // wrong codes or PINs in a row
+	Locked_until     *time.Time         `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	Last_login_at    *time.Time         `bson:"last_login_at,omitempty" json:"last_login_at,omitempty"`
+	Created_by       string             `bson:"created_by" json:"created_by"`
+	Created_at       time.Time          `bson:"created_at" json:"created_at"`
+	Updated_at       time.Time          `bson:"updated_at" json:"updated_at"`
+	Deleted_at       *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	Deleted_by       *string            `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReceiptTemplate holds the per-outlet text printed on invoices and receipts
+type ReceiptTemplate struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Outlet_code     string             `json:"outlet_code" bson:"outlet_code"`
+	Restaurant_name string             `json:"restaurant_name" bson:"restaurant_name" validate:"required,max=64"`
+	Address_lines   []string           `json:"address_lines" bson:"address_lines"`
+	Phone           string             `json:"phone" bson:"phone"`
+	Tax_id          string             `json:"tax_id" bson:"tax_id"` // e.g. GSTIN, printed under the header
+	Currency        string             `json:"currency" bson:"currency"`
+	Footer_lines    []string           `json:"footer_lines" bson:"footer_lines"`
+	Updated_by      string             `json:"updated_by" bson:"updated_by"`
+	Updated_at      time.Time          `json:"updated_at" bson:"updated_at"`
+}
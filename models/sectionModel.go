@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SectionAssignment puts a server in charge of a floor section of an outlet. Tables of the section
+// go to them unless the table has a server of its own.
+type SectionAssignment struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Outlet_id   string             `bson:"outlet_id,omitempty" json:"outlet_id,omitempty"`
+	Section     string             `bson:"section" json:"section"`
+	Server_id   string             `bson:"server_id" json:"server_id"` // user_id of the server
+	Assigned_by string             `bson:"assigned_by" json:"assigned_by"`
+	Assigned_at time.Time          `bson:"assigned_at" json:"assigned_at"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Staff is an employee of an outlet. Unlike User it need not have a login: staff clock in and out
+// at the outlet with their employee code and PIN.
+type Staff struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Staff_id         string             `bson:"staff_id" json:"staff_id"`
+	Employee_code    string             `bson:"employee_code" json:"employee_code" validate:"required,alphanum,min=2,max=20"` // unique across outlets
+	First_name       string             `bson:"first_name" json:"first_name" validate:"required,min=2,max=100"`
+	Last_name        string             `bson:"last_name" json:"last_name" validate:"required,min=1,max=100"`
+	Role             string             `bson:"role" json:"role" validate:"required,min=2,max=30"` // job role, e.g. SERVER, COOK, CASHIER
+	Hourly_rate      float64            `bson:"hourly_rate" json:"hourly_rate" validate:"gte=0"`
+	Outlet_id        string             `bson:"outlet_id,omitempty" json:"outlet_id,omitempty"`
+	User_id          *string            `bson:"user_id,omitempty" json:"user_id,omitempty"`                       // login account, if the employee has one
+	Pin              string             `bson:"pin" json:"pin,omitempty" validate:"required,numeric,min=4,max=6"` // bcrypt hash once stored, never returned
+	Pin_failures     int                `bson:"pin_failures,omitempty" json:"pin_failures,omitempty"`             // wrong PINs in a row
+	Pin_locked_until *time.Time         `bson:"pin_locked_until,omitempty" json:"pin_locked_until,omitempty"`
+	Created_at       time.Time          `bson:"created_at" json:"created_at"`
+	Updated_at       time.Time          `bson:"updated_at" json:"updated_at"`
+	Deleted_at       *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	Deleted_by       *string            `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
+
+// Shift is a scheduled stretch of work for one employee
+type Shift struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Shift_id   string             `bson:"shift_id" json:"shift_id"`
+	Staff_id   string             `bson:"staff_id" json:"staff_id" validate:"required"`
+	Outlet_id  string             `bson:"outlet_id,omitempty" json:"outlet_id,omitempty"`
+	Start      time.Time          `bson:"start" json:"start" validate:"required"`
+	End        time.Time          `bson:"end" json:"end" validate:"required"`
+	Notes      string             `bson:"notes,omitempty" json:"notes,omitempty" validate:"max=300"`
+	Created_by string             `bson:"created_by" json:"created_by"`
+	Created_at time.Time          `bson:"created_at" json:"created_at"`
+	Updated_at time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// TimeEntry is one clock-in to clock-out of an employee, with the breaks taken in between
+type TimeEntry struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Time_entry_id  string             `bson:"time_entry_id" json:"time_entry_id"`
+	Staff_id       string             `bson:"staff_id" json:"staff_id"`
+	Employee_code  string             `bson:"employee_code" json:"employee_code"`
+	Outlet_id      string             `bson:"outlet_id,omitempty" json:"outlet_id,omitempty"`
+	Shift_id       string             `bson:"shift_id,omitempty" json:"shift_id,omitempty"` // scheduled shift clocked in for, if any
+	Status         string             `bson:"status" json:"status"`                         // OPEN / CLOSED
+	Clock_in       time.Time          `bson:"clock_in" json:"clock_in"`
+	Clock_out      *time.Time         `bson:"clock_out,omitempty" json:"clock_out,omitempty"`
+	Breaks         []Break            `bson:"breaks" json:"breaks"`
+	Hourly_rate    float64            `bson:"hourly_rate" json:"hourly_rate"`       // rate at clock-in, so later raises do not reprice past work
+	Worked_minutes float64            `bson:"worked_minutes" json:"worked_minutes"` // clocked time less breaks, set at clock-out
+	Break_minutes  float64            `bson:"break_minutes" json:"break_minutes"`
+	Adjusted_by    string             `bson:"adjusted_by,omitempty" json:"adjusted_by,omitempty"` // manager who last corrected the times
+	Adjust_reason  string             `bson:"adjust_reason,omitempty" json:"adjust_reason,omitempty"`
+	Updated_at     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Break is an unpaid break within a time entry; End is nil while it lasts
+type Break struct {
+	Start time.Time  `bson:"start" json:"start"`
+	End   *time.Time `bson:"end,omitempty" json:"end,omitempty"`
+}
@@ -10,8 +10,24 @@ type Table struct {
 	ID               primitive.ObjectID `bson:"_id"`
 	Number_of_guests *int               `json:"number_of_guests" bson:"number_of_guests" validate:"required"`
 	Table_number     *int               `json:"table_number" bson:"table_number" validate:"required"`
-	Status           string             `json:"status" bson:"status"` // status field: Reserved / Not Reserved
+	Status           string             `json:"status" bson:"status"` // status field: Available / Reserved / Seated / Ordering / Waiting For Bill / Needs Cleaning / Out Of Service
 	Created_at       time.Time          `json:"created_at" bson:"created_at"`
 	Updated_at       time.Time          `json:"updated_at" bson:"updated_at"`
 	Table_id         string             `json:"table_id" bson:"table_id"`
+	Qr_version       int                `json:"qr_version" bson:"qr_version"` // bumped to rotate the table's QR code
+	Outlet_id        string             `json:"outlet_id,omitempty" bson:"outlet_id,omitempty"`
+
+	Seated_at         *time.Time `json:"seated_at,omitempty" bson:"seated_at,omitempty"`
+	Status_changed_at *time.Time `json:"status_changed_at,omitempty" bson:"status_changed_at,omitempty"`
+
+	// Floor plan layout, number_of_guests doubles as the seat count
+	Section    string   `json:"section" bson:"section"`
+	Position_x *float64 `json:"position_x" bson:"position_x"`
+	Position_y *float64 `json:"position_y" bson:"position_y"`
+	Shape      string   `json:"shape" bson:"shape" validate:"omitempty,oneof=Square Round Rectangle"`
+	Server_id  string   `json:"server_id,omitempty" bson:"server_id,omitempty"` // server of the table, over its section's
+
+	// Merging: secondary tables point at the primary, the primary lists its secondaries
+	Merged_into   string   `json:"merged_into,omitempty" bson:"merged_into,omitempty"`
+	Merged_tables []string `json:"merged_tables,omitempty" bson:"merged_tables,omitempty"`
 }
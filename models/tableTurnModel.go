@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TableTurn records one seating of a table, from guests sitting down to the bill being paid
+type TableTurn struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	Turn_id      string             `json:"turn_id" bson:"turn_id"`
+	Table_id     string             `json:"table_id" bson:"table_id"`
+	Order_id     string             `json:"order_id" bson:"order_id"`
+	Seated_at    time.Time          `json:"seated_at" bson:"seated_at"`
+	Cleared_at   time.Time          `json:"cleared_at" bson:"cleared_at"`
+	Turn_minutes float64            `json:"turn_minutes" bson:"turn_minutes"`
+	Created_at   time.Time          `json:"created_at" bson:"created_at"`
+}
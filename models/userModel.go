@@ -18,4 +18,7 @@ type User struct {
 	Created_at    time.Time          `json:"created_at"`
 	Updated_at    time.Time          `json:"updated_at"`
 	User_id       string             `json:"user_id"`
+	Role          *string            `json:"role" validate:"omitempty,eq=ADMIN|eq=MANAGER|eq=STAFF|eq=USER"` // access role, USER when missing
+	Outlet_id     *string            `json:"outlet_id,omitempty" bson:"outlet_id,omitempty"`                 // outlet the user works at
+	Head_office   bool               `json:"head_office,omitempty" bson:"head_office,omitempty"`             // sees every outlet
 }
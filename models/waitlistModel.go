@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WaitlistEntry struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	Waitlist_id    string             `json:"waitlist_id" bson:"waitlist_id"`
+	Party_name     *string            `json:"party_name" bson:"party_name" validate:"required,min=2,max=100"`
+	Party_size     *int               `json:"party_size" bson:"party_size" validate:"required,min=1"`
+	Phone          *string            `json:"phone" bson:"phone" validate:"required"`
+	Quoted_minutes int                `json:"quoted_minutes" bson:"quoted_minutes"`
+	Quoted_at      time.Time          `json:"quoted_at" bson:"quoted_at"`
+	Status         string             `json:"status" bson:"status"` // status field: Waiting / Seated / Cancelled
+	Table_id       string             `json:"table_id,omitempty" bson:"table_id,omitempty"`
+	Seated_at      *time.Time         `json:"seated_at,omitempty" bson:"seated_at,omitempty"`
+	Outlet_id      string             `json:"outlet_id,omitempty" bson:"outlet_id,omitempty"`
+	Created_at     time.Time          `json:"created_at" bson:"created_at"`
+	Updated_at     time.Time          `json:"updated_at" bson:"updated_at"`
+}
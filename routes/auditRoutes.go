@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/gorilla/mux"
+)
+
+func AuditProtectedRoutes(router *mux.Router) {
+	router.HandleFunc("/audit", middleware.RequireRole(middleware.RequireHeadOffice(controller.GetAuditLogs), "ADMIN")).Methods(http.MethodGet)
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/gorilla/mux"
+)
+
+func CashDrawerProtectedRoutes(router *mux.Router) {
+
+	router.HandleFunc("/cash-drawer/open", middleware.RequireRole(controller.OpenCashDrawer, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/cash-drawer/current", middleware.RequireRole(controller.GetCurrentCashDrawer, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/cash-drawer/entries", middleware.RequireRole(controller.AddCashDrawerEntry, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/cash-drawer/close", middleware.RequireRole(controller.CloseCashDrawer, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/cash-drawer/sessions", middleware.RequireRole(controller.GetCashDrawerSessions, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/cash-drawer/sessions/{session_id}", middleware.RequireRole(controller.GetCashDrawerSession, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/gorilla/mux"
+)
+
+func CustomerProtectedRoutes(router *mux.Router) {
+
+	router.HandleFunc("/customers", middleware.RequireRole(controller.FindCustomers, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/customers/{user_id}", middleware.RequireRole(controller.GetCustomer, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/customers/{user_id}", middleware.RequireRole(controller.UpdateCustomerProfile, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+
+	router.HandleFunc("/customers/{user_id}/loyalty", middleware.RequireRole(controller.GetCustomerLoyalty, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/customers/{user_id}/loyalty/history", middleware.RequireRole(controller.GetCustomerLoyaltyHistory, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodGet)
+
+	router.HandleFunc("/loyalty", controller.GetMyLoyalty).Methods(http.MethodGet)
+	router.HandleFunc("/loyalty/history", controller.GetMyLoyaltyHistory).Methods(http.MethodGet)
+}
@@ -4,17 +4,23 @@ import (
 	"net/http"
 
 	controllers "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
 	"github.com/gorilla/mux"
 )
 
 func FoodProtectedRoutes(router *mux.Router) {
 
 	router.HandleFunc("/foods", controllers.GetFoods).Methods(http.MethodGet)
-	router.HandleFunc("/foods", controllers.CreateFood).Methods(http.MethodPost)
+	router.HandleFunc("/foods", middleware.RequireRole(controllers.CreateFood, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
 
 	router.HandleFunc("/foods/{food_id}", controllers.GetFood).Methods(http.MethodGet)
-	router.HandleFunc("/foods/{food_id}", controllers.UpdateFood).Methods(http.MethodPatch)
-	router.HandleFunc("/foods/{food_id}", controllers.DeleteFood).Methods(http.MethodDelete)
+	router.HandleFunc("/foods/{food_id}", middleware.RequireRole(controllers.UpdateFood, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/foods/{food_id}", middleware.RequireRole(controllers.DeleteFood, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodDelete)
+	router.HandleFunc("/foods/{food_id}/restore", middleware.RequireRole(controllers.RestoreFood, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
 
 	router.HandleFunc("/foods/menu/{menu_id}", controllers.GetFoodsByMenu).Methods(http.MethodGet)
+
+	router.HandleFunc("/food-overrides", middleware.RequireRole(controllers.GetFoodOverrides, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/food-overrides/{food_id}", middleware.RequireRole(controllers.SetFoodOverride, "MANAGER", "ADMIN")).Methods(http.MethodPut)
+	router.HandleFunc("/food-overrides/{food_id}", middleware.RequireRole(controllers.DeleteFoodOverride, "MANAGER", "ADMIN")).Methods(http.MethodDelete)
 }
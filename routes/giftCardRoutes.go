@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/gorilla/mux"
+)
+
+func GiftCardProtectedRoutes(router *mux.Router) {
+
+	router.HandleFunc("/gift-cards", middleware.RequireRole(controller.IssueGiftCard, "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/gift-cards/{code}/balance", middleware.RequireRole(controller.GetGiftCardBalance, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/gift-cards/{code}/redeem", middleware.RequireRole(controller.RedeemGiftCard, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/gift-cards/{code}/ledger", middleware.RequireRole(controller.GetGiftCardLedger, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodGet)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	"github.com/gorilla/mux"
+)
+
+func GuestPublicRoutes(router *mux.Router) {
+	router.HandleFunc("/guest/session", controller.StartGuestSession).Methods(http.MethodPost)
+}
+
+// GuestProtectedRoutes must be mounted on a router guarded by GuestAuthentication
+func GuestProtectedRoutes(router *mux.Router) {
+	router.HandleFunc("/menus", controller.GetGuestMenus).Methods(http.MethodGet)
+	router.HandleFunc("/menus/{menu_id}/foods", controller.GetGuestMenuFoods).Methods(http.MethodGet)
+
+	router.HandleFunc("/order", controller.GetGuestOrder).Methods(http.MethodGet)
+	router.HandleFunc("/orderitems", controller.CreateGuestOrderItem).Methods(http.MethodPost)
+	router.HandleFunc("/bill", controller.RequestGuestBill).Methods(http.MethodPost)
+}
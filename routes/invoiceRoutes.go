@@ -4,21 +4,35 @@ import (
 	"net/http"
 
 	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
 	"github.com/gorilla/mux"
 )
 
 func InvoiceProtectedRoutes(router *mux.Router) {
 
 	router.HandleFunc("/invoices", controller.GetInvoices).Methods(http.MethodGet)
-	router.HandleFunc("/invoices", controller.CreateInvoice).Methods(http.MethodPost)
+	router.HandleFunc("/invoices", middleware.RequireRole(controller.CreateInvoice, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/invoices/export", middleware.RequireRole(controller.ExportInvoices, "MANAGER", "ADMIN")).Methods(http.MethodGet)
 
 	router.HandleFunc("/invoices/{invoice_id}", controller.GetInvoiceById).Methods(http.MethodGet)
-	router.HandleFunc("/invoices/{invoice_id}", controller.UpdateInvoice).Methods(http.MethodPatch)
-	router.HandleFunc("/invoices/{invoice_id}", controller.DeleteInvoice).Methods(http.MethodDelete)
+	router.HandleFunc("/invoices/{invoice_id}", middleware.RequireRole(controller.UpdateInvoice, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/invoices/{invoice_id}", middleware.RequireRole(controller.DeleteInvoice, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodDelete)
+	router.HandleFunc("/invoices/{invoice_id}/restore", middleware.RequireRole(controller.RestoreInvoice, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/invoices/{invoice_id}/void", middleware.RequireRole(controller.VoidInvoice, "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/invoices/{invoice_id}/refund", middleware.RequireRole(controller.RefundInvoice, "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/invoices/{invoice_id}/credit-notes", controller.GetInvoiceCreditNotes).Methods(http.MethodGet)
+	router.HandleFunc("/invoices/{invoice_id}/print", controller.PrintInvoice).Methods(http.MethodGet)
+	router.HandleFunc("/invoices/{invoice_id}/payments/intent", controller.CreatePaymentIntent).Methods(http.MethodPost)
+	router.HandleFunc("/invoices/{invoice_id}/payments/capture", controller.CapturePayment).Methods(http.MethodPost)
 
 	router.HandleFunc("/invoices/order/{order_id}", controller.GetInvoiceByOrderId).Methods(http.MethodGet)
 	router.HandleFunc("/invoices/user/{user_id}", controller.GetInvoicesByUserId).Methods(http.MethodGet)
 
 	router.HandleFunc("/invoices/status/pending", controller.GetPendingInvoices).Methods(http.MethodGet)
 	router.HandleFunc("/invoices/status/paid", controller.GetPaidInvoices).Methods(http.MethodGet)
+
+	router.HandleFunc("/credit-notes", controller.GetCreditNotes).Methods(http.MethodGet)
+
+	router.HandleFunc("/receipt-template", controller.GetReceiptTemplate).Methods(http.MethodGet)
+	router.HandleFunc("/receipt-template", middleware.RequireRole(controller.UpdateReceiptTemplate, "MANAGER", "ADMIN")).Methods(http.MethodPut)
 }
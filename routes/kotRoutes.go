@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/gorilla/mux"
+)
+
+func KotProtectedRoutes(router *mux.Router) {
+
+	router.HandleFunc("/kots", controller.GetKOTs).Methods(http.MethodGet)
+	router.HandleFunc("/kots/{kot_id}/reprint", middleware.RequireRole(controller.ReprintKOT, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/kots/{kot_id}/cancel", middleware.RequireRole(controller.CancelKOT, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/orders/{order_id}/kots", controller.GetOrderKOTs).Methods(http.MethodGet)
+
+	router.HandleFunc("/kitchen-stations", controller.GetKitchenStations).Methods(http.MethodGet)
+	router.HandleFunc("/kitchen-stations", middleware.RequireRole(controller.CreateKitchenStation, "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/kitchen-stations/{station_id}", middleware.RequireRole(controller.UpdateKitchenStation, "MANAGER", "ADMIN")).Methods(http.MethodPut)
+	router.HandleFunc("/kitchen-stations/{station_id}", middleware.RequireRole(controller.DeleteKitchenStation, "MANAGER", "ADMIN")).Methods(http.MethodDelete)
+}
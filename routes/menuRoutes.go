@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	controllers "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
 
 	"github.com/gorilla/mux"
 )
@@ -11,9 +12,12 @@ import (
 func MenuProtectedRoutes(router *mux.Router) {
 
 	router.HandleFunc("/menus", controllers.GetMenus).Methods(http.MethodGet)
-	router.HandleFunc("/menus", controllers.CreateMenu).Methods(http.MethodPost)
+	router.HandleFunc("/menus", middleware.RequireRole(controllers.CreateMenu, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/menus/import", middleware.RequireRole(controllers.ImportMenus, "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/menus/export", middleware.RequireRole(controllers.ExportMenus, "MANAGER", "ADMIN")).Methods(http.MethodGet)
 
 	router.HandleFunc("/menus/{menu_id}", controllers.GetMenu).Methods(http.MethodGet)
-	router.HandleFunc("/menus/{menu_id}", controllers.UpdateMenu).Methods(http.MethodPatch)
-	router.HandleFunc("/menus/{menu_id}", controllers.DeleteMenu).Methods(http.MethodDelete)
+	router.HandleFunc("/menus/{menu_id}", middleware.RequireRole(controllers.UpdateMenu, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/menus/{menu_id}", middleware.RequireRole(controllers.DeleteMenu, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodDelete)
+	router.HandleFunc("/menus/{menu_id}/restore", middleware.RequireRole(controllers.RestoreMenu, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
 }
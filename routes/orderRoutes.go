@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
 
 	"github.com/gorilla/mux"
 )
@@ -11,12 +12,15 @@ import (
 func OrderProtectedRoutes(router *mux.Router) {
 
 	router.HandleFunc("/orders", controller.GetOrders).Methods(http.MethodGet)
-	router.HandleFunc("/orders", controller.CreateOrder).Methods(http.MethodPost)
+	router.HandleFunc("/orders", middleware.RequireRole(controller.CreateOrder, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/orders/export", middleware.RequireRole(controller.ExportOrders, "MANAGER", "ADMIN")).Methods(http.MethodGet)
 
 	router.HandleFunc("/orders/{order_id}", controller.GetOrderById).Methods(http.MethodGet)
-	router.HandleFunc("/orders/{order_id}", controller.UpdateOrder).Methods(http.MethodPatch)
-	router.HandleFunc("/orders/{order_id}", controller.DeleteOrder).Methods(http.MethodDelete)
-	router.HandleFunc("/orders/{order_id}/status", controller.UpdateOrderStatus).Methods(http.MethodPatch)
+	router.HandleFunc("/orders/{order_id}", middleware.RequireRole(controller.UpdateOrder, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/orders/{order_id}", middleware.RequireRole(controller.DeleteOrder, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodDelete)
+	router.HandleFunc("/orders/{order_id}/restore", middleware.RequireRole(controller.RestoreOrder, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/orders/{order_id}/status", middleware.RequireRole(controller.UpdateOrderStatus, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/orders/{order_id}/transfer", middleware.RequireRole(controller.TransferOrder, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
 
 	router.HandleFunc("/orders/table/{table_id}", controller.GetOrdersByTableId).Methods(http.MethodGet)
 	router.HandleFunc("/orders/user/{user_id}", controller.GetOrdersByUserId).Methods(http.MethodGet)
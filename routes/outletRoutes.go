@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/gorilla/mux"
+)
+
+func OutletProtectedRoutes(router *mux.Router) {
+
+	router.HandleFunc("/outlets", controller.GetOutlets).Methods(http.MethodGet)
+	router.HandleFunc("/outlets/{outlet_id}", controller.GetOutlet).Methods(http.MethodGet)
+	router.HandleFunc("/outlets", middleware.RequireRole(middleware.RequireHeadOffice(controller.CreateOutlet), "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/outlets/{outlet_id}", middleware.RequireRole(middleware.RequireHeadOffice(controller.UpdateOutlet), "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/outlets/{outlet_id}", middleware.RequireRole(middleware.RequireHeadOffice(controller.DeleteOutlet), "ADMIN")).Methods(http.MethodDelete)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	"github.com/gorilla/mux"
+)
+
+// PaymentPublicRoutes are called by payment providers; requests are authenticated by their webhook signature
+func PaymentPublicRoutes(router *mux.Router) {
+	router.HandleFunc("/payments/webhook/{provider}", controller.PaymentWebhook).Methods(http.MethodPost)
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/gorilla/mux"
+)
+
+func PosPublicRoutes(router *mux.Router) {
+	router.HandleFunc("/pos/login", controller.PosLogin).Methods(http.MethodPost)
+}
+
+func PosProtectedRoutes(router *mux.Router) {
+
+	router.HandleFunc("/pos-terminals", middleware.RequireRole(controller.GetPosTerminals, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/pos-terminals", middleware.RequireRole(controller.RegisterPosTerminal, "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/pos-terminals/{terminal_id}/rotate-key", middleware.RequireRole(controller.RotatePosTerminalKey, "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/pos-terminals/{terminal_id}", middleware.RequireRole(controller.DeletePosTerminal, "MANAGER", "ADMIN")).Methods(http.MethodDelete)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/gorilla/mux"
+)
+
+func ReportProtectedRoutes(router *mux.Router) {
+
+	router.HandleFunc("/reports/sales", middleware.RequireRole(controller.GetSalesReport, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/reports/items", middleware.RequireRole(controller.GetItemSalesReport, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/reports/items/heatmap", middleware.RequireRole(controller.GetItemHeatmap, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/reports/menu-engineering", middleware.RequireRole(controller.GetMenuEngineeringReport, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/reports/tips", middleware.RequireRole(controller.GetTipPoolReport, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/reports/servers", middleware.RequireRole(controller.GetServerReport, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/reports/z", middleware.RequireRole(controller.GetZReports, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/reports/z", middleware.RequireRole(controller.CreateZReport, "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/reports/z/{business_date}", middleware.RequireRole(controller.GetZReport, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+}
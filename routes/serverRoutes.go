@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/gorilla/mux"
+)
+
+func ServerProtectedRoutes(router *mux.Router) {
+
+	router.HandleFunc("/sections", controller.GetSections).Methods(http.MethodGet)
+	router.HandleFunc("/sections/{section}/server", middleware.RequireRole(controller.AssignSectionServer, "MANAGER", "ADMIN")).Methods(http.MethodPut)
+	router.HandleFunc("/sections/{section}/server", middleware.RequireRole(controller.UnassignSectionServer, "MANAGER", "ADMIN")).Methods(http.MethodDelete)
+
+	router.HandleFunc("/tables/{table_id}/server", middleware.RequireRole(controller.AssignTableServer, "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/servers/{user_id}/handover", middleware.RequireRole(controller.HandoverServer, "MANAGER", "ADMIN")).Methods(http.MethodPost)
+}
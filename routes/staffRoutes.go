@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
+	"github.com/gorilla/mux"
+)
+
+func StaffProtectedRoutes(router *mux.Router) {
+
+	router.HandleFunc("/staff", middleware.RequireRole(controller.GetStaff, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/staff", middleware.RequireRole(controller.CreateStaff, "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/staff/{staff_id}", middleware.RequireRole(controller.GetStaffMember, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/staff/{staff_id}", middleware.RequireRole(controller.UpdateStaff, "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/staff/{staff_id}", middleware.RequireRole(controller.DeleteStaff, "MANAGER", "ADMIN")).Methods(http.MethodDelete)
+
+	router.HandleFunc("/shifts", middleware.RequireRole(controller.GetShifts, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/shifts", middleware.RequireRole(controller.CreateShift, "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/shifts/{shift_id}", middleware.RequireRole(controller.UpdateShift, "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/shifts/{shift_id}", middleware.RequireRole(controller.DeleteShift, "MANAGER", "ADMIN")).Methods(http.MethodDelete)
+
+	router.HandleFunc("/clock/in", middleware.RequireRole(controller.ClockIn, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/clock/out", middleware.RequireRole(controller.ClockOut, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/clock/break/start", middleware.RequireRole(controller.StartBreak, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/clock/break/end", middleware.RequireRole(controller.EndBreak, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+
+	router.HandleFunc("/time-entries", middleware.RequireRole(controller.GetTimeEntries, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+	router.HandleFunc("/time-entries/{time_entry_id}", middleware.RequireRole(controller.AdjustTimeEntry, "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/timesheets", middleware.RequireRole(controller.GetTimesheets, "MANAGER", "ADMIN")).Methods(http.MethodGet)
+}
@@ -4,21 +4,32 @@ import (
 	"net/http"
 
 	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
 	"github.com/gorilla/mux"
 )
 
 func TableProtectedRoutes(router *mux.Router) {
 
 	router.HandleFunc("/tables", controller.GetTables).Methods(http.MethodGet)
-	router.HandleFunc("/tables", controller.CreateTable).Methods(http.MethodPost)
+	router.HandleFunc("/tables", middleware.RequireRole(controller.CreateTable, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
 
 	router.HandleFunc("/tables/reserved", controller.GetReservedTables).Methods(http.MethodGet)
 	router.HandleFunc("/tables/unreserved", controller.GetUnreservedTables).Methods(http.MethodGet)
+	router.HandleFunc("/tables/layout", controller.GetFloorPlan).Methods(http.MethodGet)
+	router.HandleFunc("/tables/merge", middleware.RequireRole(controller.MergeTables, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/tables/turns", controller.GetTableTurnSummary).Methods(http.MethodGet)
 
 	router.HandleFunc("/tables/{table_id}", controller.GetTable).Methods(http.MethodGet)
-	router.HandleFunc("/tables/{table_id}", controller.UpdateTable).Methods(http.MethodPatch)
-	router.HandleFunc("/tables/{table_id}", controller.DeleteTable).Methods(http.MethodDelete)
+	router.HandleFunc("/tables/{table_id}", middleware.RequireRole(controller.UpdateTable, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/tables/{table_id}", middleware.RequireRole(controller.DeleteTable, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodDelete)
 
-	router.HandleFunc("/tables/reserve/{table_id}", controller.ReserveTable).Methods(http.MethodPut)
-	router.HandleFunc("/tables/unreserve/{table_id}", controller.UnreserveTable).Methods(http.MethodPut)
+	router.HandleFunc("/tables/reserve/{table_id}", middleware.RequireRole(controller.ReserveTable, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPut)
+	router.HandleFunc("/tables/unreserve/{table_id}", middleware.RequireRole(controller.UnreserveTable, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPut)
+
+	router.HandleFunc("/tables/{table_id}/qr", controller.GetTableQR).Methods(http.MethodGet)
+	router.HandleFunc("/tables/{table_id}/qr/rotate", middleware.RequireRole(controller.RotateTableQR, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPut)
+	router.HandleFunc("/tables/{table_id}/layout", middleware.RequireRole(controller.UpdateTableLayout, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/tables/{table_id}/split", middleware.RequireRole(controller.SplitTable, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPost)
+	router.HandleFunc("/tables/{table_id}/status", middleware.RequireRole(controller.UpdateTableStatus, "STAFF", "MANAGER", "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/tables/{table_id}/turns", controller.GetTableTurns).Methods(http.MethodGet)
 }
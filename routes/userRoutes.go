@@ -4,18 +4,21 @@ import (
 	"net/http"
 
 	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	middleware "github.com/02priyeshraj/Hotel_Management_Backend/middlewares"
 
 	"github.com/gorilla/mux"
 )
 
 func UserPublicRoutes(router *mux.Router) {
-	router.HandleFunc("/users/signup", controller.SignUp).Methods(http.MethodPost)
-	router.HandleFunc("/users/login", controller.Login).Methods(http.MethodPost)
+	router.Handle("/users/signup", middleware.Audit(http.HandlerFunc(controller.SignUp))).Methods(http.MethodPost)
+	router.Handle("/users/login", middleware.Audit(http.HandlerFunc(controller.Login))).Methods(http.MethodPost)
 }
 
 func UserProtectedRoutes(router *mux.Router) {
 	router.HandleFunc("/users", controller.GetUsers).Methods(http.MethodGet)
 	router.HandleFunc("/users/{user_id}", controller.GetUser).Methods(http.MethodGet)
+	router.HandleFunc("/users/{user_id}/role", middleware.RequireRole(controller.UpdateUserRole, "ADMIN")).Methods(http.MethodPatch)
+	router.HandleFunc("/users/{user_id}/outlet", middleware.RequireRole(middleware.RequireHeadOffice(controller.UpdateUserOutlet), "ADMIN")).Methods(http.MethodPatch)
 
 	router.HandleFunc("/users/logout", controller.Logout).Methods(http.MethodPost)
 }
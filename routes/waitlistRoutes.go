@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"net/http"
+
+	controller "github.com/02priyeshraj/Hotel_Management_Backend/controllers"
+	"github.com/gorilla/mux"
+)
+
+func WaitlistProtectedRoutes(router *mux.Router) {
+
+	router.HandleFunc("/waitlist", controller.GetWaitlist).Methods(http.MethodGet)
+	router.HandleFunc("/waitlist", controller.AddToWaitlist).Methods(http.MethodPost)
+
+	router.HandleFunc("/waitlist/{waitlist_id}", controller.GetWaitlistEntry).Methods(http.MethodGet)
+	router.HandleFunc("/waitlist/{waitlist_id}", controller.RemoveFromWaitlist).Methods(http.MethodDelete)
+
+	router.HandleFunc("/waitlist/{waitlist_id}/suggest", controller.SuggestWaitlistTable).Methods(http.MethodGet)
+	router.HandleFunc("/waitlist/{waitlist_id}/seat", controller.SeatWaitlistParty).Methods(http.MethodPost)
+}